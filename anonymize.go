@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Matches @-mentions of GitHub users in bodies, but not email addresses
+var mentionPattern = regexp.MustCompile(`(^|[^\w.])@[A-Za-z0-9][A-Za-z0-9-]{0,38}`)
+
+// anonymizer hands out stable pseudonyms (user1, user2, ...) for logins.
+type anonymizer struct {
+	pseudonyms map[string]string // lower-cased login -> pseudonym
+	logins     map[string]string // pseudonym -> original login
+}
+
+func newAnonymizer() *anonymizer {
+	return &anonymizer{
+		pseudonyms: make(map[string]string),
+		logins:     make(map[string]string),
+	}
+}
+
+// pseudonym returns the pseudonym for login, assigning the next free one on first use.
+func (a *anonymizer) pseudonym(login string) string {
+	if login == "" {
+		return ""
+	}
+
+	// GitHub logins are case-insensitive
+	key := strings.ToLower(login)
+	if name, ok := a.pseudonyms[key]; ok {
+		return name
+	}
+
+	name := fmt.Sprintf("user%d", len(a.pseudonyms)+1)
+	a.pseudonyms[key] = name
+	a.logins[name] = login
+	return name
+}
+
+// body replaces every @-mention in s with the mentioned user's pseudonym.
+func (a *anonymizer) body(s string) string {
+	return mentionPattern.ReplaceAllStringFunc(s, func(match string) string {
+		at := strings.IndexByte(match, '@')
+		return match[:at+1] + a.pseudonym(match[at+1:])
+	})
+}
+
+// apply anonymizes the authors and mentions of the issue and comments in place.
+// Authors are numbered before mentions so participants get the lowest numbers.
+func (a *anonymizer) apply(issue *Issue, comments []Comment) {
+	issue.User.Login = a.pseudonym(issue.User.Login)
+	issue.User.Name = ""
+	for i := range comments {
+		comments[i].User.Login = a.pseudonym(comments[i].User.Login)
+		comments[i].User.Name = ""
+	}
+	for i := range issue.LabelEvents {
+		issue.LabelEvents[i].Actor = a.pseudonym(issue.LabelEvents[i].Actor)
+	}
+	if issue.ClosedBy != nil {
+		issue.ClosedBy.Actor = a.pseudonym(issue.ClosedBy.Actor)
+	}
+	for i := range comments {
+		a.reactors(comments[i].Reactors)
+	}
+
+	issue.Body = a.body(issue.Body)
+	for i := range comments {
+		comments[i].Body = a.body(comments[i].Body)
+	}
+}
+
+// reactors replaces the logins of who reacted with their pseudonyms, going
+// through the reactions in a fixed order so the numbering is stable.
+func (a *anonymizer) reactors(reactors map[string][]string) {
+	contents := make([]string, 0, len(reactors))
+	for content := range reactors {
+		contents = append(contents, content)
+	}
+	sort.Strings(contents)
+
+	for _, content := range contents {
+		logins := reactors[content]
+		for i, login := range logins {
+			// Deleted accounts are already shown as (ghost)
+			if login != displayLogin("") {
+				logins[i] = a.pseudonym(login)
+			}
+		}
+	}
+}
+
+// writeMap saves the pseudonym to login mapping as JSON.
+func (a *anonymizer) writeMap(filePath string) error {
+	mapJSON, err := json.MarshalIndent(a.logins, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal anonymization map: %w", err)
+	}
+
+	err = os.WriteFile(filePath, mapJSON, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write anonymization map: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnonymizerReactors(t *testing.T) {
+	tests := []struct {
+		name     string
+		authors  []string
+		reactors map[string][]string
+		want     map[string][]string
+	}{
+		{
+			name:     "reactor who also commented",
+			authors:  []string{"alice"},
+			reactors: map[string][]string{"+1": {"alice", "bob"}},
+			want:     map[string][]string{"+1": {"user1", "user2"}},
+		},
+		{
+			name:     "same reactor on several reactions",
+			authors:  []string{"alice"},
+			reactors: map[string][]string{"heart": {"Bob"}, "+1": {"bob", "carol"}},
+			want:     map[string][]string{"heart": {"user2"}, "+1": {"user2", "user3"}},
+		},
+		{
+			name:     "deleted account",
+			authors:  []string{"alice"},
+			reactors: map[string][]string{"eyes": {"(ghost)", "dave"}},
+			want:     map[string][]string{"eyes": {"(ghost)", "user2"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var comments []Comment
+			for _, login := range tt.authors {
+				comments = append(comments, Comment{User: User{Login: login}})
+			}
+			comments[0].Reactors = tt.reactors
+
+			newAnonymizer().apply(&Issue{}, comments)
+
+			if !reflect.DeepEqual(comments[0].Reactors, tt.want) {
+				t.Errorf("reactors = %v, want %v", comments[0].Reactors, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnonymizerPseudonym(t *testing.T) {
+	a := newAnonymizer()
+	tests := []struct {
+		login string
+		want  string
+	}{
+		{"alice", "user1"},
+		{"bob", "user2"},
+		{"Alice", "user1"},
+		{"", ""},
+		{"carol", "user3"},
+	}
+
+	for _, tt := range tests {
+		got := a.pseudonym(tt.login)
+		if got != tt.want {
+			t.Errorf("pseudonym(%q) = %q, want %q", tt.login, got, tt.want)
+		}
+	}
+
+	// The map keeps the login as it was first seen
+	if a.logins["user1"] != "alice" {
+		t.Errorf("logins[user1] = %q, want %q", a.logins["user1"], "alice")
+	}
+}
+
+func TestAnonymizerBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "mention",
+			body: "thanks @alice!",
+			want: "thanks @user1!",
+		},
+		{
+			name: "mention at the start",
+			body: "@bob can you look?",
+			want: "@user1 can you look?",
+		},
+		{
+			name: "same user twice",
+			body: "@alice and @Alice",
+			want: "@user1 and @user1",
+		},
+		{
+			name: "email address",
+			body: "mail alice@example.com",
+			want: "mail alice@example.com",
+		},
+		{
+			name: "no mentions",
+			body: "plain text",
+			want: "plain text",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newAnonymizer().body(tt.body)
+			if got != tt.want {
+				t.Errorf("body(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnonymizerApply(t *testing.T) {
+	issue := Issue{User: User{Login: "alice", Name: "Alice A."}, Body: "cc @carol"}
+	comments := []Comment{
+		{User: User{Login: "bob", Name: "Bob B."}, Body: "@alice agreed"},
+		{User: User{Login: "alice"}, Body: "ping @dave"},
+	}
+
+	newAnonymizer().apply(&issue, comments)
+
+	// Authors are numbered before anyone who is only mentioned
+	if issue.User.Login != "user1" || issue.User.Name != "" {
+		t.Errorf("issue user = %+v, want user1 without a name", issue.User)
+	}
+	if got := []string{comments[0].User.Login, comments[1].User.Login}; !reflect.DeepEqual(got, []string{"user2", "user1"}) {
+		t.Errorf("comment authors = %v, want [user2 user1]", got)
+	}
+	if comments[0].User.Name != "" {
+		t.Errorf("comment name = %q, want it cleared", comments[0].User.Name)
+	}
+	if issue.Body != "cc @user3" {
+		t.Errorf("issue body = %q, want %q", issue.Body, "cc @user3")
+	}
+	if comments[0].Body != "@user1 agreed" || comments[1].Body != "ping @user4" {
+		t.Errorf("comment bodies = %q, %q", comments[0].Body, comments[1].Body)
+	}
+}
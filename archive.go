@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Directory --archive-layout date writes under when --output isn't given
+const defaultArchiveRoot = "output"
+
+// archivePath places the output of an issue under root/YYYY/MM/, going by
+// the day it was created in UTC, as owner-repo-number with the format's extension.
+func archivePath(root, owner, repo, issueNumber string, created time.Time) string {
+	ext := formatExtensions[formatFlag]
+	if gzipFlag {
+		ext += ".gz"
+	}
+	name := fmt.Sprintf("%s-%s-%s%s", sanitizeFilename(owner), sanitizeFilename(repo), sanitizeFilename(issueNumber), ext)
+
+	created = created.UTC()
+	if strings.HasPrefix(root, s3Scheme) {
+		return s3Scheme + path.Join(strings.TrimPrefix(root, s3Scheme), created.Format("2006"), created.Format("01"), name)
+	}
+	return filepath.Join(root, created.Format("2006"), created.Format("01"), name)
+}
+
+// archiveOutput works out where an issue goes in the archive and creates
+// the directories leading there.
+func archiveOutput(owner, repo, issueNumber string, issue Issue) (string, error) {
+	root := outputFlag
+	if root == "" {
+		root = defaultArchiveRoot
+	}
+
+	outputFile := archivePath(root, owner, repo, issueNumber, issue.DateTime)
+	if strings.HasPrefix(outputFile, s3Scheme) {
+		return outputFile, nil
+	}
+
+	err := os.MkdirAll(filepath.Dir(outputFile), 0755)
+	if err != nil {
+		return "", fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	return outputFile, nil
+}
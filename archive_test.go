@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArchivePath(t *testing.T) {
+	created := time.Date(2023, time.March, 31, 23, 30, 0, 0, time.FixedZone("", -2*60*60))
+
+	tests := []struct {
+		name   string
+		root   string
+		format string
+		gzip   bool
+		owner  string
+		want   string
+	}{
+		{name: "text", root: "output", format: "text", owner: "o", want: filepath.Join("output", "2023", "04", "o-r-7.txt")},
+		{name: "gzip", root: "archive", format: "json", gzip: true, owner: "o", want: filepath.Join("archive", "2023", "04", "o-r-7.json.gz")},
+		{name: "unsafe owner", root: "output", format: "markdown", owner: "../o", want: filepath.Join("output", "2023", "04", "o-r-7.md")},
+		{name: "s3", root: "s3://bucket/comments", format: "ndjson", owner: "o", want: "s3://bucket/comments/2023/04/o-r-7.ndjson"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &formatFlag, tt.format)
+			setFlag(t, &gzipFlag, tt.gzip)
+			if got := archivePath(tt.root, tt.owner, "r", "7", created); got != tt.want {
+				t.Errorf("archivePath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArchiveOutputCreatesDirectories(t *testing.T) {
+	dir := chdirTemp(t)
+	setFlag(t, &formatFlag, "text")
+	setFlag(t, &outputFlag, "")
+
+	got, err := archiveOutput("o", "r", "7", Issue{DateTime: time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatalf("archiveOutput() error = %v", err)
+	}
+	want := filepath.Join("output", "2024", "01", "o-r-7.txt")
+	if got != want {
+		t.Errorf("archiveOutput() = %q, want %q", got, want)
+	}
+	info, err := os.Stat(filepath.Join(dir, "output", "2024", "01"))
+	if err != nil || !info.IsDir() {
+		t.Errorf("archive directory not created: %v", err)
+	}
+}
+
+func TestRunArchiveLayoutWithoutIssue(t *testing.T) {
+	setFlag(t, &archiveLayout, "date")
+	setFlag(t, &includeIssueFlag, false)
+
+	err := runStubbed(t, stubAPI{})
+	if exitCode(err) != exitUsage || !strings.Contains(err.Error(), "--include-issue=false") {
+		t.Errorf("run() error = %v, want a usage error about --include-issue=false", err)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// atomicFile is written next to its target under a temporary name and only
+// replaces the target on commit, so a failed run leaves the old file intact.
+type atomicFile struct {
+	*os.File
+	target    string
+	committed bool
+}
+
+// createAtomic starts writing a replacement for target.
+func createAtomic(target string) (*atomicFile, error) {
+	file, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+	return &atomicFile{File: file, target: target}, nil
+}
+
+// commit closes the file and moves it over the target, keeping the mode of
+// the file it replaces.
+func (a *atomicFile) commit() error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(a.target); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	err := a.File.Chmod(mode)
+	if err == nil {
+		err = a.File.Close()
+	}
+	if err == nil {
+		err = os.Rename(a.Name(), a.target)
+	}
+	if err != nil {
+		os.Remove(a.Name())
+		return fmt.Errorf("failed to save %s: %w", a.target, err)
+	}
+
+	a.committed = true
+	return nil
+}
+
+// Close throws the temporary file away unless it was committed.
+func (a *atomicFile) Close() error {
+	if a.committed {
+		return nil
+	}
+	a.File.Close()
+	return os.Remove(a.Name())
+}
+
+// pipeFile writes straight into a named pipe, where the reader sees the
+// output as it's generated. A pipe can't be replaced, so commit just closes it.
+type pipeFile struct {
+	*os.File
+}
+
+// openPipe opens a FIFO for writing, waiting until something reads from it.
+func openPipe(target string) (*pipeFile, error) {
+	file, err := os.OpenFile(target, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pipe: %w", err)
+	}
+	return &pipeFile{File: file}, nil
+}
+
+func (p *pipeFile) commit() error {
+	return p.Close()
+}
+
+// Close closes the pipe, which the reader sees as the end of the output.
+func (p *pipeFile) Close() error {
+	err := p.File.Close()
+	if errors.Is(err, os.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// writeFileAtomic works like os.WriteFile, replacing the file in one step.
+func writeFileAtomic(target string, data []byte) error {
+	file, err := createAtomic(target)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(data)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	return file.commit()
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAtomicFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing bool
+		commit   bool
+		want     string
+		wantMode os.FileMode
+	}{
+		{name: "new file", commit: true, want: "new", wantMode: 0644},
+		{name: "replaced file keeps its mode", existing: true, commit: true, want: "new", wantMode: 0600},
+		{name: "failed run keeps the old file", existing: true, commit: false, want: "old", wantMode: 0600},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			target := filepath.Join(dir, "comments.txt")
+			if tt.existing {
+				os.WriteFile(target, []byte("old"), 0600)
+			}
+
+			file, err := createAtomic(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file.WriteString("new")
+			if tt.commit {
+				err = file.commit()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			file.Close()
+
+			content, err := os.ReadFile(target)
+			if tt.existing || tt.commit {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(content) != tt.want {
+					t.Errorf("content = %q, want %q", content, tt.want)
+				}
+				info, _ := os.Stat(target)
+				if info.Mode().Perm() != tt.wantMode {
+					t.Errorf("mode = %v, want %v", info.Mode().Perm(), tt.wantMode)
+				}
+			}
+
+			// No temporary file is left behind either way
+			entries, _ := os.ReadDir(dir)
+			if len(entries) != 1 {
+				t.Errorf("directory holds %d files, want only the target", len(entries))
+			}
+		})
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "manifest.json")
+	for _, data := range []string{"first", "second"} {
+		err := writeFileAtomic(target, []byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := os.ReadFile(target)
+		if string(content) != data {
+			t.Errorf("content = %q, want %q", content, data)
+		}
+	}
+
+	err := writeFileAtomic(filepath.Join(t.TempDir(), "missing", "manifest.json"), []byte("x"))
+	if err == nil {
+		t.Error("writeFileAtomic() into a missing directory succeeded")
+	}
+}
@@ -0,0 +1,86 @@
+//go:build unix
+
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestOpenOutputPipe(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+	}{
+		{name: "single write", chunks: []string{"Issue Title: Crash\n"}},
+		{name: "streamed writes", chunks: []string{"Issue Title: Crash\n", "Comment 1 by bob\n", "Same here.\n"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "comments.fifo")
+			err := syscall.Mkfifo(path, 0600)
+			if err != nil {
+				t.Skipf("can't create a FIFO here: %v", err)
+			}
+
+			// Read the pipe concurrently, as opening it for writing waits for a reader
+			read := make(chan string)
+			go func() {
+				reader, err := os.Open(path)
+				if err != nil {
+					read <- "error: " + err.Error()
+					return
+				}
+				defer reader.Close()
+				data, _ := io.ReadAll(reader)
+				read <- string(data)
+			}()
+
+			out, err := openOutput(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want string
+			for _, chunk := range tt.chunks {
+				_, err = io.WriteString(out, chunk)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want += chunk
+			}
+			err = out.commit()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = out.Close()
+			if err != nil {
+				t.Errorf("Close() after commit() = %v, want nil", err)
+			}
+
+			if got := <-read; got != want {
+				t.Errorf("read %q from the pipe, want %q", got, want)
+			}
+
+			// The pipe is written in place, not replaced by a regular file
+			info, err := os.Lstat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode()&os.ModeNamedPipe == 0 {
+				t.Errorf("%s is now %s, want the named pipe", path, info.Mode())
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("directory holds %d entries, want only the pipe", len(entries))
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import "strings"
+
+// loginList is the value of the repeatable --author and --exclude-author
+// flags, each taking one or more comma separated logins.
+type loginList []string
+
+func (l *loginList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *loginList) Set(value string) error {
+	for _, login := range strings.Split(value, ",") {
+		login = strings.TrimPrefix(strings.TrimSpace(login), "@")
+		if login != "" {
+			*l = append(*l, login)
+		}
+	}
+	return nil
+}
+
+// has reports whether login is in the list. Logins are case-insensitive.
+func (l loginList) has(login string) bool {
+	for _, listed := range l {
+		if strings.EqualFold(listed, login) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterAuthors keeps the comments written by the included authors, when
+// any are given, and then drops those by the excluded ones.
+func filterAuthors(comments []Comment, include, exclude loginList) []Comment {
+	filtered := comments[:0]
+	for _, comment := range comments {
+		if len(include) > 0 && !include.has(comment.User.Login) {
+			continue
+		}
+		if exclude.has(comment.User.Login) {
+			continue
+		}
+		filtered = append(filtered, comment)
+	}
+	return filtered
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoginListSet(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   loginList
+	}{
+		{values: []string{"alice"}, want: loginList{"alice"}},
+		{values: []string{"alice,@bob", "carol"}, want: loginList{"alice", "bob", "carol"}},
+		{values: []string{" alice , ,"}, want: loginList{"alice"}},
+	}
+
+	for _, tt := range tests {
+		var l loginList
+		for _, value := range tt.values {
+			l.Set(value)
+		}
+		if !reflect.DeepEqual(l, tt.want) {
+			t.Errorf("Set(%q) = %v, want %v", tt.values, l, tt.want)
+		}
+	}
+}
+
+func TestFilterAuthors(t *testing.T) {
+	comments := []Comment{
+		{ID: 1, User: User{Login: "alice"}},
+		{ID: 2, User: User{Login: "Bob"}},
+		{ID: 3, User: User{Login: "carol"}},
+	}
+
+	tests := []struct {
+		name    string
+		include loginList
+		exclude loginList
+		want    []int64
+	}{
+		{name: "include", include: loginList{"alice", "bob"}, want: []int64{1, 2}},
+		{name: "exclude", exclude: loginList{"BOB"}, want: []int64{1, 3}},
+		{name: "exclude applied after include", include: loginList{"alice", "bob"}, exclude: loginList{"alice"}, want: []int64{2}},
+		{name: "nobody matches", include: loginList{"dave"}, want: []int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := commentIDs(filterAuthors(append([]Comment(nil), comments...), tt.include, tt.exclude))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filterAuthors() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Response saved by --cache, revalidated with its ETag on later runs
+type cacheEntry struct {
+	URL      string          `json:"url"`
+	ETag     string          `json:"etag"`
+	Link     string          `json:"link,omitempty"`
+	StoredAt time.Time       `json:"stored_at"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// On-disk store of API responses, one file per URL, media type and token
+type responseCache struct {
+	dir   string
+	ttl   time.Duration // entries of closed issues younger than this are used without asking GitHub
+	token string        // what a token sees depends on its access, so it's part of every key
+
+	closedMu sync.Mutex
+	closed   []string // URLs of the issues found closed during this run
+}
+
+// newResponseCache opens the cache in dir for responses fetched with token,
+// creating the directory if needed.
+func newResponseCache(dir string, ttl time.Duration, token string) (*responseCache, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &responseCache{dir: dir, ttl: ttl, token: token}, nil
+}
+
+// path names the file caching url as fetched with the accept media type. Only
+// a hash of the key ends up on disk, so the token isn't written anywhere.
+func (c *responseCache) path(url, accept string) string {
+	sum := sha256.Sum256([]byte(url + "\x00" + accept + "\x00" + c.token))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// markClosed lets --cache-ttl skip revalidating the responses under a closed
+// issue, such as its comments and events.
+func (c *responseCache) markClosed(issueURL string) {
+	c.closedMu.Lock()
+	defer c.closedMu.Unlock()
+	c.closed = append(c.closed, issueURL+"/")
+}
+
+// load returns the cached response for url, or nil when there is none.
+func (c *responseCache) load(url, accept string) *cacheEntry {
+	data, err := os.ReadFile(c.path(url, accept))
+	if err != nil {
+		return nil
+	}
+
+	var entry cacheEntry
+	err = json.Unmarshal(data, &entry)
+	if err != nil || entry.URL != url {
+		return nil
+	}
+	return &entry
+}
+
+// fresh reports whether the entry can be used without revalidating it. Open
+// issues can get new comments any time, so only responses under an issue
+// found closed during this run qualify.
+func (c *responseCache) fresh(entry *cacheEntry) bool {
+	if time.Since(entry.StoredAt) >= c.ttl {
+		return false
+	}
+
+	c.closedMu.Lock()
+	defer c.closedMu.Unlock()
+	for _, prefix := range c.closed {
+		if strings.HasPrefix(entry.URL, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// store saves the response for url. Failures only cost a later refetch, so
+// they are logged rather than returned.
+func (c *responseCache) store(url, accept string, entry cacheEntry) {
+	entry.URL = url
+	entry.StoredAt = time.Now()
+
+	data, err := json.Marshal(entry)
+	if err == nil {
+		err = os.WriteFile(c.path(url, accept), data, 0600)
+	}
+	if err != nil {
+		log.Printf("Failed to cache %s: %s", url, err)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetJSONCache(t *testing.T) {
+	tests := []struct {
+		name         string
+		ttl          time.Duration
+		closed       bool
+		wantRequests []string // If-None-Match of each request that reached the server
+	}{
+		{name: "revalidated", ttl: 0, closed: true, wantRequests: []string{"", `"v1"`}},
+		{name: "fresh for a closed issue", ttl: time.Hour, closed: true, wantRequests: []string{""}},
+		{name: "open issues are always revalidated", ttl: time.Hour, closed: false, wantRequests: []string{"", `"v1"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.Header.Get("If-None-Match"))
+				if r.Header.Get("If-None-Match") == `"v1"` {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set("ETag", `"v1"`)
+				fmt.Fprint(w, `[{"body":"cached"}]`)
+			}))
+			defer server.Close()
+
+			cache, err := newResponseCache(t.TempDir(), tt.ttl, "token")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.closed {
+				cache.markClosed(server.URL + "/repos/o/r/issues/1")
+			}
+			f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats(), cache: cache}
+
+			for i := 0; i < 2; i++ {
+				var comments []Comment
+				err = f.getJSON(server.URL+"/repos/o/r/issues/1/comments", &comments)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(comments) != 1 || comments[0].Body != "cached" {
+					t.Errorf("fetch #%d: comments = %+v, want the cached one", i+1, comments)
+				}
+			}
+
+			if !reflect.DeepEqual(requests, tt.wantRequests) {
+				t.Errorf("requests with If-None-Match %q, want %q", requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestResponseCacheKeys(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := newResponseCache(dir, time.Hour, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.store("https://api.github.com/a", "application/json", cacheEntry{ETag: "1", Body: []byte(`{}`)})
+
+	tests := []struct {
+		name   string
+		url    string
+		accept string
+		token  string
+		want   bool
+	}{
+		{name: "same URL, media type and token", url: "https://api.github.com/a", accept: "application/json", token: "token", want: true},
+		{name: "other media type", url: "https://api.github.com/a", accept: "application/vnd.github.text+json", token: "token", want: false},
+		{name: "other URL", url: "https://api.github.com/b", accept: "application/json", token: "token", want: false},
+		{name: "other token", url: "https://api.github.com/a", accept: "application/json", token: "other", want: false},
+		{name: "no token", url: "https://api.github.com/a", accept: "application/json", token: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := newResponseCache(dir, time.Hour, tt.token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.load(tt.url, tt.accept) != nil; got != tt.want {
+				t.Errorf("load() found an entry: %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
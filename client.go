@@ -0,0 +1,438 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// fetcher talks to the GitHub API on behalf of one run
+type fetcher struct {
+	ctx         context.Context // cancelled when the user interrupts the run
+	client      *http.Client
+	accessToken string
+	accept      string // media type asked for on REST requests
+	stats       *runStats
+	names       map[string]string    // display names by login, for --pretty-author
+	cache       *responseCache       // nil unless --cache is set
+	titles      map[string]string    // issue titles by owner/repo#number, for --resolve
+	state       *runState            // nil unless --state-file is set
+	warned      map[string]bool      // API warnings already shown
+	warnedMu    sync.Mutex           // guards warned against concurrent requests
+	retrySpent  atomic.Int64         // nanoseconds waited before retries, for --retry-budget
+	offline     *rawThread           // thread read with --from-file instead of fetching
+	tagTimes    map[string]time.Time // commit times by owner/repo@tag, for --since-tag
+	resume      *resumeState         // nil unless --resume is set
+	noReactions atomic.Bool          // set once the server rejected the reactions preview
+
+	// Comment filters by thread, so --follow keeps filtering the same way
+	filters map[string]*commentFilter
+}
+
+// newHTTPClient creates the HTTP client used for all requests, routed through
+// --proxy when one is given.
+func newHTTPClient() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	// Nearly every request goes to the same host, so that's where connections are kept
+	transport.MaxIdleConns = maxIdleConnsFlag
+	transport.MaxIdleConnsPerHost = maxIdleConnsFlag
+	transport.IdleConnTimeout = idleTimeoutFlag
+
+	if proxyFlag != "" {
+		proxyURL, err := url.Parse(proxyFlag)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %w", proxyFlag, err)
+		}
+
+		switch proxyURL.Scheme {
+		case "http", "https":
+			transport.Proxy = http.ProxyURL(proxyURL)
+		case "socks5", "socks5h":
+			// SOCKS5 works at the connection level, so it replaces the dialer instead
+			dialer, err := proxy.FromURL(proxyURL, &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second})
+			if err != nil {
+				return nil, fmt.Errorf("failed to set up SOCKS5 proxy: %w", err)
+			}
+
+			contextDialer, ok := dialer.(proxy.ContextDialer)
+			if !ok {
+				return nil, fmt.Errorf("SOCKS5 proxy dialer doesn't support contexts")
+			}
+
+			transport.Proxy = nil
+			transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return contextDialer.DialContext(ctx, network, addr)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q; expected http, https or socks5", proxyURL.Scheme)
+		}
+	}
+
+	tlsConfig, err := newTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// newTLSConfig builds the TLS settings from --ca-cert, --client-cert,
+// --client-key and --insecure, for Enterprise servers behind a private CA or
+// requiring mTLS.
+func newTLSConfig() (*tls.Config, error) {
+	config := &tls.Config{}
+
+	if caCertFlag != "" {
+		caPEM, err := os.ReadFile(caCertFlag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no PEM certificates found in %s", caCertFlag)
+		}
+		config.RootCAs = pool
+	}
+
+	if (clientCertFlag == "") != (clientKeyFlag == "") {
+		return nil, fmt.Errorf("the --client-cert and --client-key flags must be given together")
+	}
+	if clientCertFlag != "" {
+		certificate, err := tls.LoadX509KeyPair(clientCertFlag, clientKeyFlag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	// Accept any certificate, loudly, for self-signed test instances
+	if insecureFlag {
+		log.Print("WARNING: --insecure disables TLS certificate verification; the connection can be intercepted. Use it only for testing.")
+		config.InsecureSkipVerify = true
+	}
+
+	return config, nil
+}
+
+// sendRequest sends the request and, when GitHub answers with a secondary
+// (abuse detection) rate limit carrying a Retry-After header or with a
+// temporary server error, waits and tries again up to maxRetriesFlag times.
+// All waits of a run together stay within --retry-budget.
+func (f *fetcher) sendRequest(req *http.Request) (*http.Response, error) {
+	// Identify the tool, as GitHub asks clients to
+	req.Header.Set("User-Agent", userAgentFlag)
+
+	// Pin the REST API version so responses keep their shape
+	if apiVersionFlag != "" && schemaFlag == "github" {
+		req.Header.Set("X-GitHub-Api-Version", apiVersionFlag)
+	}
+
+	// Add the headers given with --header, which win over the ones above
+	headersFlag.apply(req)
+
+	for attempt := 0; ; attempt++ {
+		// Bodies are read by each attempt, so retries start from a fresh copy
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		// Keep to --rate whatever the server allows
+		err := waitForRequest(req.Context())
+		if err != nil {
+			return nil, err
+		}
+
+		f.stats.requests.Add(1)
+		resp, err := f.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		f.stats.recordRateLimit(resp.Header)
+		f.warnAPIChanges(resp)
+
+		var wait time.Duration
+		var reason string
+		switch resp.StatusCode {
+		case http.StatusForbidden, http.StatusTooManyRequests:
+			// The primary limit can't be waited out in a reasonable time, so leave it to the caller
+			if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+				log.Printf("Primary rate limit exhausted; it resets at %s", rateLimitReset(resp))
+				return resp, nil
+			}
+
+			var ok bool
+			wait, ok = parseRetryAfter(resp.Header.Get("Retry-After"))
+			if !ok {
+				return resp, nil
+			}
+			reason = "Secondary rate limit hit"
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			// Back off exponentially unless the server says how long to wait
+			var ok bool
+			wait, ok = parseRetryAfter(resp.Header.Get("Retry-After"))
+			if !ok {
+				wait = time.Second << attempt
+			}
+			reason = "Server error " + resp.Status
+		default:
+			return resp, nil
+		}
+		if attempt >= maxRetriesFlag {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		// Give up once the waits would go over the budget for the whole run
+		spent := time.Duration(f.retrySpent.Load())
+		if retryBudgetFlag > 0 && spent+wait > retryBudgetFlag {
+			return nil, fmt.Errorf("retry budget exhausted after %s", spent.Round(time.Millisecond))
+		}
+		f.retrySpent.Add(int64(wait))
+
+		log.Printf("%s; retrying in %s (attempt %d of %d)", reason, wait, attempt+1, maxRetriesFlag)
+		err = sleepContext(req.Context(), wait)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// warnAPIChanges shows the deprecation notices GitHub sends in the Sunset
+// and Warning headers, each only once per run.
+func (f *fetcher) warnAPIChanges(resp *http.Response) {
+	var warnings []string
+	if sunset := resp.Header.Get("Sunset"); sunset != "" {
+		warnings = append(warnings, fmt.Sprintf("%s %s will sunset on %s", resp.Request.Method, resp.Request.URL.Path, sunset))
+	}
+	warnings = append(warnings, resp.Header.Values("Warning")...)
+
+	f.warnedMu.Lock()
+	defer f.warnedMu.Unlock()
+	for _, warning := range warnings {
+		if f.warned[warning] {
+			continue
+		}
+		if f.warned == nil {
+			f.warned = make(map[string]bool)
+		}
+		f.warned[warning] = true
+		log.Printf("API warning: %s", warning)
+	}
+}
+
+// sleepContext waits for d, returning early with the context's error when it's cancelled.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		wait := time.Until(at)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+
+	return 0, false
+}
+
+// rateLimitReset formats the X-RateLimit-Reset header of a response as a local time.
+func rateLimitReset(resp *http.Response) string {
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return "an unknown time"
+	}
+	return time.Unix(reset, 0).Format("2006-01-02 15:04:05")
+}
+
+// getJSON fetches url with the access token and decodes the JSON response into v.
+func (f *fetcher) getJSON(url string, v interface{}) error {
+	_, err := f.getJSONPage(url, v)
+	return err
+}
+
+// getJSONPage works like getJSON for one page of a paginated list, returning
+// the URL of the next page from the Link header, or "" on the last page.
+func (f *fetcher) getJSONPage(url string, v interface{}) (string, error) {
+	// Create the request
+	req, err := http.NewRequestWithContext(f.ctx, "GET", url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Add the access token to the request header (optional)
+	if f.accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+f.accessToken)
+	}
+
+	// Ask for the body representation chosen with --body-format
+	accept := f.acceptHeader()
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	// Use a fresh cached copy as is, and ask whether an older one changed
+	var cached *cacheEntry
+	if f.cache != nil {
+		cached = f.cache.load(url, accept)
+		if cached != nil && f.cache.fresh(cached) {
+			return nextPageURL(cached.Link), decodeBody(cached.Body, v)
+		}
+		if cached != nil && cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+	}
+
+	// Send the request
+	resp, err := f.sendRequest(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Read the response body
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response body: %w", err)
+	}
+	f.stats.bytes.Add(int64(len(body)))
+	link := resp.Header.Get("Link")
+
+	// An unchanged response is served from the cache, a changed one replaces it
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		f.cache.store(url, accept, *cached)
+		return nextPageURL(cached.Link), decodeBody(cached.Body, v)
+	}
+	if resp.StatusCode == http.StatusOK && f.cache != nil && resp.Header.Get("ETag") != "" {
+		f.cache.store(url, accept, cacheEntry{ETag: resp.Header.Get("ETag"), Link: link, Body: body})
+	}
+
+	// Older servers that don't know the reactions preview refuse the request, so ask again without it
+	if resp.StatusCode == http.StatusUnsupportedMediaType && accept != f.accept {
+		if f.noReactions.CompareAndSwap(false, true) {
+			log.Print("Reactions not supported on this instance, continuing without them")
+		}
+		return f.getJSONPage(url, v)
+	}
+
+	// Check the response status code
+	if resp.StatusCode != http.StatusOK {
+		return "", newResponseError(resp, body)
+	}
+
+	return nextPageURL(link), decodeBody(body, v)
+}
+
+// decodeBody parses a JSON response body into v.
+func decodeBody(body []byte, v interface{}) error {
+	err := json.Unmarshal(body, v)
+	if err != nil {
+		return fmt.Errorf("failed to parse response body: %w", err)
+	}
+	return nil
+}
+
+// fetchPaged fetches every page of a list endpoint, following the Link
+// header. When interrupted it returns the items fetched so far with
+// errInterrupted.
+func fetchPaged[T any](f *fetcher, url string) ([]T, error) {
+	return fetchPagesFrom[T](f, firstPageURL(url), nil, nil)
+}
+
+// firstPageURL adds the page size to a list endpoint.
+func firstPageURL(url string) string {
+	separator := "?"
+	if strings.Contains(url, "?") {
+		separator = "&"
+	}
+	return url + separator + pageSizeParam()
+}
+
+// fetchPagesFrom carries on fetching pages from next, adding to the items
+// already fetched. checkpoint, if set, is called after every page with the
+// URL of the page still to come and the items of the page just fetched.
+func fetchPagesFrom[T any](f *fetcher, next string, items []T, checkpoint func(next string, page []T) error) ([]T, error) {
+	for next != "" {
+		var page []T
+		var err error
+		next, err = f.getJSONPage(next, &page)
+		if err != nil {
+			// Hand back what was fetched so far so it can still be saved
+			if f.ctx.Err() != nil {
+				return items, errInterrupted
+			}
+			return nil, err
+		}
+		f.stats.pages.Add(1)
+		items = append(items, page...)
+
+		if checkpoint != nil {
+			err = checkpoint(next, page)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return items, nil
+}
+
+// nextPageURL picks the rel="next" URL out of a Link header.
+func nextPageURL(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		sections := strings.Split(part, ";")
+		if len(sections) < 2 {
+			continue
+		}
+
+		for _, param := range sections[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(sections[0]), "<>")
+			}
+		}
+	}
+	return ""
+}
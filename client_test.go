@@ -0,0 +1,615 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "seconds", value: "30", want: 30 * time.Second, wantOK: true},
+		{name: "zero", value: "0", want: 0, wantOK: true},
+		{name: "past date", value: "Wed, 21 Oct 2015 07:28:00 GMT", want: 0, wantOK: true},
+		{name: "empty", value: "", wantOK: false},
+		{name: "negative", value: "-5", wantOK: false},
+		{name: "garbage", value: "soon", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("parseRetryAfter(%q) = %s, %v, want %s, %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfterFutureDate(t *testing.T) {
+	at := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	got, ok := parseRetryAfter(at)
+	if !ok || got < 58*time.Minute || got > time.Hour {
+		t.Errorf("parseRetryAfter(%q) = %s, %v, want about an hour", at, got, ok)
+	}
+}
+
+func TestSendRequestRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		headers      http.Header
+		maxRetries   int
+		wantStatus   int
+		wantRequests int
+	}{
+		{
+			name:         "secondary rate limit",
+			statuses:     []int{http.StatusForbidden, http.StatusOK},
+			headers:      http.Header{"Retry-After": {"0"}},
+			maxRetries:   3,
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+		},
+		{
+			name:         "forbidden without Retry-After",
+			statuses:     []int{http.StatusForbidden, http.StatusOK},
+			maxRetries:   3,
+			wantStatus:   http.StatusForbidden,
+			wantRequests: 1,
+		},
+		{
+			name:         "primary rate limit",
+			statuses:     []int{http.StatusForbidden, http.StatusOK},
+			headers:      http.Header{"Retry-After": {"0"}, "X-Ratelimit-Remaining": {"0"}},
+			maxRetries:   3,
+			wantStatus:   http.StatusForbidden,
+			wantRequests: 1,
+		},
+		{
+			name:         "server error",
+			statuses:     []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK},
+			headers:      http.Header{"Retry-After": {"0"}},
+			maxRetries:   3,
+			wantStatus:   http.StatusOK,
+			wantRequests: 3,
+		},
+		{
+			name:         "out of retries",
+			statuses:     []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusOK},
+			headers:      http.Header{"Retry-After": {"0"}},
+			maxRetries:   1,
+			wantStatus:   http.StatusBadGateway,
+			wantRequests: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statuses[requests]
+				requests++
+				if status != http.StatusOK {
+					for key, values := range tt.headers {
+						w.Header()[key] = values
+					}
+				}
+				w.WriteHeader(status)
+			}))
+			defer server.Close()
+			setFlag(t, &maxRetriesFlag, tt.maxRetries)
+
+			f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats()}
+			req, err := http.NewRequest("GET", server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := f.sendRequest(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus || requests != tt.wantRequests {
+				t.Errorf("got status %d after %d request(s), want %d after %d", resp.StatusCode, requests, tt.wantStatus, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestSendRequestRetryBudget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	setFlag(t, &maxRetriesFlag, 3)
+	setFlag(t, &retryBudgetFlag, time.Minute-time.Second)
+
+	f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats()}
+	req, err := http.NewRequest("GET", server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.sendRequest(req)
+	if err == nil || !strings.Contains(err.Error(), "retry budget exhausted") {
+		t.Errorf("sendRequest() error = %v, want the retry budget to run out", err)
+	}
+}
+
+func TestSendRequestRetryBudgetShared(t *testing.T) {
+	tests := []struct {
+		name      string
+		budget    time.Duration
+		spent     time.Duration
+		wantErr   string
+		wantSpent time.Duration
+	}{
+		{name: "within budget", budget: 2 * time.Minute, spent: 0, wantErr: "context deadline exceeded", wantSpent: time.Minute},
+		{name: "spent by earlier requests", budget: 2 * time.Minute, spent: 90 * time.Second, wantErr: "retry budget exhausted after 1m30s", wantSpent: 90 * time.Second},
+		{name: "no budget", budget: 0, spent: time.Hour, wantErr: "context deadline exceeded", wantSpent: time.Hour + time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", "60")
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer server.Close()
+			setFlag(t, &maxRetriesFlag, 3)
+			setFlag(t, &retryBudgetFlag, tt.budget)
+
+			// A retry that goes ahead sleeps until the request times out
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			f := &fetcher{ctx: ctx, client: server.Client(), stats: newRunStats()}
+			f.retrySpent.Store(int64(tt.spent))
+			req, err := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = f.sendRequest(req)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("sendRequest() error = %v, want %q", err, tt.wantErr)
+			}
+			if spent := time.Duration(f.retrySpent.Load()); spent != tt.wantSpent {
+				t.Errorf("retry time spent = %s, want %s", spent, tt.wantSpent)
+			}
+		})
+	}
+}
+
+func TestNewHTTPClientProxy(t *testing.T) {
+	tests := []struct {
+		name      string
+		proxy     string
+		wantProxy string
+		wantErr   string
+	}{
+		{name: "no proxy", proxy: "", wantProxy: "environment"},
+		{name: "HTTP proxy", proxy: "http://proxy.example.com:3128", wantProxy: "http://proxy.example.com:3128"},
+		{name: "SOCKS5 proxy", proxy: "socks5://127.0.0.1:1080"},
+		{name: "unsupported scheme", proxy: "ftp://proxy.example.com", wantErr: "unsupported proxy scheme"},
+		{name: "invalid URL", proxy: "http://[::1", wantErr: "invalid proxy URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &proxyFlag, tt.proxy)
+
+			client, err := newHTTPClient()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("newHTTPClient() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// SOCKS5 dials through the proxy instead, and without --proxy the environment decides
+			transport := client.Transport.(*http.Transport)
+			switch tt.wantProxy {
+			case "":
+				if transport.Proxy != nil {
+					t.Error("requests go through an HTTP proxy, want none")
+				}
+				return
+			case "environment":
+				return
+			}
+			req, _ := http.NewRequest("GET", "https://api.github.com", nil)
+			proxyURL, err := transport.Proxy(req)
+			if err != nil || proxyURL == nil || proxyURL.String() != tt.wantProxy {
+				t.Errorf("proxy = %v, %v, want %s", proxyURL, err, tt.wantProxy)
+			}
+		})
+	}
+}
+
+func TestNewHTTPClientIdleConns(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxIdleConns int
+		idleTimeout  time.Duration
+	}{
+		{name: "defaults", maxIdleConns: 10, idleTimeout: 90 * time.Second},
+		{name: "bulk fetch", maxIdleConns: 50, idleTimeout: 5 * time.Minute},
+		{name: "no reuse", maxIdleConns: 0, idleTimeout: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &proxyFlag, "")
+			setFlag(t, &maxIdleConnsFlag, tt.maxIdleConns)
+			setFlag(t, &idleTimeoutFlag, tt.idleTimeout)
+
+			client, err := newHTTPClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			transport := client.Transport.(*http.Transport)
+			if transport.MaxIdleConns != tt.maxIdleConns || transport.MaxIdleConnsPerHost != tt.maxIdleConns {
+				t.Errorf("idle connections = %d, %d per host, want %d", transport.MaxIdleConns, transport.MaxIdleConnsPerHost, tt.maxIdleConns)
+			}
+			if transport.IdleConnTimeout != tt.idleTimeout {
+				t.Errorf("idle timeout = %v, want %v", transport.IdleConnTimeout, tt.idleTimeout)
+			}
+		})
+	}
+}
+
+func TestNextPageURL(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+		want string
+	}{
+		{
+			name: "next and last",
+			link: `<https://api.github.com/repos/o/r/issues/1/comments?page=2>; rel="next", <https://api.github.com/repos/o/r/issues/1/comments?page=5>; rel="last"`,
+			want: "https://api.github.com/repos/o/r/issues/1/comments?page=2",
+		},
+		{
+			name: "next listed last",
+			link: `<https://api.github.com/x?page=1>; rel="prev", <https://api.github.com/x?page=3>; rel="next"`,
+			want: "https://api.github.com/x?page=3",
+		},
+		{
+			name: "last page",
+			link: `<https://api.github.com/x?page=1>; rel="first", <https://api.github.com/x?page=4>; rel="prev"`,
+			want: "",
+		},
+		{name: "no header", link: "", want: ""},
+		{name: "malformed", link: "garbage", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextPageURL(tt.link); got != tt.want {
+				t.Errorf("nextPageURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// writeTestCertificate writes a self-signed certificate and its key as PEM
+// files in dir, returning their paths.
+func writeTestCertificate(t *testing.T, dir string) (certPath, keyPath string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPath = filepath.Join(dir, "cert.pem")
+	keyPath = filepath.Join(dir, "key.pem")
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certPath, keyPath
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := writeTestCertificate(t, dir)
+	notPEM := filepath.Join(dir, "not.pem")
+	os.WriteFile(notPEM, []byte("not a certificate"), 0600)
+
+	tests := []struct {
+		name       string
+		caCert     string
+		clientCert string
+		clientKey  string
+		insecure   bool
+		wantRoots  bool
+		wantCerts  int
+		wantErr    string
+	}{
+		{name: "defaults"},
+		{name: "private CA", caCert: certPath, wantRoots: true},
+		{name: "client certificate", clientCert: certPath, clientKey: keyPath, wantCerts: 1},
+		{name: "insecure", insecure: true},
+		{name: "missing CA file", caCert: filepath.Join(dir, "missing.pem"), wantErr: "failed to read CA certificate"},
+		{name: "CA file without PEM", caCert: notPEM, wantErr: "no PEM certificates found"},
+		{name: "certificate without key", clientCert: certPath, wantErr: "must be given together"},
+		{name: "key without certificate", clientKey: keyPath, wantErr: "must be given together"},
+		{name: "mismatched key", clientCert: certPath, clientKey: notPEM, wantErr: "failed to load client certificate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &caCertFlag, tt.caCert)
+			setFlag(t, &clientCertFlag, tt.clientCert)
+			setFlag(t, &clientKeyFlag, tt.clientKey)
+			setFlag(t, &insecureFlag, tt.insecure)
+
+			config, err := newTLSConfig()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("newTLSConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if (config.RootCAs != nil) != tt.wantRoots {
+				t.Errorf("custom root CAs = %v, want %v", config.RootCAs != nil, tt.wantRoots)
+			}
+			if len(config.Certificates) != tt.wantCerts {
+				t.Errorf("%d client certificate(s), want %d", len(config.Certificates), tt.wantCerts)
+			}
+			if config.InsecureSkipVerify != tt.insecure {
+				t.Errorf("InsecureSkipVerify = %v, want %v", config.InsecureSkipVerify, tt.insecure)
+			}
+		})
+	}
+}
+
+func TestFetchPaged(t *testing.T) {
+	type listedIssue struct {
+		Number int `json:"number"`
+	}
+
+	tests := []struct {
+		name  string
+		pages []string
+		want  []int
+	}{
+		{name: "single page", pages: []string{`[{"number":1},{"number":2}]`}, want: []int{1, 2}},
+		{name: "several pages", pages: []string{`[{"number":1}]`, `[{"number":2}]`, `[{"number":3}]`}, want: []int{1, 2, 3}},
+		{name: "empty", pages: []string{`[]`}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var server *httptest.Server
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				page := 1
+				if p := r.URL.Query().Get("page"); p != "" {
+					page, _ = strconv.Atoi(p)
+				}
+				if page < len(tt.pages) {
+					w.Header().Set("Link", fmt.Sprintf(`<%s/issues?page=%d>; rel="next"`, server.URL, page+1))
+				}
+				fmt.Fprint(w, tt.pages[page-1])
+			}))
+			defer server.Close()
+
+			f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats()}
+			issues, err := fetchPaged[listedIssue](f, server.URL+"/issues")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []int
+			for _, issue := range issues {
+				got = append(got, issue.Number)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fetchPaged() = %v, want %v", got, tt.want)
+			}
+			if pages := f.stats.pages.Load(); pages != int64(len(tt.pages)) {
+				t.Errorf("counted %d pages, want %d", pages, len(tt.pages))
+			}
+		})
+	}
+}
+
+func TestFetchPagedInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The run is interrupted while the second page is being fetched
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			cancel()
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s/items?page=2>; rel="next"`, server.URL))
+		fmt.Fprint(w, `[{"id":1},{"id":2}]`)
+	}))
+	defer server.Close()
+
+	f := &fetcher{ctx: ctx, client: server.Client(), stats: newRunStats()}
+	items, err := fetchPaged[Comment](f, server.URL+"/items")
+	if !errors.Is(err, errInterrupted) {
+		t.Fatalf("fetchPaged() error = %v, want errInterrupted", err)
+	}
+	if got := commentIDs(items); !reflect.DeepEqual(got, []int64{1, 2}) {
+		t.Errorf("fetchPaged() kept %v, want the first page [1 2]", got)
+	}
+}
+
+func TestSendRequestAPIVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiVersion string
+		schema     string
+		want       string
+	}{
+		{name: "pinned", apiVersion: "2022-11-28", schema: "github", want: "2022-11-28"},
+		{name: "not pinned", apiVersion: "", schema: "github", want: ""},
+		{name: "gitea", apiVersion: "2022-11-28", schema: "gitea", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get("X-GitHub-Api-Version")
+			}))
+			defer server.Close()
+			setFlag(t, &apiVersionFlag, tt.apiVersion)
+			setFlag(t, &schemaFlag, tt.schema)
+
+			f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats()}
+			req, _ := http.NewRequest("GET", server.URL, nil)
+			resp, err := f.sendRequest(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if got != tt.want {
+				t.Errorf("X-GitHub-Api-Version = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendRequestUserAgent(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		header    string
+		want      string
+	}{
+		{name: "default", userAgent: "github-comments-fetcher/dev", want: "github-comments-fetcher/dev"},
+		{name: "configured", userAgent: "nightly-archiver/2.0", want: "nightly-archiver/2.0"},
+		{name: "--header wins", userAgent: "github-comments-fetcher/dev", header: "User-Agent: other", want: "other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get("User-Agent")
+			}))
+			defer server.Close()
+			setFlag(t, &userAgentFlag, tt.userAgent)
+			setFlag(t, &headersFlag, headerList{})
+			if tt.header != "" {
+				headersFlag.Set(tt.header)
+			}
+
+			f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats()}
+			req, _ := http.NewRequest("GET", server.URL, nil)
+			resp, err := f.sendRequest(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if got != tt.want {
+				t.Errorf("User-Agent = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWarnAPIChanges(t *testing.T) {
+	var logged strings.Builder
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	tests := []struct {
+		name    string
+		header  http.Header
+		wantLog []string
+	}{
+		{
+			name:    "sunset",
+			header:  http.Header{"Sunset": {"Wed, 01 Jan 2025 00:00:00 GMT"}},
+			wantLog: []string{"API warning: GET /repos/o/r/issues will sunset on Wed, 01 Jan 2025 00:00:00 GMT"},
+		},
+		{
+			name:    "warnings",
+			header:  http.Header{"Warning": {`299 - "Deprecated parameter"`, `299 - "Use v4"`}},
+			wantLog: []string{`API warning: 299 - "Deprecated parameter"`, `API warning: 299 - "Use v4"`},
+		},
+		{
+			name:    "nothing to report",
+			header:  http.Header{},
+			wantLog: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logged.Reset()
+			req, _ := http.NewRequest("GET", "https://api.github.com/repos/o/r/issues", nil)
+			resp := &http.Response{Header: tt.header, Request: req}
+
+			// Repeats within a run are only shown once
+			f := &fetcher{}
+			f.warnAPIChanges(resp)
+			f.warnAPIChanges(resp)
+
+			for _, want := range tt.wantLog {
+				if strings.Count(logged.String(), want) != 1 {
+					t.Errorf("log doesn't show %q once:\n%s", want, logged.String())
+				}
+			}
+			if tt.wantLog == nil && logged.Len() != 0 {
+				t.Errorf("logged %q, want nothing", logged.String())
+			}
+		})
+	}
+}
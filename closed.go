@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Who closed an issue, when and why
+type Closure struct {
+	Actor  string    `json:"actor"`
+	At     time.Time `json:"closed_at"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// findClosure picks the last closed event of an issue, as it may have been
+// reopened and closed again. It returns nil when there's none.
+func findClosure(events []issueEvent, reason string) *Closure {
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Event == "closed" {
+			return &Closure{Actor: events[i].Actor.Login, At: events[i].CreatedAt, Reason: reason}
+		}
+	}
+	return nil
+}
+
+// closedByLine reads like "Closed by @alice on 2024-05-01 10:00:00 (not_planned)".
+func closedByLine(c *Closure) string {
+	actor := "@" + c.Actor
+	if c.Actor == "" {
+		actor = displayLogin(c.Actor)
+	}
+
+	line := fmt.Sprintf("Closed by %s on %s", actor, formatTime(c.At))
+	if c.Reason != "" {
+		line += fmt.Sprintf(" (%s)", c.Reason)
+	}
+	return line
+}
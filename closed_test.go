@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFetchIssueDetailsClosedBy(t *testing.T) {
+	events := `[
+		{"event":"closed","actor":{"login":"bob"},"created_at":"2024-04-01T10:00:00Z"},
+		{"event":"reopened","actor":{"login":"alice"},"created_at":"2024-04-02T10:00:00Z"},
+		{"event":"labeled","actor":{"login":"alice"},"label":{"name":"wontfix"},"created_at":"2024-05-01T09:00:00Z"},
+		{"event":"closed","actor":{"login":"alice"},"created_at":"2024-05-01T10:00:00Z"}
+	]`
+
+	tests := []struct {
+		name       string
+		issue      string
+		want       *Closure
+		wantEvents bool
+	}{
+		{
+			name:       "closed",
+			issue:      `{"number":1,"state":"closed","state_reason":"not_planned"}`,
+			want:       &Closure{Actor: "alice", At: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), Reason: "not_planned"},
+			wantEvents: true,
+		},
+		{name: "open", issue: `{"number":1,"state":"open"}`, want: nil, wantEvents: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetchedEvents := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/repos/o/r/issues/1":
+					fmt.Fprint(w, tt.issue)
+				case "/repos/o/r/issues/1/events":
+					fetchedEvents = true
+					fmt.Fprint(w, events)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+			setFlag(t, &apiBaseURL, server.URL)
+			setFlag(t, &schemaFlag, "github")
+			setFlag(t, &closedByFlag, true)
+			setFlag(t, &watchLabelsFlag, false)
+
+			f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats()}
+			issue, err := fetchIssueDetails(f, "o", "r", "1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fetchedEvents != tt.wantEvents {
+				t.Errorf("fetched events = %v, want %v", fetchedEvents, tt.wantEvents)
+			}
+			if !reflect.DeepEqual(issue.ClosedBy, tt.want) {
+				t.Errorf("closed by = %+v, want %+v", issue.ClosedBy, tt.want)
+			}
+		})
+	}
+}
+
+func TestClosedByLine(t *testing.T) {
+	at := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		closure Closure
+		want    string
+	}{
+		{closure: Closure{Actor: "alice", At: at, Reason: "not_planned"}, want: "Closed by @alice on " + formatTime(at) + " (not_planned)"},
+		{closure: Closure{Actor: "alice", At: at}, want: "Closed by @alice on " + formatTime(at)},
+		{closure: Closure{At: at, Reason: "completed"}, want: "Closed by (ghost) on " + formatTime(at) + " (completed)"},
+	}
+
+	for _, tt := range tests {
+		if got := closedByLine(&tt.closure); got != tt.want {
+			t.Errorf("closedByLine(%+v) = %q, want %q", tt.closure, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ANSI escape codes used in terminal output
+const (
+	colorBold   = "1"
+	colorYellow = "33"
+	colorCyan   = "36"
+)
+
+// Set when the text output goes to a terminal that should get colors
+var colorEnabled bool
+
+// useColor reports whether output written to file should be colorized: it has
+// to be a terminal, and neither NO_COLOR nor --no-color may be set.
+func useColor(file *os.File) bool {
+	if noColorFlag || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// colorize wraps s in the given ANSI code when colors are enabled.
+func colorize(s, code string) string {
+	if !colorEnabled {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+// More ANSI codes, for --render-markdown
+const (
+	colorDim     = "2"
+	colorItalic  = "3"
+	colorMagenta = "35"
+	colorHeading = "1;4"
+)
+
+// Inline Markdown styled by renderMarkdown, code spans first so nothing inside them is touched
+var (
+	inlineCode  = regexp.MustCompile("`[^`\n]+`")
+	boldText    = regexp.MustCompile(`\*\*([^*\n]+)\*\*|__([^_\n]+)__`)
+	italicText  = regexp.MustCompile(`(^|[^\w*])\*([^*\n]+)\*|(^|[^\w_])_([^_\n]+)_`)
+	headingLine = regexp.MustCompile(`^#{1,6}\s+(.*?)\s*#*$`)
+	quotedLine  = regexp.MustCompile(`^\s*>`)
+)
+
+// renderBody styles the Markdown of a body for the terminal with
+// --render-markdown, and leaves it as it is otherwise.
+func renderBody(body string) string {
+	if !renderMDFlag || !colorEnabled {
+		return body
+	}
+	return renderMarkdown(body)
+}
+
+// renderMarkdown styles headings, bold and italic text, code and quotes
+// with ANSI codes, dropping the Markdown markers it replaces.
+func renderMarkdown(s string) string {
+	lines := strings.Split(s, "\n")
+	var rendered []string
+	inFence := false
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(strings.TrimSpace(line), "```"):
+			inFence = !inFence
+		case inFence:
+			rendered = append(rendered, colorize("    "+line, colorMagenta))
+		case headingLine.MatchString(line):
+			rendered = append(rendered, colorize(headingLine.FindStringSubmatch(line)[1], colorHeading))
+		case quotedLine.MatchString(line):
+			rendered = append(rendered, colorize(line, colorDim))
+		default:
+			rendered = append(rendered, renderInline(line))
+		}
+	}
+	return strings.Join(rendered, "\n")
+}
+
+// renderInline styles the code spans, bold and italic text of a line. The
+// text between code spans is styled on its own, so nothing inside a code
+// span is touched.
+func renderInline(line string) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range inlineCode.FindAllStringIndex(line, -1) {
+		b.WriteString(renderEmphasis(line[last:span[0]]))
+		b.WriteString(colorize(strings.Trim(line[span[0]:span[1]], "`"), colorMagenta))
+		last = span[1]
+	}
+	b.WriteString(renderEmphasis(line[last:]))
+	return b.String()
+}
+
+// renderEmphasis styles the bold and italic text of a piece of a line.
+func renderEmphasis(text string) string {
+	text = boldText.ReplaceAllStringFunc(text, func(match string) string {
+		groups := boldText.FindStringSubmatch(match)
+		return colorize(groups[1]+groups[2], colorBold)
+	})
+	return italicText.ReplaceAllStringFunc(text, func(match string) string {
+		groups := italicText.FindStringSubmatch(match)
+		return groups[1] + groups[3] + colorize(groups[2]+groups[4], colorItalic)
+	})
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	setFlag(t, &colorEnabled, true)
+
+	const (
+		bold    = "\x1b[1m"
+		italic  = "\x1b[3m"
+		code    = "\x1b[35m"
+		dim     = "\x1b[2m"
+		heading = "\x1b[1;4m"
+		reset   = "\x1b[0m"
+	)
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "plain", in: "nothing to see", want: "nothing to see"},
+		{name: "bold", in: "a **b** c __d__", want: "a " + bold + "b" + reset + " c " + bold + "d" + reset},
+		{name: "italic", in: "*a* and _b_", want: italic + "a" + reset + " and " + italic + "b" + reset},
+		{name: "snake_case left alone", in: "call snake_case_name", want: "call snake_case_name"},
+		{name: "code span", in: "run `go **test**` now", want: "run " + code + "go **test**" + reset + " now"},
+		{name: "emphasis around code", in: "**x** `y` *z*", want: bold + "x" + reset + " " + code + "y" + reset + " " + italic + "z" + reset},
+		{name: "heading", in: "## Steps ##", want: heading + "Steps" + reset},
+		{name: "quote", in: "> earlier", want: dim + "> earlier" + reset},
+		{name: "fence", in: "```\nx := 1\n```", want: code + "    x := 1" + reset},
+		{name: "NUL bytes and digits", in: "\x000\x00 \x0099\x00 `c`", want: "\x000\x00 \x0099\x00 " + code + "c" + reset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderMarkdown(tt.in); got != tt.want {
+				t.Errorf("renderMarkdown(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderBodyWithoutColors(t *testing.T) {
+	setFlag(t, &renderMDFlag, true)
+	setFlag(t, &colorEnabled, false)
+
+	body := "**bold** `code`"
+	if got := renderBody(body); got != body {
+		t.Errorf("renderBody(%q) = %q without colors", body, got)
+	}
+}
+
+func TestColorize(t *testing.T) {
+	tests := []struct {
+		enabled bool
+		want    string
+	}{
+		{enabled: true, want: "\x1b[36malice\x1b[0m"},
+		{enabled: false, want: "alice"},
+	}
+
+	for _, tt := range tests {
+		setFlag(t, &colorEnabled, tt.enabled)
+		if got := colorize("alice", colorCyan); got != tt.want {
+			t.Errorf("colorize() with colors %v = %q, want %q", tt.enabled, got, tt.want)
+		}
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "out.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	// Only terminals get colors, so a file never does whatever the settings
+	tests := []struct {
+		name    string
+		noColor bool
+		env     string
+	}{
+		{name: "file"},
+		{name: "--no-color", noColor: true},
+		{name: "NO_COLOR", env: "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &noColorFlag, tt.noColor)
+			t.Setenv("NO_COLOR", tt.env)
+
+			if useColor(file) {
+				t.Error("useColor() = true, want false")
+			}
+		})
+	}
+}
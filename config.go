@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Settings that belong to a single repository, kept in the inputs file instead
+var localSettings = map[string]bool{
+	"O": true, "owner": true, "R": true, "repo": true, "I": true, "issueNumber": true,
+}
+
+// globalConfigPath is where the defaults shared by every directory live:
+// github-comment-fetcher/config.yaml under $XDG_CONFIG_HOME or ~/.config.
+func globalConfigPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "github-comment-fetcher", "config.yaml"), nil
+}
+
+// applyGlobalConfig takes defaults for flags from the global config file,
+// whose keys are flag names such as base-url, format or token-file. Flags
+// given on the command line win, and the inputs file still picks the
+// repository and issue.
+func applyGlobalConfig() error {
+	path, err := globalConfigPath()
+	if err != nil {
+		return nil // No home directory, so no global config either
+	}
+	return applyConfigFile(flag.CommandLine, path)
+}
+
+// applyConfigFile sets the flags named in a config file that weren't given on
+// the command line. They count as set from then on, so presets such as
+// --minimize-noise leave them alone too.
+func applyConfigFile(flags *flag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return usageErrorf("failed to read config file: %w", err)
+	}
+
+	var settings map[string]yaml.Node
+	err = yaml.Unmarshal(data, &settings)
+	if err != nil {
+		return usageErrorf("failed to parse %s: %w", path, err)
+	}
+
+	// Aliases like -o and --output share a value, so that's what is compared
+	given := make(map[flag.Value]bool)
+	flags.Visit(func(f *flag.Flag) {
+		given[f.Value] = true
+	})
+
+	for name, node := range settings {
+		f := flags.Lookup(name)
+		switch {
+		case f == nil:
+			return usageErrorf("%s:%d: unknown setting %q; use the name of a flag, like base-url", path, node.Line, name)
+		case localSettings[name]:
+			return usageErrorf("%s:%d: %q belongs in github-comments-fetcher-inputs.txt or on the command line", path, node.Line, name)
+		case node.Kind != yaml.ScalarNode:
+			return usageErrorf("%s:%d: %q needs a single value", path, node.Line, name)
+		case given[f.Value]:
+			continue
+		}
+
+		err = flags.Set(name, node.Value)
+		if err != nil {
+			return usageErrorf("%s:%d: invalid %s: %w", path, node.Line, name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGlobalConfigPath(t *testing.T) {
+	tests := []struct {
+		name string
+		xdg  string
+		home string
+		want string
+	}{
+		{name: "XDG_CONFIG_HOME", xdg: "/xdg", home: "/home/alice", want: "/xdg/github-comment-fetcher/config.yaml"},
+		{name: "home directory", xdg: "", home: "/home/alice", want: "/home/alice/.config/github-comment-fetcher/config.yaml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_HOME", tt.xdg)
+			t.Setenv("HOME", tt.home)
+			got, err := globalConfigPath()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != filepath.FromSlash(tt.want) {
+				t.Errorf("globalConfigPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     string
+		args       []string
+		wantFormat string
+		wantBase   string
+		wantErr    string
+	}{
+		{
+			name:       "defaults from the config",
+			config:     "format: json\nbase-url: https://ghe.example.com/api/v3\n",
+			wantFormat: "json",
+			wantBase:   "https://ghe.example.com/api/v3",
+		},
+		{
+			name:       "flags win over the config",
+			config:     "format: json\nbase-url: https://ghe.example.com/api/v3\n",
+			args:       []string{"--format", "xml"},
+			wantFormat: "xml",
+			wantBase:   "https://ghe.example.com/api/v3",
+		},
+		{
+			name:       "alias given on the command line",
+			config:     "format: json\n",
+			args:       []string{"-f", "markdown"},
+			wantFormat: "markdown",
+			wantBase:   "https://api.github.com",
+		},
+		{name: "no config file", config: "", wantFormat: "text", wantBase: "https://api.github.com"},
+		{name: "unknown setting", config: "colour: red\n", wantErr: `:1: unknown setting "colour"`},
+		{name: "repository setting", config: "format: json\nowner: octocat\n", wantErr: `:2: "owner" belongs in github-comments-fetcher-inputs.txt`},
+		{name: "list value", config: "format: [json, xml]\n", wantErr: `"format" needs a single value`},
+		{name: "invalid YAML", config: "format: [json\n", wantErr: "failed to parse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var format, baseURL, owner string
+			flags := flag.NewFlagSet("test", flag.ContinueOnError)
+			flags.StringVar(&format, "format", "text", "")
+			flags.StringVar(&format, "f", "text", "")
+			flags.StringVar(&baseURL, "base-url", "https://api.github.com", "")
+			flags.StringVar(&owner, "owner", "", "")
+			err := flags.Parse(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if tt.config != "" {
+				err = os.WriteFile(path, []byte(tt.config), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = applyConfigFile(flags, path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("applyConfigFile() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format != tt.wantFormat || baseURL != tt.wantBase {
+				t.Errorf("format, base URL = %q, %q, want %q, %q", format, baseURL, tt.wantFormat, tt.wantBase)
+			}
+		})
+	}
+}
+
+func TestApplyGlobalConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	dir := filepath.Join(home, ".config", "github-comment-fetcher")
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("token-file: /secrets/token\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setFlag(t, &tokenFileFlag, "")
+
+	err = applyGlobalConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokenFileFlag != "/secrets/token" {
+		t.Errorf("token file = %q, want the one from %s", tokenFileFlag, dir)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OAuth scope asked for in the device flow, enough to read private repositories
+const deviceScope = "repo"
+
+// Wait between polls when the server doesn't say, and what slow_down adds
+// to it, as the device flow spec (RFC 8628) has it
+const devicePollInterval = 5 * time.Second
+
+// webBaseURL is the web host belonging to the API base URL, which serves the
+// OAuth endpoints.
+func webBaseURL() string {
+	if apiBaseURL == defaultBaseURL {
+		return "https://github.com"
+	}
+	return strings.TrimSuffix(apiBaseURL, "/api/v3")
+}
+
+// deviceLogin gets a token through the OAuth device flow: it shows the user
+// a code to enter in the browser and polls until they have authorized it.
+func deviceLogin(clientID string) (string, error) {
+	client, err := newHTTPClient()
+	if err != nil {
+		return "", usageErrorf("%w", err)
+	}
+	ctx := context.Background()
+
+	// Ask for a device code and show it to the user
+	var code struct {
+		DeviceCode      string `json:"device_code"`
+		UserCode        string `json:"user_code"`
+		VerificationURI string `json:"verification_uri"`
+		ExpiresIn       int    `json:"expires_in"`
+		Interval        int    `json:"interval"`
+		Error           string `json:"error"`
+		ErrorDesc       string `json:"error_description"`
+	}
+	err = postForm(ctx, client, webBaseURL()+"/login/device/code", url.Values{
+		"client_id": {clientID},
+		"scope":     {deviceScope},
+	}, &code)
+	if err != nil {
+		return "", err
+	}
+	if code.Error != "" {
+		return "", authErrorf("failed to start device login: %s", code.ErrorDesc)
+	}
+
+	fmt.Printf("Open %s and enter the code %s\n", code.VerificationURI, code.UserCode)
+
+	// Poll until the code is authorized, slowing down when asked to
+	interval := pollInterval(code.Interval)
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+	for time.Now().Before(deadline) {
+		err = sleepContext(ctx, interval)
+		if err != nil {
+			return "", err
+		}
+
+		var token struct {
+			AccessToken string `json:"access_token"`
+			Interval    int    `json:"interval"`
+			Error       string `json:"error"`
+			ErrorDesc   string `json:"error_description"`
+		}
+		err = postForm(ctx, client, webBaseURL()+"/login/oauth/access_token", url.Values{
+			"client_id":   {clientID},
+			"device_code": {code.DeviceCode},
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		}, &token)
+		if err != nil {
+			return "", err
+		}
+
+		switch token.Error {
+		case "":
+			return token.AccessToken, nil
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval = slowerPollInterval(interval, token.Interval)
+		default:
+			return "", authErrorf("device login failed: %s", token.ErrorDesc)
+		}
+	}
+
+	return "", authErrorf("the device code expired before it was authorized")
+}
+
+// pollInterval is the wait between polls the server asked for in seconds,
+// or the default when it didn't.
+func pollInterval(seconds int) time.Duration {
+	if seconds <= 0 {
+		return devicePollInterval
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// slowerPollInterval is the wait after a slow_down answer: longer by the
+// default interval, or the new interval the server gives if that's longer.
+func slowerPollInterval(current time.Duration, seconds int) time.Duration {
+	slower := current + devicePollInterval
+	if asked := time.Duration(seconds) * time.Second; asked > slower {
+		return asked
+	}
+	return slower
+}
+
+// postForm posts a form to an OAuth endpoint and decodes the JSON answer into v.
+func postForm(ctx context.Context, client *http.Client, endpoint string, form url.Values, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", userAgentFlag)
+
+	err = waitForRequest(ctx)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return newResponseError(resp, body)
+	}
+
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		return fmt.Errorf("failed to parse response body: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPollInterval(t *testing.T) {
+	tests := []struct {
+		seconds int
+		want    time.Duration
+	}{
+		{seconds: 0, want: 5 * time.Second},
+		{seconds: -1, want: 5 * time.Second},
+		{seconds: 1, want: time.Second},
+		{seconds: 10, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := pollInterval(tt.seconds); got != tt.want {
+			t.Errorf("pollInterval(%d) = %s, want %s", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestSlowerPollInterval(t *testing.T) {
+	tests := []struct {
+		current time.Duration
+		seconds int
+		want    time.Duration
+	}{
+		{current: 5 * time.Second, seconds: 0, want: 10 * time.Second},
+		{current: 5 * time.Second, seconds: 10, want: 10 * time.Second},
+		{current: 5 * time.Second, seconds: 30, want: 30 * time.Second},
+		{current: 20 * time.Second, seconds: 6, want: 25 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := slowerPollInterval(tt.current, tt.seconds); got != tt.want {
+			t.Errorf("slowerPollInterval(%s, %d) = %s, want %s", tt.current, tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestWebBaseURL(t *testing.T) {
+	tests := []struct {
+		api  string
+		want string
+	}{
+		{api: defaultBaseURL, want: "https://github.com"},
+		{api: "https://ghe.example.com/api/v3", want: "https://ghe.example.com"},
+	}
+
+	for _, tt := range tests {
+		setFlag(t, &apiBaseURL, tt.api)
+		if got := webBaseURL(); got != tt.want {
+			t.Errorf("webBaseURL() for %s = %s, want %s", tt.api, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// JSON output as read back by --diff
+type snapshot struct {
+	Issue    *jsonIssue    `json:"issue"`
+	Comments []jsonComment `json:"comments"`
+}
+
+// readSnapshot reads a file written with --format json.
+func readSnapshot(path string) (snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return snapshot{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var s snapshot
+	err = json.Unmarshal(data, &s)
+	if err != nil {
+		return snapshot{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// diffFiles compares two JSON outputs of the same issue and writes what changed.
+func diffFiles(out io.Writer, oldPath, newPath string) error {
+	older, err := readSnapshot(oldPath)
+	if err != nil {
+		return err
+	}
+	newer, err := readSnapshot(newPath)
+	if err != nil {
+		return err
+	}
+
+	changes := diffSnapshots(older, newer)
+	if len(changes) == 0 {
+		changes = []string{"No changes."}
+	}
+
+	_, err = io.WriteString(out, strings.Join(changes, "\n")+"\n")
+	if err != nil {
+		return fmt.Errorf("failed to write diff: %w", err)
+	}
+	return nil
+}
+
+// diffSnapshots lists the changes to the issue and its comments, one per
+// line. Comments are matched by ID, or by author and creation time for
+// snapshots written before IDs were included.
+func diffSnapshots(older, newer snapshot) []string {
+	var changes []string
+
+	// Compare the issue itself
+	if older.Issue != nil && newer.Issue != nil {
+		if older.Issue.Title != newer.Issue.Title {
+			changes = append(changes, fmt.Sprintf("Title changed: %q → %q", older.Issue.Title, newer.Issue.Title))
+		}
+		oldState, newState := snapshotState(older.Issue), snapshotState(newer.Issue)
+		if oldState != newState {
+			changes = append(changes, fmt.Sprintf("State changed: %s → %s", oldState, newState))
+		}
+		if older.Issue.Body != newer.Issue.Body {
+			changes = append(changes, "Issue body edited")
+		}
+	}
+
+	describe := func(c jsonComment) string {
+		return fmt.Sprintf("comment by %s at %s", displayLogin(c.Author), c.CreatedAt)
+	}
+
+	// Index the older comments both ways, as either snapshot may lack IDs
+	byID := make(map[int64]int)
+	byTime := make(map[string][]int)
+	for i, c := range older.Comments {
+		if c.ID != 0 {
+			byID[c.ID] = i
+		}
+		byTime[commentTimeKey(c)] = append(byTime[commentTimeKey(c)], i)
+	}
+	matched := make([]bool, len(older.Comments))
+	match := func(c jsonComment) (int, bool) {
+		if i, ok := byID[c.ID]; ok && c.ID != 0 && !matched[i] {
+			return i, true
+		}
+		for _, i := range byTime[commentTimeKey(c)] {
+			if !matched[i] && (c.ID == 0 || older.Comments[i].ID == 0) {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+
+	// Then the comments, in the order of the newer snapshot
+	for _, c := range newer.Comments {
+		i, ok := match(c)
+		if !ok {
+			changes = append(changes, "+ Added "+describe(c))
+			continue
+		}
+		matched[i] = true
+		if older.Comments[i].Body != c.Body {
+			changes = append(changes, "~ Edited "+describe(c))
+		}
+	}
+	for i, c := range older.Comments {
+		if !matched[i] {
+			changes = append(changes, "- Removed "+describe(c))
+		}
+	}
+
+	return changes
+}
+
+// commentTimeKey identifies a comment by author and creation time, in UTC so
+// snapshots written with different --timezone values still match.
+func commentTimeKey(c jsonComment) string {
+	createdAt := c.CreatedAt
+	if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
+		createdAt = t.UTC().Format(time.RFC3339)
+	}
+	return c.Author + "\x00" + createdAt
+}
+
+// snapshotState shows the state of an issue with the reason it was closed.
+func snapshotState(issue *jsonIssue) string {
+	if issue.StateReason == "" || issue.State == "open" {
+		return issue.State
+	}
+	return fmt.Sprintf("%s (%s)", issue.State, issue.StateReason)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	issue := &jsonIssue{Title: "Crash", Body: "It crashes", State: "open"}
+
+	tests := []struct {
+		name  string
+		older snapshot
+		newer snapshot
+		want  []string
+	}{
+		{
+			name:  "no changes",
+			older: snapshot{Issue: issue, Comments: []jsonComment{{ID: 1, Author: "a", Body: "x", CreatedAt: "2024-01-01T10:00:00Z"}}},
+			newer: snapshot{Issue: issue, Comments: []jsonComment{{ID: 1, Author: "a", Body: "x", CreatedAt: "2024-01-01T10:00:00Z"}}},
+		},
+		{
+			name:  "issue changes",
+			older: snapshot{Issue: issue},
+			newer: snapshot{Issue: &jsonIssue{Title: "Crash on start", Body: "It crashes!", State: "closed", StateReason: "completed"}},
+			want: []string{
+				`Title changed: "Crash" → "Crash on start"`,
+				"State changed: open → closed (completed)",
+				"Issue body edited",
+			},
+		},
+		{
+			name: "same author in the same second",
+			older: snapshot{Comments: []jsonComment{
+				{ID: 1, Author: "a", Body: "first", CreatedAt: "2024-01-01T10:00:00Z"},
+				{ID: 2, Author: "a", Body: "second", CreatedAt: "2024-01-01T10:00:00Z"},
+			}},
+			newer: snapshot{Comments: []jsonComment{
+				{ID: 1, Author: "a", Body: "first", CreatedAt: "2024-01-01T10:00:00Z"},
+				{ID: 2, Author: "a", Body: "second, edited", CreatedAt: "2024-01-01T10:00:00Z"},
+				{ID: 3, Author: "b", Body: "third", CreatedAt: "2024-01-02T10:00:00Z"},
+			}},
+			want: []string{
+				"~ Edited comment by a at 2024-01-01T10:00:00Z",
+				"+ Added comment by b at 2024-01-02T10:00:00Z",
+			},
+		},
+		{
+			name:  "written with different time zones",
+			older: snapshot{Comments: []jsonComment{{ID: 1, Author: "a", Body: "x", CreatedAt: "2024-01-01T10:00:00Z"}}},
+			newer: snapshot{Comments: []jsonComment{{ID: 1, Author: "a", Body: "x", CreatedAt: "2024-01-01T12:00:00+02:00"}}},
+		},
+		{
+			name:  "older snapshot without IDs",
+			older: snapshot{Comments: []jsonComment{{Author: "a", Body: "x", CreatedAt: "2024-01-01T11:00:00+01:00"}, {Author: "b", Body: "y", CreatedAt: "2024-01-01T12:00:00Z"}}},
+			newer: snapshot{Comments: []jsonComment{{ID: 1, Author: "a", Body: "x2", CreatedAt: "2024-01-01T10:00:00Z"}}},
+			want: []string{
+				"~ Edited comment by a at 2024-01-01T10:00:00Z",
+				"- Removed comment by b at 2024-01-01T12:00:00Z",
+			},
+		},
+		{
+			name:  "deleted and reposted in the same second",
+			older: snapshot{Comments: []jsonComment{{ID: 1, Author: "a", Body: "x", CreatedAt: "2024-01-01T10:00:00Z"}}},
+			newer: snapshot{Comments: []jsonComment{{ID: 2, Author: "a", Body: "x", CreatedAt: "2024-01-01T10:00:00Z"}}},
+			want: []string{
+				"+ Added comment by a at 2024-01-01T10:00:00Z",
+				"- Removed comment by a at 2024-01-01T10:00:00Z",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffSnapshots(tt.older, tt.newer)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffSnapshots() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Process exit codes
+const (
+	exitOK          = 0 // everything was fetched and written
+	exitUsage       = 1 // bad flags or inputs, or any other hard failure
+	exitAuth        = 2 // missing, invalid or insufficient token
+	exitNotFound    = 3 // the repository, issue or commit doesn't exist
+	exitRateLimited = 4 // GitHub refused because of rate limiting
+	exitPartial     = 5 // some of several targets failed
+	exitEmpty       = 6 // no comments were found with --fail-if-empty
+	exitInterrupted = 130
+)
+
+// Help text describing the exit codes
+const exitCodesUsage = `Exit codes:
+  0  success
+  1  usage error or other failure
+  2  authentication or permission error
+  3  not found
+  4  rate limited
+  5  partial failure (some of several issues failed)
+  6  no comments, with --fail-if-empty
+  130  interrupted with Ctrl-C
+`
+
+// Returned along with whatever was fetched before the user hit Ctrl-C
+var errInterrupted = errors.New("interrupted")
+
+// exitError attaches an exit code to an error.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// usageErrorf creates an error reported with the usage exit code.
+func usageErrorf(format string, args ...interface{}) error {
+	return &exitError{code: exitUsage, err: fmt.Errorf(format, args...)}
+}
+
+// authErrorf creates an error reported with the authentication exit code.
+func authErrorf(format string, args ...interface{}) error {
+	return &exitError{code: exitAuth, err: fmt.Errorf(format, args...)}
+}
+
+// APIError is returned when GitHub answers with an unexpected status.
+type APIError struct {
+	StatusCode int
+	Status     string
+	Message    string // GitHub's explanation from the error body, if any
+}
+
+func (e *APIError) Error() string {
+	message := ""
+	if e.Message != "" {
+		message = " (" + e.Message + ")"
+	}
+	return fmt.Sprintf("request failed with status: %s%s", e.Status, message)
+}
+
+// RateLimitError is returned when GitHub refuses a request because of rate
+// limiting. It unwraps to the APIError describing the response.
+type RateLimitError struct {
+	ResetAt time.Time // when requests are allowed again, zero if GitHub didn't say
+	Err     *APIError
+}
+
+func (e *RateLimitError) Error() string {
+	message := ""
+	if e.Err.Message != "" {
+		message = " (" + e.Err.Message + ")"
+	}
+
+	reset := ""
+	if !e.ResetAt.IsZero() {
+		reset = "; it resets at " + e.ResetAt.Format("2006-01-02 15:04:05")
+	}
+	return fmt.Sprintf("request was rate limited with status: %s%s%s", e.Err.Status, message, reset)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// newResponseError describes a response that didn't have the expected status,
+// picking up the message GitHub puts in error bodies. Rate limited responses
+// give a *RateLimitError, anything else an *APIError.
+func newResponseError(resp *http.Response, body []byte) error {
+	var errorBody struct {
+		Message string `json:"message"`
+	}
+	_ = json.Unmarshal(body, &errorBody)
+	apiErr := &APIError{StatusCode: resp.StatusCode, Status: resp.Status, Message: errorBody.Message}
+
+	rateLimited := resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusForbidden && (resp.Header.Get("X-RateLimit-Remaining") == "0" || resp.Header.Get("Retry-After") != ""))
+	if !rateLimited {
+		return apiErr
+	}
+
+	// Work out when to try again from the reset time or Retry-After
+	rateErr := &RateLimitError{Err: apiErr}
+	if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		rateErr.ResetAt = time.Unix(reset, 0)
+	} else if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+		rateErr.ResetAt = time.Now().Add(wait)
+	}
+	return rateErr
+}
+
+// permissionError turns the 403 GitHub returns when a fine-grained token can't
+// read a repository's issues into an explanation of which scope is missing.
+func permissionError(err error, owner, repo string) error {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden &&
+		strings.Contains(apiErr.Message, "Resource not accessible") {
+		return authErrorf("token lacks permission to read issues on %s/%s; grant the Issues read scope", owner, repo)
+	}
+	return err
+}
+
+// exitCode picks the process exit code for an error returned by run.
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+
+	var rateErr *RateLimitError
+	if errors.As(err, &rateErr) {
+		return exitRateLimited
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return exitAuth
+		case http.StatusNotFound:
+			return exitNotFound
+		}
+	}
+
+	return exitUsage
+}
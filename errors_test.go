@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNewResponseError(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		headers       http.Header
+		body          string
+		wantRateLimit bool
+		wantReset     time.Time
+		wantMessage   string
+	}{
+		{
+			name:        "not found",
+			status:      http.StatusNotFound,
+			body:        `{"message":"Not Found"}`,
+			wantMessage: "request failed with status: 404 Not Found (Not Found)",
+		},
+		{
+			name:        "body without a message",
+			status:      http.StatusInternalServerError,
+			body:        `<html>`,
+			wantMessage: "request failed with status: 500 Internal Server Error",
+		},
+		{
+			name:        "forbidden",
+			status:      http.StatusForbidden,
+			body:        `{"message":"Resource not accessible by integration"}`,
+			wantMessage: "request failed with status: 403 Forbidden (Resource not accessible by integration)",
+		},
+		{
+			name:          "primary rate limit",
+			status:        http.StatusForbidden,
+			headers:       http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {"1700000000"}},
+			body:          `{"message":"API rate limit exceeded"}`,
+			wantRateLimit: true,
+			wantReset:     time.Unix(1700000000, 0),
+		},
+		{
+			name:          "too many requests",
+			status:        http.StatusTooManyRequests,
+			wantRateLimit: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: tt.status,
+				Status:     fmt.Sprintf("%d %s", tt.status, http.StatusText(tt.status)),
+				Header:     tt.headers,
+			}
+			if resp.Header == nil {
+				resp.Header = http.Header{}
+			}
+			err := newResponseError(resp, []byte(tt.body))
+
+			var rateErr *RateLimitError
+			if errors.As(err, &rateErr) != tt.wantRateLimit {
+				t.Fatalf("newResponseError() = %T, want a rate limit error: %v", err, tt.wantRateLimit)
+			}
+			if tt.wantRateLimit && !rateErr.ResetAt.Equal(tt.wantReset) {
+				t.Errorf("ResetAt = %s, want %s", rateErr.ResetAt, tt.wantReset)
+			}
+
+			// Rate limit errors still carry the API error
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status {
+				t.Errorf("newResponseError() doesn't unwrap to an APIError with status %d", tt.status)
+			}
+			if tt.wantMessage != "" && err.Error() != tt.wantMessage {
+				t.Errorf("Error() = %q, want %q", err.Error(), tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "success", err: nil, want: exitOK},
+		{name: "usage", err: usageErrorf("bad flag"), want: exitUsage},
+		{name: "auth", err: authErrorf("no token"), want: exitAuth},
+		{name: "unauthorized", err: &APIError{StatusCode: http.StatusUnauthorized}, want: exitAuth},
+		{name: "not found", err: fmt.Errorf("failed to fetch: %w", &APIError{StatusCode: http.StatusNotFound}), want: exitNotFound},
+		{name: "rate limited", err: &RateLimitError{Err: &APIError{StatusCode: http.StatusForbidden}}, want: exitRateLimited},
+		{name: "other", err: errors.New("boom"), want: exitUsage},
+		{name: "interrupted", err: &exitError{code: exitInterrupted, err: errInterrupted}, want: exitInterrupted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPermissionError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantAuth bool
+	}{
+		{name: "fine-grained token without Issues scope", err: &APIError{StatusCode: http.StatusForbidden, Message: "Resource not accessible by personal access token"}, wantAuth: true},
+		{name: "other forbidden", err: &APIError{StatusCode: http.StatusForbidden, Message: "Must have admin rights"}},
+		{name: "not found", err: &APIError{StatusCode: http.StatusNotFound, Message: "Resource not accessible"}},
+		{name: "not an API error", err: errors.New("connection refused")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := permissionError(tt.err, "o", "r")
+			if !tt.wantAuth {
+				if got != tt.err {
+					t.Errorf("permissionError() = %v, want the error unchanged", got)
+				}
+				return
+			}
+			if exitCode(got) != exitAuth || got.Error() != "token lacks permission to read issues on o/r; grant the Issues read scope" {
+				t.Errorf("permissionError() = %v (exit %d), want an auth error explaining the scope", got, exitCode(got))
+			}
+		})
+	}
+}
+
+func TestCheckEmpty(t *testing.T) {
+	tests := []struct {
+		name  string
+		saved []savedThread
+		want  int
+	}{
+		{name: "comments", saved: []savedThread{{outputFile: "a.txt", count: 1}}, want: 0},
+		{name: "no comments", saved: []savedThread{{outputFile: "a.txt"}}, want: exitEmpty},
+		{name: "one of several empty", saved: []savedThread{{outputFile: "a.txt", count: 1}, {outputFile: "b.txt"}}, want: exitEmpty},
+		{name: "nothing saved", saved: nil, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkEmpty(tt.saved)
+			got := 0
+			if err != nil {
+				got = exitCode(err)
+			}
+			if got != tt.want {
+				t.Errorf("exit code = %d, want %d (error %v)", got, tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Media types selecting the body representation for each --body-format
+var bodyMediaTypes = map[string]string{
+	"raw":  "application/vnd.github.raw+json",
+	"text": "application/vnd.github.text+json",
+	"html": "application/vnd.github.html+json",
+}
+
+// selectBody picks the body representation chosen with --body-format.
+func selectBody(raw, text, html string) string {
+	switch bodyFormatFlag {
+	case "text":
+		return text
+	case "html":
+		return html
+	default:
+		return raw
+	}
+}
+
+// issueURL is the REST endpoint of an issue or PR.
+func issueURL(owner, repo, issueNumber string) string {
+	return fmt.Sprintf("%s/repos/%s/%s/issues/%s", apiBaseURL, owner, repo, issueNumber)
+}
+
+// commitCommentsURL is the REST endpoint listing the comments of a commit.
+func commitCommentsURL(owner, repo, sha string) string {
+	return fmt.Sprintf("%s/repos/%s/%s/commits/%s/comments", apiBaseURL, owner, repo, sha)
+}
+
+// printURLs writes the API URLs fetching the jobs starts with: the issue
+// and the first page of its comments, or the comments of the --sha commit.
+func printURLs(out io.Writer, jobs []target) {
+	for _, job := range jobs {
+		if typeFlag == "commit" {
+			fmt.Fprintln(out, firstPageURL(commitCommentsURL(job.Owner, job.Repo, shaFlag)))
+			continue
+		}
+
+		if includeIssueFlag {
+			fmt.Fprintln(out, issueURL(job.Owner, job.Repo, job.IssueNumber))
+		}
+		if graphqlFlag {
+			fmt.Fprintf(out, "POST %s (comments of %s/%s#%s)\n", graphqlURL(), job.Owner, job.Repo, job.IssueNumber)
+		} else if !bodyOnlyFlag {
+			fmt.Fprintln(out, firstPageURL(issueURL(job.Owner, job.Repo, job.IssueNumber)+"/comments"))
+		}
+	}
+}
+
+// fetchIssue fetches an issue or PR.
+func (f *fetcher) fetchIssue(owner, repo, issueNumber string) (Issue, error) {
+	if schemaFlag == "gitea" {
+		issue, err := f.fetchGiteaIssue(owner, repo, issueNumber)
+		if err != nil {
+			return Issue{}, fmt.Errorf("failed to fetch issue: %w", permissionError(err, owner, repo))
+		}
+		return issue, nil
+	}
+
+	var issue Issue
+	err := f.getJSON(issueURL(owner, repo, issueNumber), &issue)
+	if err != nil {
+		return Issue{}, fmt.Errorf("failed to fetch issue: %w", permissionError(err, owner, repo))
+	}
+
+	issue.Body = selectBody(issue.Body, issue.BodyText, issue.BodyHTML)
+	return issue, nil
+}
+
+// fetchPullRequest fetches the pull request details of a PR.
+func (f *fetcher) fetchPullRequest(owner, repo, number string) (*PullRequest, error) {
+	var pullRequest PullRequest
+	err := f.getJSON(fmt.Sprintf("%s/repos/%s/%s/pulls/%s", apiBaseURL, owner, repo, number), &pullRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pull request: %w", err)
+	}
+	return &pullRequest, nil
+}
+
+// fetchComments fetches the comments of an issue or PR, through GraphQL when
+// --graphql is set.
+func (f *fetcher) fetchComments(owner, repo, issueNumber string) ([]Comment, error) {
+	var comments []Comment
+	var err error
+
+	if graphqlFlag {
+		number, convErr := strconv.Atoi(issueNumber)
+		if convErr != nil {
+			return nil, usageErrorf("invalid issue number %q: %w", issueNumber, convErr)
+		}
+		comments, err = f.fetchCommentsGraphQL(owner, repo, number)
+	} else if f.resume != nil {
+		comments, err = f.fetchCommentPagesResumable(issueURL(owner, repo, issueNumber)+"/comments", stateKey(owner, repo, issueNumber))
+	} else {
+		comments, err = f.fetchCommentPages(issueURL(owner, repo, issueNumber) + "/comments")
+	}
+	if errors.Is(err, errInterrupted) {
+		return comments, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch comments: %w", permissionError(err, owner, repo))
+	}
+
+	return comments, nil
+}
+
+// fetchComment fetches a single issue or PR comment by its ID.
+func (f *fetcher) fetchComment(owner, repo string, id int64) (Comment, error) {
+	var comment Comment
+	err := f.getJSON(fmt.Sprintf("%s/repos/%s/%s/issues/comments/%d", apiBaseURL, owner, repo, id), &comment)
+	if err != nil {
+		return Comment{}, fmt.Errorf("failed to fetch comment: %w", permissionError(err, owner, repo))
+	}
+
+	comment.Body = selectBody(comment.Body, comment.BodyText, comment.BodyHTML)
+	return comment, nil
+}
+
+// fetchCommitComments fetches the comments made on a commit.
+func (f *fetcher) fetchCommitComments(owner, repo, sha string) ([]Comment, error) {
+	comments, err := f.fetchCommentPages(commitCommentsURL(owner, repo, sha))
+	if errors.Is(err, errInterrupted) {
+		return comments, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch comments: %w", err)
+	}
+	return comments, nil
+}
+
+// fetchCommentPages fetches every page of a comments listing.
+func (f *fetcher) fetchCommentPages(url string) ([]Comment, error) {
+	if schemaFlag == "gitea" {
+		return f.fetchGiteaComments(url)
+	}
+
+	comments, err := fetchPaged[Comment](f, url)
+	if err != nil && !errors.Is(err, errInterrupted) {
+		return nil, err
+	}
+
+	for i := range comments {
+		comments[i].Body = selectBody(comments[i].Body, comments[i].BodyText, comments[i].BodyHTML)
+	}
+
+	return comments, err
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFetchCommitComments(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/o/r/commits/abc123/comments" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `[{"body":"one"},{"body":"two","path":"main.go","position":12}]`)
+	}))
+	defer server.Close()
+
+	f := &fetcher{ctx: context.Background(), client: serverClient(server), stats: newRunStats()}
+	comments, err := f.fetchCommitComments("o", "r", "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(comments) != 2 {
+		t.Fatalf("got %d comments, want 2", len(comments))
+	}
+	if last := comments[1]; last.Path != "main.go" || last.Position == nil || *last.Position != 12 {
+		t.Errorf("last comment is on %q:%v, want main.go:12", last.Path, last.Position)
+	}
+}
+
+func TestFetchCommitCommentsNotFound(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	f := &fetcher{ctx: context.Background(), client: serverClient(server), stats: newRunStats()}
+	_, err := f.fetchCommitComments("o", "r", "missing")
+	if exitCode(err) != exitNotFound {
+		t.Errorf("fetchCommitComments() error = %v, want a not found error", err)
+	}
+}
+
+func TestFetchCommentsFollowsPages(t *testing.T) {
+	var queries []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/o/r/issues/1/comments?per_page=100&page=2>; rel="next"`, server.URL))
+			fmt.Fprint(w, `[{"body":"one"},{"body":"two"}]`)
+		case "2":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/o/r/issues/1/comments?per_page=100&page=3>; rel="next"`, server.URL))
+			fmt.Fprint(w, `[{"body":"three"}]`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer server.Close()
+	setFlag(t, &graphqlFlag, false)
+
+	f := &fetcher{ctx: context.Background(), client: serverClient(server), stats: newRunStats()}
+	comments, err := f.fetchComments("o", "r", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var bodies []string
+	for _, comment := range comments {
+		bodies = append(bodies, comment.Body)
+	}
+	if !reflect.DeepEqual(bodies, []string{"one", "two", "three"}) {
+		t.Errorf("comments = %q, want [one two three]", bodies)
+	}
+	want := []string{"per_page=100", "per_page=100&page=2", "per_page=100&page=3"}
+	if !reflect.DeepEqual(queries, want) {
+		t.Errorf("queries = %q, want %q", queries, want)
+	}
+}
+
+func TestSelectBody(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: "", want: "**raw**"},
+		{format: "raw", want: "**raw**"},
+		{format: "text", want: "raw"},
+		{format: "html", want: "<p><strong>raw</strong></p>"},
+	}
+
+	for _, tt := range tests {
+		setFlag(t, &bodyFormatFlag, tt.format)
+		if got := selectBody("**raw**", "raw", "<p><strong>raw</strong></p>"); got != tt.want {
+			t.Errorf("selectBody() with --body-format %q = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestFetchIssueBodyFormat(t *testing.T) {
+	var accept string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+		fmt.Fprint(w, `{"number":1,"body":"**hi**","body_text":"hi","body_html":"<p><strong>hi</strong></p>"}`)
+	}))
+	defer server.Close()
+	setFlag(t, &bodyFormatFlag, "text")
+
+	f := &fetcher{ctx: context.Background(), client: server.Client(), accept: bodyMediaTypes["text"], stats: newRunStats()}
+	var issue Issue
+	err := f.getJSON(server.URL+"/repos/o/r/issues/1", &issue)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if accept != "application/vnd.github.text+json" {
+		t.Errorf("Accept = %q, want the text media type", accept)
+	}
+	if got := selectBody(issue.Body, issue.BodyText, issue.BodyHTML); got != "hi" {
+		t.Errorf("body = %q, want the text representation", got)
+	}
+}
+
+func TestFetchComment(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/o/r/issues/comments/42" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Not Found"}`)
+			return
+		}
+		fmt.Fprint(w, `{"id":42,"body":"**raw**","body_text":"raw","user":{"login":"alice"}}`)
+	}))
+	defer server.Close()
+	setFlag(t, &apiBaseURL, server.URL)
+
+	tests := []struct {
+		name       string
+		id         int64
+		bodyFormat string
+		wantBody   string
+		wantExit   int
+	}{
+		{name: "raw body", id: 42, wantBody: "**raw**"},
+		{name: "text body", id: 42, bodyFormat: "text", wantBody: "raw"},
+		{name: "missing", id: 7, wantExit: exitNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &bodyFormatFlag, tt.bodyFormat)
+
+			f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats()}
+			comment, err := f.fetchComment("o", "r", tt.id)
+			if exitCode(err) != tt.wantExit {
+				t.Fatalf("fetchComment() error = %v, want exit code %d", err, tt.wantExit)
+			}
+			if comment.Body != tt.wantBody {
+				t.Errorf("body = %q, want %q", comment.Body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestPrintURLs(t *testing.T) {
+	setFlag(t, &apiBaseURL, "https://api.github.com")
+	setFlag(t, &schemaFlag, "github")
+	setFlag(t, &shaFlag, "abc123")
+	jobs := []target{{Owner: "o", Repo: "r", IssueNumber: "1"}, {Owner: "o", Repo: "r", IssueNumber: "2"}}
+
+	tests := []struct {
+		name         string
+		typ          string
+		includeIssue bool
+		graphql      bool
+		bodyOnly     bool
+		want         string
+	}{
+		{
+			name: "comments",
+			typ:  "issue",
+			want: "https://api.github.com/repos/o/r/issues/1/comments?per_page=100\n" +
+				"https://api.github.com/repos/o/r/issues/2/comments?per_page=100\n",
+		},
+		{
+			name:         "with issue",
+			typ:          "issue",
+			includeIssue: true,
+			want: "https://api.github.com/repos/o/r/issues/1\n" +
+				"https://api.github.com/repos/o/r/issues/1/comments?per_page=100\n" +
+				"https://api.github.com/repos/o/r/issues/2\n" +
+				"https://api.github.com/repos/o/r/issues/2/comments?per_page=100\n",
+		},
+		{
+			name:         "body only",
+			typ:          "issue",
+			includeIssue: true,
+			bodyOnly:     true,
+			want: "https://api.github.com/repos/o/r/issues/1\n" +
+				"https://api.github.com/repos/o/r/issues/2\n",
+		},
+		{
+			name:    "graphql",
+			typ:     "issue",
+			graphql: true,
+			want: "POST https://api.github.com/graphql (comments of o/r#1)\n" +
+				"POST https://api.github.com/graphql (comments of o/r#2)\n",
+		},
+		{
+			name:         "commit",
+			typ:          "commit",
+			includeIssue: true,
+			want: "https://api.github.com/repos/o/r/commits/abc123/comments?per_page=100\n" +
+				"https://api.github.com/repos/o/r/commits/abc123/comments?per_page=100\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &typeFlag, tt.typ)
+			setFlag(t, &includeIssueFlag, tt.includeIssue)
+			setFlag(t, &graphqlFlag, tt.graphql)
+			setFlag(t, &bodyOnlyFlag, tt.bodyOnly)
+
+			var out strings.Builder
+			printURLs(&out, jobs)
+			if out.String() != tt.want {
+				t.Errorf("printURLs() =\n%s\nwant\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
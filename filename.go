@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Longest value substituted into an output file name
+const maxFilenamePart = 100
+
+// sanitizeFilename makes s safe to use as a single path element by replacing
+// path separators, dropping ".." and control characters and trimming the result.
+func sanitizeFilename(s string) string {
+	s = strings.ReplaceAll(s, "..", "")
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '/' || r == '\\' || r == ':':
+			return '-'
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, s)
+	s = strings.Trim(s, " .-")
+
+	if runes := []rune(s); len(runes) > maxFilenamePart {
+		s = strings.TrimRight(string(runes[:maxFilenamePart]), " .-")
+	}
+	if s == "" {
+		s = "untitled"
+	}
+	return s
+}
+
+// expandOutputName fills in the {number}, {title} and {author} placeholders
+// of an output file name. The values are sanitized, and the result must stay
+// inside the directory named before the first placeholder.
+func expandOutputName(pattern, issueNumber string, issue Issue) (string, error) {
+	if !strings.Contains(pattern, "{") {
+		return pattern, nil
+	}
+
+	// S3 keys are checked like paths inside the bucket
+	scheme := ""
+	if strings.HasPrefix(pattern, s3Scheme) {
+		scheme, pattern = s3Scheme, strings.TrimPrefix(pattern, s3Scheme)
+	}
+
+	replacer := strings.NewReplacer(
+		"{number}", sanitizeFilename(issueNumber),
+		"{title}", sanitizeFilename(issue.Title),
+		"{author}", sanitizeFilename(issue.User.Login),
+	)
+	expanded := filepath.Clean(replacer.Replace(pattern))
+
+	// Whatever the values contained, the file has to end up in the intended
+	// directory, which placeholders in directory names can only extend
+	dir := filepath.Dir(pattern[:strings.Index(pattern, "{")])
+	rel, err := filepath.Rel(dir, expanded)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("output file %q escapes the output directory %q", expanded, dir)
+	}
+
+	if scheme != "" {
+		return scheme + filepath.ToSlash(expanded), nil
+	}
+	return expanded, nil
+}
+
+// makeOutputDir creates the directory an expanded output file name points
+// into, as placeholders can name directories that don't exist yet.
+func makeOutputDir(outputFile string) error {
+	if strings.HasPrefix(outputFile, s3Scheme) {
+		return nil
+	}
+	err := os.MkdirAll(filepath.Dir(outputFile), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Crash on startup", "Crash on startup"},
+		{"a/b\\c:d", "a-b-c-d"},
+		{"../../etc/passwd", "etc-passwd"},
+		{"tab\there\x00", "tabhere"},
+		{" .-hidden-. ", "hidden"},
+		{"", "untitled"},
+		{"..", "untitled"},
+	}
+
+	for _, tt := range tests {
+		if got := sanitizeFilename(tt.in); got != tt.want {
+			t.Errorf("sanitizeFilename(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	long := ""
+	for i := 0; i < 150; i++ {
+		long += "é"
+	}
+	if got := []rune(sanitizeFilename(long)); len(got) != maxFilenamePart {
+		t.Errorf("sanitizeFilename of 150 runes kept %d runes, want %d", len(got), maxFilenamePart)
+	}
+}
+
+func TestExpandOutputName(t *testing.T) {
+	issue := Issue{Title: "Fix ../../ escape: now", User: User{Login: "octocat"}}
+
+	tests := []struct {
+		name    string
+		pattern string
+		want    string
+		wantErr bool
+	}{
+		{name: "no placeholders", pattern: "out/comments.txt", want: "out/comments.txt"},
+		{name: "number", pattern: "comments-{number}.txt", want: "comments-5.txt"},
+		{name: "title and author", pattern: "out/{author}-{title}.md", want: "out/octocat-Fix -- escape- now.md"},
+		{name: "placeholder in a directory", pattern: "out/{number}/c.txt", want: "out/5/c.txt"},
+		{name: "placeholder as the first directory", pattern: "{author}/{number}.txt", want: "octocat/5.txt"},
+		{name: "parent directory given literally", pattern: "../out/{number}.txt", want: "../out/5.txt"},
+		{name: "S3 key", pattern: "s3://bucket/{author}/{number}.json", want: "s3://bucket/octocat/5.json"},
+		{name: "pattern climbing out after the placeholder", pattern: "out/{number}/../../c.txt", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandOutputName(tt.pattern, "5", issue)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expandOutputName(%q) error = %v, want error %v", tt.pattern, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != filepath.FromSlash(tt.want) && got != tt.want {
+				t.Errorf("expandOutputName(%q) = %q, want %q", tt.pattern, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunOutputInPlaceholderDirectory(t *testing.T) {
+	tests := []struct {
+		format     string
+		outputFile string
+		gzip       bool
+	}{
+		{format: "text", outputFile: "out/{number}/c.txt"},
+		{format: "jsonl-gz", outputFile: "out/{number}/c.jsonl", gzip: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			setFlag(t, &formatFlag, tt.format)
+			setFlag(t, &outputFlag, tt.outputFile)
+			setFlag(t, &gzipFlag, false)
+			setFlag(t, &streamOutput, false)
+
+			err := runStubbed(t, stubAPI{
+				"/repos/o/r/issues/1":          `{"number":1,"title":"Crash on start","body":"It crashes."}`,
+				"/repos/o/r/issues/1/comments": `[{"id":7,"user":{"login":"bob"},"body":"Same here."}]`,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			path := filepath.Join("out", "1", filepath.Base(tt.outputFile))
+			if tt.gzip {
+				path += ".gz"
+			}
+			file, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			var r io.Reader = file
+			if tt.gzip {
+				r, err = gzip.NewReader(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			out, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(out), "Same here.") {
+				t.Errorf("comment is missing from %s:\n%s", path, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// isBot reports whether the user is a GitHub App or other automation account.
+func isBot(user User) bool {
+	return user.Type == "Bot" || strings.HasSuffix(user.Login, "[bot]")
+}
+
+// filterBots keeps only the bot comments when onlyBots is set, and only the
+// human comments otherwise.
+func filterBots(comments []Comment, onlyBots bool) []Comment {
+	filtered := comments[:0]
+	for _, comment := range comments {
+		if isBot(comment.User) == onlyBots {
+			filtered = append(filtered, comment)
+		}
+	}
+	return filtered
+}
+
+// filterHidden drops comments that were minimized on GitHub.
+func filterHidden(comments []Comment) []Comment {
+	filtered := comments[:0]
+	for _, comment := range comments {
+		if !comment.Minimized {
+			filtered = append(filtered, comment)
+		}
+	}
+	return filtered
+}
+
+// dedupMode is the value of --dedup, which can be given bare to drop
+// consecutive duplicates or as --dedup=global to drop them anywhere.
+type dedupMode string
+
+const (
+	dedupOff         dedupMode = ""
+	dedupConsecutive dedupMode = "consecutive"
+	dedupGlobal      dedupMode = "global"
+)
+
+func (d *dedupMode) String() string {
+	return string(*d)
+}
+
+func (d *dedupMode) Set(value string) error {
+	switch value {
+	case "true", "consecutive":
+		*d = dedupConsecutive
+	case "false":
+		*d = dedupOff
+	case "global":
+		*d = dedupGlobal
+	default:
+		return fmt.Errorf("expected consecutive or global")
+	}
+	return nil
+}
+
+// IsBoolFlag lets --dedup be used without a value.
+func (d *dedupMode) IsBoolFlag() bool {
+	return true
+}
+
+// commentFilter leaves out the comments of a thread that the flags drop. A
+// thread can go through it in batches, as new comments come in with
+// --follow, and duplicates are still caught across batches.
+type commentFilter struct {
+	key      string          // the thread in the --state-file
+	since    time.Time       // when the --since-tag release was tagged
+	seen     map[string]bool // authors and bodies so far, for --dedup
+	previous string          // author and body of the last comment, for --dedup
+	removed  int             // duplicates dropped so far
+}
+
+// commentFilter returns the filter for a thread, made on first use so later
+// batches of the same thread share it.
+func (f *fetcher) commentFilter(owner, repo, issueNumber string) (*commentFilter, error) {
+	key := stateKey(owner, repo, issueNumber)
+	if filter, ok := f.filters[key]; ok {
+		return filter, nil
+	}
+
+	filter := &commentFilter{key: key, seen: make(map[string]bool)}
+	if sinceTagFlag != "" {
+		since, err := f.tagTime(owner, repo, sinceTagFlag)
+		if err != nil {
+			return nil, err
+		}
+		filter.since = since
+	}
+
+	if f.filters == nil {
+		f.filters = make(map[string]*commentFilter)
+	}
+	f.filters[key] = filter
+	return filter, nil
+}
+
+// apply drops the comments left out by the filtering flags, and those an
+// earlier run already wrote with --state-file.
+func (c *commentFilter) apply(comments []Comment, state *runState) []Comment {
+	// Leave out minimized comments if asked to
+	if !includeHidden {
+		comments = filterHidden(comments)
+	}
+
+	// Drop bot or human comments, or those of particular authors, if asked to
+	if excludeBotsFlag || onlyBotsFlag {
+		comments = filterBots(comments, onlyBotsFlag)
+	}
+	if len(authorFlag) > 0 || len(excludeAuthors) > 0 {
+		comments = filterAuthors(comments, authorFlag, excludeAuthors)
+	}
+
+	// Drop double posts
+	if dedupFlag != dedupOff {
+		comments = c.dedup(comments, dedupFlag)
+	}
+
+	// Keep only the discussion since the tagged release
+	if !c.since.IsZero() {
+		comments = filterSince(comments, c.since)
+	}
+
+	// Keep only the comments newer than the one given, going by ID as many can share a second
+	if sinceIDFlag > 0 {
+		comments = filterAfterID(comments, sinceIDFlag)
+	}
+
+	// Keep only the well received comments
+	if minReactionsFlag > 0 {
+		comments = filterReactions(comments, minReactionsFlag)
+	}
+
+	// Leave out what earlier runs already wrote
+	if state != nil {
+		comments = state.newComments(c.key, comments)
+	}
+
+	return comments
+}
+
+// dedup removes comments with the same author and body as an earlier one,
+// either the one right before it or any earlier one in global mode.
+func (c *commentFilter) dedup(comments []Comment, mode dedupMode) []Comment {
+	deduped := comments[:0]
+	for _, comment := range comments {
+		key := comment.User.Login + "\x00" + comment.Body
+
+		duplicate := key == c.previous
+		if mode == dedupGlobal {
+			duplicate = c.seen[key]
+		}
+
+		c.seen[key] = true
+		c.previous = key
+
+		if duplicate {
+			c.removed++
+			continue
+		}
+		deduped = append(deduped, comment)
+	}
+	return deduped
+}
+
+// filterReactions keeps the comments with at least min reactions in total.
+func filterReactions(comments []Comment, min int) []Comment {
+	filtered := comments[:0]
+	for _, comment := range comments {
+		if comment.Reactions.TotalCount >= min {
+			filtered = append(filtered, comment)
+		}
+	}
+	return filtered
+}
+
+// filterAfterID keeps the comments with an ID higher than id.
+func filterAfterID(comments []Comment, id int64) []Comment {
+	filtered := comments[:0]
+	for _, comment := range comments {
+		if comment.ID > id {
+			filtered = append(filtered, comment)
+		}
+	}
+	return filtered
+}
+
+// filterSince keeps the comments posted after since.
+func filterSince(comments []Comment, since time.Time) []Comment {
+	filtered := comments[:0]
+	for _, comment := range comments {
+		if comment.DateTime.After(since) {
+			filtered = append(filtered, comment)
+		}
+	}
+	return filtered
+}
+
+// sampleComments picks n of the comments at random, without replacement,
+// keeping them in their original order. The same seed picks the same sample.
+func sampleComments(comments []Comment, n int, seed int64) []Comment {
+	if n >= len(comments) {
+		return comments
+	}
+
+	picked := rand.New(rand.NewSource(seed)).Perm(len(comments))[:n]
+	sort.Ints(picked)
+
+	sample := make([]Comment, n)
+	for i, index := range picked {
+		sample[i] = comments[index]
+	}
+	return sample
+}
+
+// applySeed checks --seed against --sample and, when --sample is given
+// without a --seed, picks a new seed for this run. A --seed 0 given on the
+// command line or in the global config file is kept as given.
+func applySeed(flags *flag.FlagSet) error {
+	given := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			given = true
+		}
+	})
+
+	if given && sampleFlag == 0 {
+		return usageErrorf("the --seed flag requires --sample")
+	}
+	if sampleFlag > 0 && !given {
+		seedFlag = time.Now().UnixNano()
+	}
+	return nil
+}
+
+// sortComments orders the comments for --sort: oldest first for created, or
+// most reactions first for reactions, keeping ties in posting order.
+func sortComments(comments []Comment, by string) {
+	if by == "reactions" {
+		sort.SliceStable(comments, func(i, j int) bool {
+			return comments[i].Reactions.TotalCount > comments[j].Reactions.TotalCount
+		})
+	}
+}
+
+// Flags turned on by --minimize-noise, with the value each one gets
+var noisePreset = []struct{ name, value string }{
+	{"exclude-bots", "true"},
+	{"flatten-quotes", "true"},
+	{"dedup", "consecutive"},
+	{"normalize-newlines", "true"},
+}
+
+// applyNoisePreset turns on the filters bundled by --minimize-noise: bots
+// are left out, quoted replies flattened, back to back repeats dropped and
+// newlines normalized. Each of those flags given on the command line or in
+// the global config file keeps its value, and --only-bots keeps the bot
+// comments.
+func applyNoisePreset(flags *flag.FlagSet) error {
+	given := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	for _, setting := range noisePreset {
+		if given[setting.name] || setting.name == "exclude-bots" && given["only-bots"] {
+			continue
+		}
+		err := flags.Set(setting.name, setting.value)
+		if err != nil {
+			return fmt.Errorf("--minimize-noise can't set --%s: %w", setting.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,475 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// commentIDs lists the IDs of the comments, in order.
+func commentIDs(comments []Comment) []int64 {
+	ids := []int64{}
+	for _, comment := range comments {
+		ids = append(ids, comment.ID)
+	}
+	return ids
+}
+
+func TestIsBot(t *testing.T) {
+	tests := []struct {
+		user User
+		want bool
+	}{
+		{User{Login: "dependabot[bot]"}, true},
+		{User{Login: "ci-runner", Type: "Bot"}, true},
+		{User{Login: "alice", Type: "User"}, false},
+		{User{Login: "robot"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := isBot(tt.user); got != tt.want {
+			t.Errorf("isBot(%+v) = %v, want %v", tt.user, got, tt.want)
+		}
+	}
+}
+
+func TestFilterBots(t *testing.T) {
+	comments := []Comment{
+		{ID: 1, User: User{Login: "alice"}},
+		{ID: 2, User: User{Login: "dependabot[bot]"}},
+		{ID: 3, User: User{Login: "bob"}},
+		{ID: 4, User: User{Login: "ci", Type: "Bot"}},
+	}
+
+	tests := []struct {
+		name     string
+		onlyBots bool
+		want     []int64
+	}{
+		{name: "exclude bots", onlyBots: false, want: []int64{1, 3}},
+		{name: "only bots", onlyBots: true, want: []int64{2, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := commentIDs(filterBots(append([]Comment(nil), comments...), tt.onlyBots))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filterBots() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterHidden(t *testing.T) {
+	comments := []Comment{{ID: 1}, {ID: 2, Minimized: true}, {ID: 3}}
+	if got := commentIDs(filterHidden(comments)); !reflect.DeepEqual(got, []int64{1, 3}) {
+		t.Errorf("filterHidden() = %v, want [1 3]", got)
+	}
+}
+
+func TestDedupModeSet(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    dedupMode
+		wantErr bool
+	}{
+		{value: "true", want: dedupConsecutive},
+		{value: "consecutive", want: dedupConsecutive},
+		{value: "global", want: dedupGlobal},
+		{value: "false", want: dedupOff},
+		{value: "all", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		var mode dedupMode
+		err := mode.Set(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Set(%q) error = %v, want error: %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if mode != tt.want {
+			t.Errorf("Set(%q) = %q, want %q", tt.value, mode, tt.want)
+		}
+	}
+}
+
+func TestDedup(t *testing.T) {
+	comments := []Comment{
+		{ID: 1, User: User{Login: "alice"}, Body: "+1"},
+		{ID: 2, User: User{Login: "alice"}, Body: "+1"},
+		{ID: 3, User: User{Login: "bob"}, Body: "+1"},
+		{ID: 4, User: User{Login: "alice"}, Body: "+1"},
+		{ID: 5, User: User{Login: "alice"}, Body: "+2"},
+	}
+
+	tests := []struct {
+		mode        dedupMode
+		want        []int64
+		wantRemoved int
+	}{
+		{mode: dedupConsecutive, want: []int64{1, 3, 4, 5}, wantRemoved: 1},
+		{mode: dedupGlobal, want: []int64{1, 3, 5}, wantRemoved: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			c := &commentFilter{seen: make(map[string]bool)}
+			got := commentIDs(c.dedup(append([]Comment(nil), comments...), tt.mode))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dedup() = %v, want %v", got, tt.want)
+			}
+			if c.removed != tt.wantRemoved {
+				t.Errorf("removed = %d, want %d", c.removed, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+func TestFilterReactions(t *testing.T) {
+	comments := []Comment{
+		{ID: 1, Reactions: Reactions{TotalCount: 0}},
+		{ID: 2, Reactions: Reactions{TotalCount: 3}},
+		{ID: 3, Reactions: Reactions{TotalCount: 5}},
+	}
+
+	tests := []struct {
+		min  int
+		want []int64
+	}{
+		{min: 1, want: []int64{2, 3}},
+		{min: 3, want: []int64{2, 3}},
+		{min: 4, want: []int64{3}},
+		{min: 6, want: []int64{}},
+	}
+
+	for _, tt := range tests {
+		got := commentIDs(filterReactions(append([]Comment(nil), comments...), tt.min))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("filterReactions(%d) = %v, want %v", tt.min, got, tt.want)
+		}
+	}
+}
+
+func TestSortComments(t *testing.T) {
+	comments := []Comment{
+		{ID: 1, Reactions: Reactions{TotalCount: 2}},
+		{ID: 2, Reactions: Reactions{TotalCount: 7}},
+		{ID: 3, Reactions: Reactions{TotalCount: 2}},
+		{ID: 4, Reactions: Reactions{TotalCount: 0}},
+	}
+
+	tests := []struct {
+		by   string
+		want []int64
+	}{
+		{by: "created", want: []int64{1, 2, 3, 4}},
+		{by: "reactions", want: []int64{2, 1, 3, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			sorted := append([]Comment(nil), comments...)
+			sortComments(sorted, tt.by)
+			if got := commentIDs(sorted); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sortComments(%s) = %v, want %v", tt.by, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommentFilterBatches(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	first := []Comment{
+		{ID: 1, User: User{Login: "alice"}, Body: "+1", DateTime: day(1)},
+		{ID: 2, User: User{Login: "ci[bot]", Type: "Bot"}, Body: "passed", DateTime: day(2)},
+		{ID: 3, User: User{Login: "bob"}, Body: "fixed", DateTime: day(3), Minimized: true},
+	}
+	second := []Comment{
+		{ID: 4, User: User{Login: "alice"}, Body: "+1", DateTime: day(4)},
+		{ID: 5, User: User{Login: "carol"}, Body: "thanks", DateTime: day(5)},
+	}
+
+	tests := []struct {
+		name        string
+		setup       func(t *testing.T, c *commentFilter)
+		state       *runState
+		first       []int64
+		second      []int64
+		wantRemoved int
+	}{
+		{
+			name: "hidden comments",
+			setup: func(t *testing.T, c *commentFilter) {
+				setFlag(t, &includeHidden, false)
+			},
+			first:  []int64{1, 2},
+			second: []int64{4, 5},
+		},
+		{
+			name: "bots and excluded authors",
+			setup: func(t *testing.T, c *commentFilter) {
+				setFlag(t, &excludeBotsFlag, true)
+				setFlag(t, &excludeAuthors, loginList{"Carol"})
+			},
+			first:  []int64{1, 3},
+			second: []int64{4},
+		},
+		{
+			name: "only the given authors, while following too",
+			setup: func(t *testing.T, c *commentFilter) {
+				setFlag(t, &authorFlag, loginList{"alice"})
+			},
+			first:  []int64{1},
+			second: []int64{4},
+		},
+		{
+			name: "global duplicates across batches",
+			setup: func(t *testing.T, c *commentFilter) {
+				setFlag(t, &dedupFlag, dedupGlobal)
+			},
+			first:       []int64{1, 2, 3},
+			second:      []int64{5},
+			wantRemoved: 1,
+		},
+		{
+			name: "back to back duplicates across batches",
+			setup: func(t *testing.T, c *commentFilter) {
+				setFlag(t, &dedupFlag, dedupConsecutive)
+			},
+			first:  []int64{1, 2, 3},
+			second: []int64{4, 5},
+		},
+		{
+			name: "since a tag and a comment ID",
+			setup: func(t *testing.T, c *commentFilter) {
+				c.since = day(1)
+				setFlag(t, &sinceIDFlag, 4)
+			},
+			first:  []int64{},
+			second: []int64{5},
+		},
+		{
+			name:   "already written with --state-file",
+			setup:  func(t *testing.T, c *commentFilter) {},
+			state:  &runState{LastID: map[string]int64{"o/r#1": 4}},
+			first:  []int64{},
+			second: []int64{5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &includeHidden, true)
+			c := &commentFilter{key: "o/r#1", seen: make(map[string]bool)}
+			tt.setup(t, c)
+
+			got := commentIDs(c.apply(append([]Comment(nil), first...), tt.state))
+			if !reflect.DeepEqual(got, tt.first) {
+				t.Errorf("first batch = %v, want %v", got, tt.first)
+			}
+			got = commentIDs(c.apply(append([]Comment(nil), second...), tt.state))
+			if !reflect.DeepEqual(got, tt.second) {
+				t.Errorf("second batch = %v, want %v", got, tt.second)
+			}
+			if c.removed != tt.wantRemoved {
+				t.Errorf("removed = %d, want %d", c.removed, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+func TestNoisePreset(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		config string
+		want   string
+	}{
+		{name: "preset alone", args: []string{"--minimize-noise"}, want: "exclude-bots=true flatten-quotes=true dedup=consecutive normalize-newlines=true"},
+		{name: "flag wins", args: []string{"--minimize-noise", "--dedup=global", "--flatten-quotes=false"}, want: "exclude-bots=true flatten-quotes=false dedup=global normalize-newlines=true"},
+		{name: "only bots", args: []string{"--minimize-noise", "--only-bots"}, want: "exclude-bots=false flatten-quotes=true dedup=consecutive normalize-newlines=true"},
+		{name: "config file wins", args: []string{"--minimize-noise"}, config: "normalize-newlines: false\nexclude-bots: false\n", want: "exclude-bots=false flatten-quotes=true dedup=consecutive normalize-newlines=false"},
+		{name: "preset from the config file", config: "minimize-noise: true\ndedup: global\n", want: "exclude-bots=true flatten-quotes=true dedup=global normalize-newlines=true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &minimizeNoise, false)
+			setFlag(t, &excludeBotsFlag, false)
+			setFlag(t, &onlyBotsFlag, false)
+			setFlag(t, &flattenFlag, false)
+			setFlag(t, &dedupFlag, dedupOff)
+			setFlag(t, &normalizeFlag, false)
+
+			flags := flag.NewFlagSet("test", flag.ContinueOnError)
+			flags.BoolVar(&minimizeNoise, "minimize-noise", false, "")
+			flags.BoolVar(&excludeBotsFlag, "exclude-bots", false, "")
+			flags.BoolVar(&onlyBotsFlag, "only-bots", false, "")
+			flags.BoolVar(&flattenFlag, "flatten-quotes", false, "")
+			flags.Var(&dedupFlag, "dedup", "")
+			flags.BoolVar(&normalizeFlag, "normalize-newlines", false, "")
+			err := flags.Parse(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.config != "" {
+				path := filepath.Join(t.TempDir(), "config.yaml")
+				os.WriteFile(path, []byte(tt.config), 0600)
+				err = applyConfigFile(flags, path)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if minimizeNoise {
+				err = applyNoisePreset(flags)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got := fmt.Sprintf("exclude-bots=%t flatten-quotes=%t dedup=%s normalize-newlines=%t", excludeBotsFlag, flattenFlag, dedupFlag, normalizeFlag)
+			if got != tt.want {
+				t.Errorf("got %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterAfterID(t *testing.T) {
+	comments := func() []Comment {
+		return []Comment{{ID: 5}, {ID: 998877}, {ID: 998878}, {ID: 1000000}}
+	}
+
+	tests := []struct {
+		name    string
+		sinceID int64
+		want    []int64
+	}{
+		{name: "higher IDs only", sinceID: 998877, want: []int64{998878, 1000000}},
+		{name: "below every ID", sinceID: 1, want: []int64{5, 998877, 998878, 1000000}},
+		{name: "above every ID", sinceID: 2000000, want: []int64{}},
+		{name: "not set", sinceID: 0, want: []int64{5, 998877, 998878, 1000000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &sinceIDFlag, tt.sinceID)
+			filter := &commentFilter{}
+			if got := commentIDs(filter.apply(comments(), nil)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("apply() with --since-comment-id %d = %v, want %v", tt.sinceID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSampleComments(t *testing.T) {
+	var comments []Comment
+	for i := 1; i <= 20; i++ {
+		comments = append(comments, Comment{ID: int64(i)})
+	}
+
+	tests := []struct {
+		name string
+		n    int
+		seed int64
+	}{
+		{name: "small sample", n: 3, seed: 42},
+		{name: "half", n: 10, seed: 7},
+		{name: "other seed", n: 3, seed: 43},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := commentIDs(sampleComments(comments, tt.n, tt.seed))
+			second := commentIDs(sampleComments(comments, tt.n, tt.seed))
+			if !reflect.DeepEqual(first, second) {
+				t.Errorf("seed %d picked %v, then %v", tt.seed, first, second)
+			}
+			if len(first) != tt.n {
+				t.Fatalf("sampled %d comments, want %d", len(first), tt.n)
+			}
+
+			// Picked without replacement and kept in chronological order
+			for i := 1; i < len(first); i++ {
+				if first[i] <= first[i-1] {
+					t.Errorf("sample %v isn't in order without repeats", first)
+				}
+			}
+		})
+	}
+
+	if a, b := commentIDs(sampleComments(comments, 10, 1)), commentIDs(sampleComments(comments, 10, 2)); reflect.DeepEqual(a, b) {
+		t.Errorf("seeds 1 and 2 picked the same sample %v", a)
+	}
+}
+
+func TestApplySeed(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantSeed int64 // -1 for a new seed picked by the run
+		wantErr  string
+	}{
+		{name: "seed given", args: []string{"--sample", "3", "--seed", "42"}, wantSeed: 42},
+		{name: "seed 0 given", args: []string{"--sample", "3", "--seed", "0"}, wantSeed: 0},
+		{name: "no seed", args: []string{"--sample", "3"}, wantSeed: -1},
+		{name: "no sample", args: nil, wantSeed: 0},
+		{name: "seed without sample", args: []string{"--seed", "0"}, wantErr: "the --seed flag requires --sample"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &sampleFlag, 0)
+			setFlag(t, &seedFlag, 0)
+
+			flags := flag.NewFlagSet("test", flag.ContinueOnError)
+			flags.IntVar(&sampleFlag, "sample", 0, "")
+			flags.Int64Var(&seedFlag, "seed", 0, "")
+			err := flags.Parse(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = applySeed(flags)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("applySeed() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantSeed == -1 {
+				if seedFlag == 0 {
+					t.Errorf("no seed picked for --sample without --seed")
+				}
+			} else if seedFlag != tt.wantSeed {
+				t.Errorf("seed = %d, want %d", seedFlag, tt.wantSeed)
+			}
+		})
+	}
+}
+
+func TestSampleCommentsAll(t *testing.T) {
+	comments := []Comment{{ID: 1}, {ID: 2}, {ID: 3}}
+	tests := []struct {
+		n    int
+		want []int64
+	}{
+		{n: 3, want: []int64{1, 2, 3}},
+		{n: 10, want: []int64{1, 2, 3}},
+	}
+
+	for _, tt := range tests {
+		if got := commentIDs(sampleComments(comments, tt.n, 1)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sampleComments(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Longest wait between polls when backing off from rate limits
+const maxFollowInterval = 10 * time.Minute
+
+// followComments polls an issue for comments posted after startedAt and
+// appends them to the output of the initial fetch until interrupted.
+func followComments(f *fetcher, owner, repo string, thread savedThread, startedAt time.Time, names *anonymizer) error {
+	// Comments already written are skipped when they show up again
+	seen := make(map[int64]bool)
+	for _, id := range thread.ids {
+		seen[id] = true
+	}
+	written := thread.count
+	since := startedAt
+
+	// New comments are filtered like the ones fetched at first
+	filter, err := f.commentFilter(owner, repo, thread.issueNumber)
+	if err != nil {
+		return err
+	}
+
+	statusf("Following #%s for new comments every %s; press Ctrl-C to stop.\n", thread.issueNumber, intervalFlag)
+
+	interval := intervalFlag
+	for {
+		select {
+		case <-f.ctx.Done():
+			statusf("Stopped following #%s.\n", thread.issueNumber)
+			return nil
+		case <-time.After(interval):
+		}
+
+		// The since parameter filters on updated_at, so edited comments come back too
+		commentsURL := issueURL(owner, repo, thread.issueNumber) + "/comments?since=" + url.QueryEscape(since.UTC().Format(time.RFC3339))
+		comments, err := f.fetchCommentPages(commentsURL)
+		if err != nil {
+			var rateErr *RateLimitError
+			if errors.As(err, &rateErr) {
+				interval *= 2
+				if interval > maxFollowInterval {
+					interval = maxFollowInterval
+				}
+				log.Printf("Rate limited while following; next poll in %s", interval)
+			} else {
+				log.Printf("Failed to poll for new comments: %s", err)
+			}
+			continue
+		}
+		interval = intervalFlag
+
+		var fresh []Comment
+		for _, comment := range comments {
+			if comment.UpdatedAt.After(since) {
+				since = comment.UpdatedAt
+			}
+			if seen[comment.ID] {
+				continue
+			}
+			seen[comment.ID] = true
+			fresh = append(fresh, comment)
+		}
+
+		fresh = filter.apply(fresh, f.state)
+		if len(fresh) == 0 {
+			continue
+		}
+
+		if prettyAuthorFlag {
+			f.resolveNames(&Issue{}, fresh)
+		}
+		prepareThread(&Issue{}, fresh, names)
+
+		err = appendComments(thread.outputFile, fresh, written+1)
+		if err != nil {
+			return err
+		}
+		written += len(fresh)
+		if f.state != nil {
+			f.state.record(filter.key, fresh)
+		}
+
+		if thread.outputFile != "-" {
+			statusf("Added %d new comment(s) to %s.\n", len(fresh), thread.outputFile)
+		}
+	}
+}
+
+// appendComments adds comments to the end of the text output, numbering them from first.
+func appendComments(outputFile string, comments []Comment, first int) error {
+	var out io.Writer = os.Stdout
+	if outputFile != "-" {
+		file, err := os.OpenFile(outputFile, os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open output for appending: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	err := writeCommentsFrom(out, comments, first)
+	if err != nil {
+		return fmt.Errorf("failed to append comments: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFollowComments(t *testing.T) {
+	startedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	outputFile := filepath.Join(t.TempDir(), "comments.txt")
+	os.WriteFile(outputFile, []byte("Comment 1 by alice:\nfirst\n"), 0644)
+
+	// Each poll answers with everything updated since the last one
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var sinces []string
+	polls := []string{
+		`[{"id":1,"body":"first","user":{"login":"alice"},"created_at":"2024-03-01T11:00:00Z","updated_at":"2024-03-01T12:05:00Z"},
+		  {"id":2,"body":"second","user":{"login":"bob"},"created_at":"2024-03-01T12:10:00Z","updated_at":"2024-03-01T12:10:00Z"},
+		  {"id":3,"body":"build passed","user":{"login":"ci[bot]"},"created_at":"2024-03-01T12:11:00Z","updated_at":"2024-03-01T12:11:00Z"}]`,
+		`[{"id":2,"body":"second","user":{"login":"bob"},"created_at":"2024-03-01T12:10:00Z","updated_at":"2024-03-01T12:10:00Z"},
+		  {"id":4,"body":"third","user":{"login":"carol"},"created_at":"2024-03-01T12:20:00Z","updated_at":"2024-03-01T12:20:00Z"}]`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sinces = append(sinces, r.URL.Query().Get("since"))
+		if len(sinces) > len(polls) {
+			cancel()
+			fmt.Fprint(w, `[]`)
+			return
+		}
+		fmt.Fprint(w, polls[len(sinces)-1])
+	}))
+	defer server.Close()
+
+	setFlag(t, &apiBaseURL, server.URL)
+	setFlag(t, &intervalFlag, time.Millisecond)
+	setFlag(t, &includeHidden, true)
+	setFlag(t, &excludeBotsFlag, true)
+	setFlag(t, &outputFlag, outputFile)
+
+	f := &fetcher{ctx: ctx, client: server.Client(), stats: newRunStats()}
+	thread := savedThread{owner: "o", repo: "r", issueNumber: "1", outputFile: outputFile, count: 1, ids: []int64{1}}
+	err := followComments(f, "o", "r", thread, startedAt, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The since parameter moves on to the latest update seen
+	wantSinces := []string{"2024-03-01T12:00:00Z", "2024-03-01T12:11:00Z", "2024-03-01T12:20:00Z"}
+	if strings.Join(sinces, ",") != strings.Join(wantSinces, ",") {
+		t.Errorf("since = %v, want %v", sinces, wantSinces)
+	}
+
+	// Comments already written and bot comments aren't appended
+	content, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Comment 2 [id=2] by bob", "Comment 3 [id=4] by carol"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("output is missing %q:\n%s", want, content)
+		}
+	}
+	if strings.Count(string(content), "first") != 1 || strings.Contains(string(content), "build passed") {
+		t.Errorf("output repeats a comment or keeps the bot comment:\n%s", content)
+	}
+}
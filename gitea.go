@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Gitea returns at most this many items per page
+const giteaPageSize = 50
+
+// pageSizeParam is the query parameter asking for the largest pages the
+// forge in use allows.
+func pageSizeParam() string {
+	if schemaFlag == "gitea" {
+		return fmt.Sprintf("limit=%d", giteaPageSize)
+	}
+	return "per_page=100"
+}
+
+// User as Gitea returns it, with the display name included
+type giteaUser struct {
+	Login    string `json:"login"`
+	FullName string `json:"full_name"`
+}
+
+// user converts the Gitea user, keeping the display name for --pretty-author.
+// Comments migrated from another forge belong to a placeholder account and
+// name their original author separately.
+func (u giteaUser) user(originalAuthor string) User {
+	if originalAuthor != "" && (u.Login == "" || u.Login == "ghost") {
+		return User{Login: originalAuthor}
+	}
+
+	user := User{Login: u.Login}
+	if prettyAuthorFlag {
+		user.Name = u.FullName
+	}
+	return user
+}
+
+// Issue or pull request as Gitea returns it
+type giteaIssue struct {
+	ID             int64     `json:"id"`
+	Title          string    `json:"title"`
+	Body           string    `json:"body"`
+	User           giteaUser `json:"user"`
+	OriginalAuthor string    `json:"original_author"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Comments       int       `json:"comments"`
+	State          string    `json:"state"`
+	PullRequest    *struct {
+		Merged bool `json:"merged"`
+	} `json:"pull_request"`
+}
+
+// issue converts the Gitea issue to GitHub's shape.
+func (i giteaIssue) issue() Issue {
+	issue := Issue{
+		ID:        i.ID,
+		Title:     i.Title,
+		Body:      i.Body,
+		User:      i.User.user(i.OriginalAuthor),
+		DateTime:  i.CreatedAt,
+		UpdatedAt: i.UpdatedAt,
+		Comments:  i.Comments,
+		State:     i.State,
+	}
+	if i.PullRequest != nil {
+		issue.PullRequestLinks = &struct {
+			URL string `json:"url"`
+		}{}
+	}
+	return issue
+}
+
+// Comment as Gitea returns it
+type giteaComment struct {
+	ID             int64     `json:"id"`
+	Body           string    `json:"body"`
+	User           giteaUser `json:"user"`
+	OriginalAuthor string    `json:"original_author"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// comment converts the Gitea comment to GitHub's shape.
+func (c giteaComment) comment() Comment {
+	return Comment{
+		ID:        c.ID,
+		Body:      c.Body,
+		User:      c.User.user(c.OriginalAuthor),
+		DateTime:  c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+}
+
+// fetchGiteaIssue fetches an issue or pull request from Gitea.
+func (f *fetcher) fetchGiteaIssue(owner, repo, issueNumber string) (Issue, error) {
+	var issue giteaIssue
+	err := f.getJSON(issueURL(owner, repo, issueNumber), &issue)
+	if err != nil {
+		return Issue{}, err
+	}
+	return issue.issue(), nil
+}
+
+// fetchGiteaComments fetches every page of a Gitea comments listing.
+func (f *fetcher) fetchGiteaComments(url string) ([]Comment, error) {
+	page, err := fetchPaged[giteaComment](f, url)
+	if err != nil && len(page) == 0 {
+		return nil, err
+	}
+
+	comments := make([]Comment, len(page))
+	for i, comment := range page {
+		comments[i] = comment.comment()
+	}
+	return comments, err
+}
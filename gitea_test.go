@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFetchGiteaComments(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		fmt.Fprint(w, `[
+			{"id":1,"body":"Works for me.","user":{"login":"alice","full_name":"Alice Liddell"},"created_at":"2024-03-01T09:30:00Z","updated_at":"2024-03-01T10:00:00Z"},
+			{"id":2,"body":"Migrated.","user":{"login":"ghost"},"original_author":"bob","created_at":"2024-03-02T09:30:00Z"}
+		]`)
+	}))
+	defer server.Close()
+	setFlag(t, &schemaFlag, "gitea")
+	setFlag(t, &prettyAuthorFlag, true)
+
+	f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats()}
+	comments, err := f.fetchGiteaComments(server.URL + "/repos/o/r/issues/1/comments")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Comment{
+		{
+			ID:        1,
+			Body:      "Works for me.",
+			User:      User{Login: "alice", Name: "Alice Liddell"},
+			DateTime:  time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC),
+			UpdatedAt: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			ID:       2,
+			Body:     "Migrated.",
+			User:     User{Login: "bob"},
+			DateTime: time.Date(2024, 3, 2, 9, 30, 0, 0, time.UTC),
+		},
+	}
+	if !reflect.DeepEqual(comments, want) {
+		t.Errorf("fetchGiteaComments() = %+v, want %+v", comments, want)
+	}
+	if !reflect.DeepEqual(queries, []string{"limit=50"}) {
+		t.Errorf("queries = %q, want Gitea's page size", queries)
+	}
+}
+
+func TestGiteaUser(t *testing.T) {
+	tests := []struct {
+		name           string
+		user           giteaUser
+		originalAuthor string
+		prettyAuthor   bool
+		want           User
+	}{
+		{name: "login", user: giteaUser{Login: "alice", FullName: "Alice Liddell"}, want: User{Login: "alice"}},
+		{name: "full name", user: giteaUser{Login: "alice", FullName: "Alice Liddell"}, prettyAuthor: true, want: User{Login: "alice", Name: "Alice Liddell"}},
+		{name: "migrated to ghost", user: giteaUser{Login: "ghost"}, originalAuthor: "bob", want: User{Login: "bob"}},
+		{name: "migrated without a user", user: giteaUser{}, originalAuthor: "bob", want: User{Login: "bob"}},
+		{name: "original author of a real user", user: giteaUser{Login: "carol"}, originalAuthor: "bob", want: User{Login: "carol"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &prettyAuthorFlag, tt.prettyAuthor)
+			if got := tt.user.user(tt.originalAuthor); got != tt.want {
+				t.Errorf("user(%q) = %+v, want %+v", tt.originalAuthor, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGiteaIssue(t *testing.T) {
+	tests := []struct {
+		name   string
+		issue  giteaIssue
+		wantPR bool
+	}{
+		{name: "issue", issue: giteaIssue{ID: 5, Title: "Crash", State: "open"}, wantPR: false},
+		{name: "pull request", issue: giteaIssue{ID: 6, Title: "Fix", State: "closed", PullRequest: &struct {
+			Merged bool `json:"merged"`
+		}{Merged: true}}, wantPR: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.issue.issue()
+			if got.ID != tt.issue.ID || got.Title != tt.issue.Title || got.State != tt.issue.State {
+				t.Errorf("issue() = %+v, want the fields of %+v", got, tt.issue)
+			}
+			if (got.PullRequestLinks != nil) != tt.wantPR {
+				t.Errorf("pull request = %v, want %v", got.PullRequestLinks != nil, tt.wantPR)
+			}
+		})
+	}
+}
@@ -1,23 +1,140 @@
 package main
 
 import (
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
-	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
 var (
-	ownerFlag       string
-	repoFlag        string
-	issueNumberFlag string
+	ownerFlag        string
+	repoFlag         string
+	issueNumberFlag  string
+	redactFlag       bool
+	gzipFlag         bool
+	maxRetriesFlag   int
+	templateFlag     string
+	excludeBotsFlag  bool
+	onlyBotsFlag     bool
+	versionFlag      bool
+	typeFlag         string
+	shaFlag          string
+	normalizeFlag    bool
+	graphqlFlag      bool
+	includeHidden    bool
+	anonymizeFlag    bool
+	anonymizeMap     string
+	formatFlag       string
+	fieldsFlag       string
+	issuesFileFlag   string
+	proxyFlag        string
+	manifestFlag     string
+	outputFlag       string
+	noColorFlag      bool
+	dedupFlag        dedupMode
+	timeFormatFlag   string
+	timezoneFlag     string
+	countOnlyFlag    bool
+	bodyFormatFlag   string
+	followFlag       bool
+	intervalFlag     time.Duration
+	prettyFlag       bool
+	indentFlag       int
+	searchFlag       string
+	maxResultsFlag   int
+	statsFlag        bool
+	caCertFlag       string
+	clientCertFlag   string
+	clientKeyFlag    string
+	insecureFlag     bool
+	wrapFlag         int
+	prettyAuthorFlag bool
+	cacheFlag        string
+	cacheTTLFlag     time.Duration
+	headersFlag      headerList
+	apiVersionFlag   string
+	minReactionsFlag int
+	sortFlag         string
+	execFlag         string
+	deviceFlag       bool
+	clientIDFlag     string
+	commentIDFlag    int64
+	maxBodyBytesFlag int
+	onOversizeFlag   string
+	mergeFlag        bool
+	resolveFlag      bool
+	stateFileFlag    string
+	userAgentFlag    string
+	orgFlag          string
+	issueTitleFlag   string
+	diffFlag         bool
+	saveInputsFlag   bool
+	reviewFlag       bool
+	failIfEmptyFlag  bool
+	bodyOnlyFlag     bool
+	fromFileFlag     string
+	saveRawFlag      string
+	maxIdleConnsFlag int
+	idleTimeoutFlag  time.Duration
+	tocFlag          bool
+	nodeIDFlag       string
+	harFlag          string
+	reactorsFlag     bool
+	flattenFlag      bool
+	s3EndpointFlag   string
+	linkedPRsFlag    bool
+	compactFlag      bool
+	retryBudgetFlag  time.Duration
+	sinceTagFlag     string
+	headFlag         int
+	noFileFlag       bool
+	metricsFileFlag  string
+	schemaFlag       string
+	watchLabelsFlag  bool
+	resumeFlag       string
+	translateToFlag  string
+	translateURLFlag string
+	translateKeyFlag string
+	groupByFlag      string
+	stripHTMLFlag    bool
+	onlyUnresolved   bool
+	sinceIDFlag      int64
+	renderMDFlag     bool
+	closedByFlag     bool
+	tokenFileFlag    string
+	sampleFlag       int
+	seedFlag         int64
+	authorFlag       loginList
+	excludeAuthors   loginList
+	archiveLayout    string
+	printURLFlag     bool
+	rateFlag         float64
+	participantsCSV  string
+	minimizeNoise    bool
+	includeIssueFlag bool
+	tokenFlag        string
+	baseURLFlag      string
 )
 
+// Default GitHub API endpoint to fetch issues, PRs, commits and their comments
+const defaultBaseURL = "https://api.github.com"
+
+// REST API version the responses are known to parse with
+const defaultAPIVersion = "2022-11-28"
+
+// GitHub API endpoint in use, changed with --base-url for GitHub Enterprise
+var apiBaseURL = defaultBaseURL
+
 type File struct {
 	Name string `json:"name"`
 	Type string `json:"type"`
@@ -25,23 +142,96 @@ type File struct {
 
 // GitHub issue/PR struct
 type Issue struct {
+	ID        int64     `json:"id"`
 	Title     string    `json:"title"`
 	Body      string    `json:"body"`
+	BodyText  string    `json:"body_text"`
+	BodyHTML  string    `json:"body_html"`
 	User      User      `json:"user"`
 	DateTime  time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	Comments  int       `json:"comments"`
+
+	// open or closed, and why it was closed: completed, not_planned or reopened
+	State       string `json:"state"`
+	StateReason string `json:"state_reason"`
+
+	// Only present when the issue is a pull request
+	PullRequestLinks *struct {
+		URL string `json:"url"`
+	} `json:"pull_request"`
+
+	// Details fetched separately for pull requests
+	PullRequest    *PullRequest `json:"-"`
+	ReviewComments []Comment    `json:"-"`
+
+	// Pull requests closing or mentioning the issue, only fetched with --linked-prs
+	LinkedPRs []LinkedPR `json:"-"`
+
+	// Labels added and removed over time, only fetched with --watch-labels
+	LabelEvents []LabelEvent `json:"-"`
+
+	// Who last closed the issue, only fetched with --include-closed-by
+	ClosedBy *Closure `json:"-"`
+}
+
+// GitHub pull request struct, for what the issue endpoint leaves out
+type PullRequest struct {
+	Merged    bool `json:"merged"`
+	Draft     bool `json:"draft"`
+	Additions int  `json:"additions"`
+	Deletions int  `json:"deletions"`
+	Base      struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+	Head struct {
+		Ref string `json:"ref"`
+	} `json:"head"`
 }
 
 // GitHub comment struct
 type Comment struct {
-	Body     string    `json:"body"`
-	User     User      `json:"user"`
-	DateTime time.Time `json:"created_at"`
+	ID        int64     `json:"id"`
+	Body      string    `json:"body"`
+	BodyText  string    `json:"body_text"`
+	BodyHTML  string    `json:"body_html"`
+	User      User      `json:"user"`
+	DateTime  time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Only set for commit and review comments
+	Path     string `json:"path"`
+	Position *int   `json:"position"`
+
+	// Only set for review comments
+	Line        *int  `json:"line"`
+	InReplyToID int64 `json:"in_reply_to_id"`
+
+	// Only known when fetching through GraphQL
+	Minimized       bool   `json:"minimized,omitempty"`
+	MinimizedReason string `json:"minimized_reason,omitempty"`
+
+	Reactions Reactions `json:"reactions"`
+
+	// Logins by reaction content, only fetched with --detailed-reactions
+	Reactors map[string][]string `json:"-"`
+
+	// Body in the --translate-to language, empty when not translated
+	Translation string `json:"-"`
+}
+
+// Reaction summary GitHub includes with each comment
+type Reactions struct {
+	TotalCount int `json:"total_count"`
 }
 
 // GitHub user struct
 type User struct {
 	Login string `json:"login"`
+	Type  string `json:"type"`
+
+	// Display name, only looked up with --pretty-author
+	Name string `json:"-"`
 }
 
 func init() {
@@ -51,36 +241,224 @@ func init() {
 	flag.StringVar(&repoFlag, "R", "", "Repository name")
 	flag.StringVar(&repoFlag, "repo", "", "Repository name")
 
-	flag.StringVar(&issueNumberFlag, "I", "", "Reference number of the issue or PR")
-	flag.StringVar(&issueNumberFlag, "issueNumber", "", "Reference number of the issue or PR")
+	flag.StringVar(&issueNumberFlag, "I", "", "Reference number of the issue or PR; a comma-separated list or - to read them from stdin")
+	flag.StringVar(&issueNumberFlag, "issueNumber", "", "Reference number of the issue or PR; a comma-separated list or - to read them from stdin")
+
+	flag.BoolVar(&redactFlag, "redact", false, "Mask tokens and keys found in issue and comment bodies")
+	flag.BoolVar(&gzipFlag, "gzip", false, "Compress the output file with gzip and append .gz to its name")
+	flag.IntVar(&maxRetriesFlag, "max-retries", 3, "Maximum number of retries when GitHub asks to back off or has a temporary server error")
+	flag.StringVar(&templateFlag, "template", "", "Path to a Go text/template file used to render the output")
+	flag.BoolVar(&excludeBotsFlag, "exclude-bots", false, "Leave out comments written by bots")
+	flag.BoolVar(&onlyBotsFlag, "only-bots", false, "Keep only comments written by bots")
+	flag.BoolVar(&versionFlag, "version", false, "Print version information and exit")
+	flag.StringVar(&typeFlag, "type", "issue", "What to fetch comments for: issue or commit")
+	flag.StringVar(&shaFlag, "sha", "", "Commit SHA when using --type commit")
+	flag.BoolVar(&normalizeFlag, "normalize-newlines", false, "Convert CRLF line endings to LF and strip trailing spaces in bodies")
+	flag.BoolVar(&graphqlFlag, "graphql", false, "Fetch comments through the GraphQL API, which reports minimized comments")
+	flag.BoolVar(&includeHidden, "include-hidden", true, "Keep comments that were minimized (hidden) on GitHub; requires --graphql to detect them")
+	flag.BoolVar(&anonymizeFlag, "anonymize", false, "Replace usernames and @-mentions with stable pseudonyms like user1")
+	flag.StringVar(&anonymizeMap, "anonymize-map", "", "File to save the pseudonym to username mapping to when using --anonymize")
+	flag.StringVar(&formatFlag, "format", "text", "Output format: text, json, ndjson, jsonl-gz (gzipped NDJSON), xml or markdown")
+	flag.StringVar(&fieldsFlag, "fields", "", "Comma-separated keys to keep in JSON output, e.g. author,created_at,body")
+	flag.StringVar(&issuesFileFlag, "issues-file", "", "File listing issue numbers to fetch, one per line")
+	flag.StringVar(&proxyFlag, "proxy", "", "Proxy URL for API requests, e.g. http://host:port or socks5://host:port")
+	flag.StringVar(&manifestFlag, "manifest", "", "Write a JSON manifest describing the run and the output checksums to this file")
+
+	flag.StringVar(&outputFlag, "o", "", "Output file, or - for stdout (default comments.txt, or the extension of --format); may contain {number}, {title} and {author}")
+	flag.StringVar(&outputFlag, "output", "", "Output file, or - for stdout (default comments.txt, or the extension of --format); may contain {number}, {title} and {author}")
+	flag.BoolVar(&noColorFlag, "no-color", false, "Never colorize text output written to a terminal")
+	flag.Var(&dedupFlag, "dedup", "Remove repeated comments with the same author and body; --dedup=global removes them anywhere, not just back to back")
+	flag.StringVar(&timeFormatFlag, "time-format", "", "Timestamp format: a Go layout or one of rfc3339, date, relative")
+	flag.StringVar(&timezoneFlag, "timezone", "", "Timezone for timestamps, e.g. America/New_York (default UTC)")
+	flag.BoolVar(&countOnlyFlag, "count-only", false, "Print the number of comments instead of writing a file")
+	flag.StringVar(&bodyFormatFlag, "body-format", "raw", "Body representation to fetch: raw (Markdown), text or html (rendered by GitHub)")
+	flag.BoolVar(&followFlag, "follow", false, "Keep polling the issue and add new comments as they are posted, until interrupted")
+	flag.DurationVar(&intervalFlag, "interval", 30*time.Second, "How often to poll for new comments with --follow")
+	flag.BoolVar(&prettyFlag, "pretty", true, "Indent JSON output; --pretty=false writes compact JSON")
+	flag.IntVar(&indentFlag, "indent", 2, "Number of spaces to indent JSON output with")
+	flag.StringVar(&searchFlag, "search", "", "Fetch the issues of the repository matching a search query, e.g. \"is:issue label:bug\"")
+	flag.IntVar(&maxResultsFlag, "max-results", 30, "Maximum number of search results to fetch with --search")
+	flag.BoolVar(&statsFlag, "stats", false, "Print a summary of comments, pages, requests, bytes and time at the end")
+	flag.StringVar(&caCertFlag, "ca-cert", "", "PEM file with an extra CA certificate to trust, e.g. for GitHub Enterprise")
+	flag.StringVar(&clientCertFlag, "client-cert", "", "PEM client certificate for servers requiring mutual TLS")
+	flag.StringVar(&clientKeyFlag, "client-key", "", "PEM private key of the --client-cert")
+	flag.BoolVar(&insecureFlag, "insecure", false, "Skip TLS certificate verification; unsafe, only for testing against self-signed servers")
+	flag.IntVar(&wrapFlag, "wrap", 0, "Wrap issue and comment bodies at this many columns in text output, leaving code blocks alone (0 disables)")
+	flag.BoolVar(&prettyAuthorFlag, "pretty-author", false, "Show authors by their display name, e.g. \"Alice Smith (@alice)\", looking up each user once")
+	flag.StringVar(&cacheFlag, "cache", "", "Directory to cache API responses in; later runs revalidate them with conditional requests")
+	flag.DurationVar(&cacheTTLFlag, "cache-ttl", 0, "Use cached responses of closed issues younger than this without revalidating them, e.g. 24h; open issues are always revalidated")
+	flag.Var(&headersFlag, "header", "Extra HTTP header to send with every request, as \"Key: Value\"; can be repeated")
+	flag.StringVar(&apiVersionFlag, "api-version", defaultAPIVersion, "GitHub REST API version sent in X-GitHub-Api-Version; empty to leave the header out")
+	flag.IntVar(&minReactionsFlag, "min-reactions", 0, "Keep only comments with at least this many reactions")
+	flag.StringVar(&sortFlag, "sort", "created", "Order of the comments: created (oldest first) or reactions (most reactions first)")
+	flag.StringVar(&execFlag, "exec", "", "Shell command to pipe each comment body through; its output replaces the body")
+	flag.BoolVar(&deviceFlag, "device", false, "With login, sign in through the browser using the OAuth device flow")
+	flag.StringVar(&clientIDFlag, "client-id", "", "OAuth app client ID used by login --device")
+	flag.Int64Var(&commentIDFlag, "comment-id", 0, "Fetch a single issue or PR comment by its ID and write only its body")
+	flag.IntVar(&maxBodyBytesFlag, "max-body-bytes", 0, "Limit issue and comment bodies to this many bytes (0 for no limit)")
+	flag.StringVar(&onOversizeFlag, "on-oversize", "truncate", "What to do with bodies over --max-body-bytes: truncate or fail")
+	flag.BoolVar(&mergeFlag, "merge", false, "Write all issues into one report, interleaving their posts by time")
+	flag.BoolVar(&resolveFlag, "resolve", false, "Add the title after #123 and owner/repo#123 references in bodies")
+	flag.StringVar(&stateFileFlag, "state-file", "", "File remembering the last comment written per issue, so later runs only write newer comments")
+	flag.StringVar(&userAgentFlag, "user-agent", "github-comments-fetcher/"+version, "User-Agent header sent with every request")
+	flag.StringVar(&orgFlag, "org", "", "Organization to search for the issue given with --issue-title, instead of naming the repository")
+	flag.StringVar(&issueTitleFlag, "issue-title", "", "Title of the issue or PR to find in the --org repositories")
+	flag.BoolVar(&diffFlag, "diff", false, "Compare two saved JSON outputs given as arguments, e.g. --diff old.json new.json")
+	flag.BoolVar(&saveInputsFlag, "save-inputs", true, "Read and update github-comments-fetcher-inputs.txt; --save-inputs=false goes by the flags only")
+	flag.BoolVar(&reviewFlag, "review-threads", false, "Also fetch the review comments of pull requests and show them grouped in threads")
+	flag.BoolVar(&failIfEmptyFlag, "fail-if-empty", false, "Exit with code 6 when an issue has no comments")
+	flag.BoolVar(&bodyOnlyFlag, "body-only", false, "Write only the issue description, without headers or comments")
+	flag.StringVar(&fromFileFlag, "from-file", "", "Read the issue and comments from a file saved with --save-raw instead of GitHub")
+	flag.StringVar(&saveRawFlag, "save-raw", "", "Save the issue and comments as fetched from GitHub to this file, for --from-file")
+	flag.IntVar(&maxIdleConnsFlag, "max-idle-conns", 10, "Number of idle connections to GitHub kept open for reuse")
+	flag.DurationVar(&idleTimeoutFlag, "idle-conn-timeout", 90*time.Second, "How long idle connections are kept open")
+	flag.BoolVar(&tocFlag, "toc", false, "Start Markdown output with a table of contents linking to each comment")
+	flag.StringVar(&nodeIDFlag, "node-id", "", "GraphQL node ID of the issue or PR to fetch, instead of naming the repository and number")
+	flag.StringVar(&harFlag, "har", "", "Record every API request and response in this HAR file, with credentials redacted")
+	flag.BoolVar(&reactorsFlag, "detailed-reactions", false, "Fetch who reacted to each comment, at the cost of a request per comment with reactions")
+	flag.BoolVar(&flattenFlag, "flatten-quotes", false, "Strip the > quoted text of earlier comments from each comment, keeping only what's new")
+	flag.StringVar(&s3EndpointFlag, "s3-endpoint", "", "URL of the S3-compatible server, such as MinIO, that s3:// outputs are uploaded to (default AWS S3)")
+	flag.BoolVar(&linkedPRsFlag, "linked-prs", false, "List the pull requests that close or mention the issue")
+	flag.BoolVar(&compactFlag, "compact", false, "Write one line per comment with the start of its body, for a quick scan")
+	flag.DurationVar(&retryBudgetFlag, "retry-budget", 0, "Longest total time to spend waiting before retries over the whole run, e.g. 5m (default no limit)")
+	flag.StringVar(&sinceTagFlag, "since-tag", "", "Keep only comments posted after the commit of this git tag, e.g. the last release")
+	flag.IntVar(&headFlag, "head", 0, "Also print the thread with only its first N comments to stdout, as a preview")
+	flag.BoolVar(&noFileFlag, "no-file", false, "Only print the --head preview, without writing the output file")
+	flag.StringVar(&metricsFileFlag, "metrics-file", "", "Write Prometheus metrics about the run to this file, for the node_exporter textfile collector")
+	flag.StringVar(&schemaFlag, "schema", "github", "API the --base-url server speaks: github, or gitea for Gitea and Forgejo (e.g. --base-url https://gitea.example.com/api/v1)")
+	flag.BoolVar(&watchLabelsFlag, "watch-labels", false, "Also write the history of labels added to and removed from the issue")
+	flag.StringVar(&resumeFlag, "resume", "", "File keeping the comment pages fetched so far, so a failed run continues where it stopped")
+	flag.StringVar(&translateToFlag, "translate-to", "", "Language code to translate comment bodies into, written alongside the original (e.g. en)")
+	flag.StringVar(&translateURLFlag, "translate-url", "", "LibreTranslate-compatible endpoint used by --translate-to (e.g. https://libretranslate.example.com/translate)")
+	flag.StringVar(&translateKeyFlag, "translate-key", "", "API key sent to the --translate-url endpoint")
+	flag.StringVar(&groupByFlag, "group-by", "", "Group the comments of the text and markdown output in a section per author: author")
+	flag.BoolVar(&stripHTMLFlag, "strip-html", false, "Turn bodies fetched with --body-format html into plain text, keeping links as text (url)")
+	flag.BoolVar(&onlyUnresolved, "only-unresolved", false, "With --review-threads, keep only the review threads not marked as resolved (uses GraphQL)")
+	flag.Int64Var(&sinceIDFlag, "since-comment-id", 0, "Keep only comments with an ID higher than this; --state-file remembers the highest ID written for you")
+	flag.BoolVar(&renderMDFlag, "render-markdown", false, "Style headings, bold text and code of the bodies when printing text output to a terminal")
+	flag.BoolVar(&closedByFlag, "include-closed-by", false, "Also write who closed a closed issue, when, and why")
+	flag.StringVar(&tokenFileFlag, "token-file", "", "File holding the GitHub access token, used when neither --token nor GITHUB_ACCESS_TOKEN is set")
+	flag.IntVar(&sampleFlag, "sample", 0, "Keep a random sample of this many comments, in their original order")
+	flag.Int64Var(&seedFlag, "seed", 0, "Seed for --sample, so runs pick the same comments (default a new sample each run)")
+	flag.Var(&authorFlag, "author", "Keep only comments by these logins; repeatable or comma separated")
+	flag.Var(&excludeAuthors, "exclude-author", "Leave out comments by these logins, applied after --author; repeatable or comma separated")
+	flag.StringVar(&archiveLayout, "archive-layout", "flat", "Where output files go: flat, or date to write OUTPUT/YYYY/MM/owner-repo-number.ext by the issue's creation date, with --output the archive directory (default output)")
+	flag.BoolVar(&printURLFlag, "print-url", false, "Print the API URLs that would be fetched, with their pagination parameters, and exit without requesting them")
+	flag.Float64Var(&rateFlag, "rate", 0, "Send at most this many API requests per second, e.g. 0.5 for one every two seconds (default no limit)")
+	flag.StringVar(&participantsCSV, "participants-csv", "", "Write a CSV of every commenter across the fetched issues with their comment and issue counts to this file")
+	flag.BoolVar(&minimizeNoise, "minimize-noise", false, "Clean up the archive in one go: same as --exclude-bots --flatten-quotes --dedup --normalize-newlines, where any of those given explicitly wins")
+	flag.BoolVar(&includeIssueFlag, "include-issue", true, "Fetch and write the issue itself; --include-issue=false writes only the comments")
+	flag.StringVar(&tokenFlag, "token", "", "GitHub access token (default $GITHUB_ACCESS_TOKEN, then --token-file, then the token saved with login)")
+	flag.StringVar(&baseURLFlag, "base-url", defaultBaseURL, "GitHub API base URL, e.g. https://github.example.com/api/v3 for Enterprise")
 }
 
 func main() {
-	// GitHub API endpoint to fetch issue/PR and comments
-	apiURL := "https://api.github.com/repos/{owner}/{repo}/issues/{issueNumber}"
+	// Document the exit codes alongside the flags
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprintf(flag.CommandLine.Output(), "\n%s", exitCodesUsage)
+	}
 
 	// Parse command-line flags
 	flag.Parse()
 
+	err := run()
+	if err != nil {
+		log.Print(err)
+		os.Exit(exitCode(err))
+	}
+}
+
+// run fetches the issue or commit and its comments and writes them out.
+func run() error {
+	// Print the build information for --version or the version subcommand
+	if versionFlag || flag.Arg(0) == "version" {
+		fmt.Println(versionString())
+		return nil
+	}
+
+	// Defaults from the global config file come under the flags given
+	err := applyGlobalConfig()
+	if err != nil {
+		return err
+	}
+	apiBaseURL = strings.TrimSuffix(baseURLFlag, "/")
+
+	// Save, forget or check the token for the base URL, taking flags after the subcommand too
+	switch subcommand := flag.Arg(0); subcommand {
+	case "login", "logout", "check-token":
+		err := flag.CommandLine.Parse(flag.Args()[1:])
+		if err != nil {
+			return usageErrorf("%w", err)
+		}
+		apiBaseURL = strings.TrimSuffix(baseURLFlag, "/")
+
+		switch subcommand {
+		case "login":
+			return login()
+		case "check-token":
+			return checkToken(os.Stdout)
+		}
+		return logout()
+	}
+
+	// Compare two earlier outputs without fetching anything
+	if diffFlag {
+		if flag.NArg() != 2 {
+			return usageErrorf("the --diff flag needs two JSON files, e.g. --diff old.json new.json")
+		}
+		return diffFiles(os.Stdout, flag.Arg(0), flag.Arg(1))
+	}
+
+	// Take the repository and issue numbers given as arguments
+	err = applyPositionalArgs()
+	if err != nil {
+		return err
+	}
+	if minimizeNoise {
+		err = applyNoisePreset(flag.CommandLine)
+		if err != nil {
+			return usageErrorf("%w", err)
+		}
+	}
+
 	// Get the absolute path to github-tree-inputs.txt
-	inputsFilePath := getAbsolutePath("github-comments-fetcher-inputs.txt")
+	inputsFilePath, err := getAbsolutePath("github-comments-fetcher-inputs.txt")
+	if err != nil {
+		return err
+	}
 
 	// Initialize variables to store inputs
 	var currentOwner string
 	var currentRepo string
 	var accessToken string
 	var currentIssueNumber string
+	var targets []target
 
 	// Check if github-comments-fetcher-inputs.txt exists
-	_, err := os.Stat(inputsFilePath)
+	_, err = os.Stat(inputsFilePath)
+
+	// With neither flags nor an inputs file there's nothing to fetch, so show how it's done
+	noInputsFile := !saveInputsFlag || err != nil
+	if noInputsFile && ownerFlag == "" && repoFlag == "" && issueNumberFlag == "" && issuesFileFlag == "" && orgFlag == "" && nodeIDFlag == "" && fromFileFlag == "" {
+		return usageErrorf("nothing to fetch; for example: %s -O octocat -R hello-world -I 1 (see -help for all flags)", filepath.Base(os.Args[0]))
+	}
 
-	if err == nil {
+	if !saveInputsFlag {
+		// Leave the inputs file alone and go by the flags alone
+		currentOwner = ownerFlag
+		currentRepo = repoFlag
+	} else if err == nil {
 		// The file exists, so read existing inputs from the file
-		currentOwner, currentRepo, currentIssueNumber = readInputsFromFile(inputsFilePath)
+		currentOwner, currentRepo, currentIssueNumber, targets, err = readInputsFromFile(inputsFilePath)
+		if err != nil {
+			return usageErrorf("%w", err)
+		}
 
-		// Check if the owner and repo fields are empty
-		if currentOwner == "" || currentRepo == "" {
-			panic("The 'owner' and 'repo' fields in github-comments-fetcher-inputs.txt cannot be empty")
+		// Check if the owner and repo fields are empty, unless targets are listed instead
+		if len(targets) == 0 && (currentOwner == "" || currentRepo == "") {
+			return usageErrorf("the 'owner' and 'repo' fields in github-comments-fetcher-inputs.txt cannot be empty")
 		}
 
 		// Update inputs if flags were provided
@@ -90,14 +468,35 @@ func main() {
 		if repoFlag != "" {
 			currentRepo = repoFlag
 		}
-		if issueNumberFlag != "" {
+		if issueNumberFlag != "" && issueNumberFlag != "-" {
 			currentIssueNumber = issueNumberFlag
 		}
 
 		// Update the inputs in the file
-		updateInputsInFile(inputsFilePath, currentOwner, currentRepo, currentIssueNumber)
+		err = updateInputsInFile(inputsFilePath, currentOwner, currentRepo, currentIssueNumber, targets)
+		if err != nil {
+			return err
+		}
+
+		// Fill in ${VAR} references only now, so the file keeps them
+		currentOwner = expandInput(currentOwner)
+		currentRepo = expandInput(currentRepo)
+		currentIssueNumber = expandInput(currentIssueNumber)
+		for i := range targets {
+			targets[i].Owner = expandInput(targets[i].Owner)
+			targets[i].Repo = expandInput(targets[i].Repo)
+			targets[i].IssueNumber = expandInput(targets[i].IssueNumber)
+		}
 	} else {
 		// The "github-comments-fetcher-inputs.txt" doesn't exist, so create it
+		currentOwner = ownerFlag
+		currentRepo = repoFlag
+
+		// Issue numbers read from stdin aren't worth remembering
+		savedIssueNumber := issueNumberFlag
+		if savedIssueNumber == "-" {
+			savedIssueNumber = ""
+		}
 
 		// Create a new inputs struct
 		newInputs := struct {
@@ -107,200 +506,1299 @@ func main() {
 		}{
 			Owner:       ownerFlag,
 			Repo:        repoFlag,
-			IssueNumber: issueNumberFlag,
+			IssueNumber: savedIssueNumber,
 		}
 
 		// Convert to JSON
 		newInputsJSON, err := json.MarshalIndent(newInputs, "", "  ")
 		if err != nil {
-			panic(fmt.Errorf("failed to marshal new inputs: %w", err))
+			return fmt.Errorf("failed to marshal new inputs: %w", err)
 		}
 
 		// Write to the file
 		err = os.WriteFile(inputsFilePath, newInputsJSON, 0644)
 		if err != nil {
-			panic(fmt.Errorf("failed to write new inputs to file: %w", err))
+			return fmt.Errorf("failed to write new inputs to file: %w", err)
 		}
 	}
 
-	// Retrieve access token from environment
-	accessToken = os.Getenv("GITHUB_ACCESS_TOKEN")
-	if accessToken == "" {
-		panic("GitHub access token not found in environment")
+	if typeFlag != "issue" && typeFlag != "commit" {
+		return usageErrorf("unknown --type %q; expected issue or commit", typeFlag)
+	}
+	if typeFlag == "commit" && shaFlag == "" {
+		return usageErrorf("the --sha flag is required with --type commit")
 	}
 
-	// GitHub repository information
-	owner := currentOwner
-	repo := currentRepo
-	issueNumber := currentIssueNumber // Replace with the issue or PR number you want to fetch
+	// Point out inputs that don't apply to what is fetched
+	if typeFlag == "commit" && (issueNumberFlag != "" || issuesFileFlag != "") {
+		log.Print("Ignoring the issue numbers given, as --type commit fetches the comments of the --sha commit")
+	}
+	if typeFlag == "issue" && shaFlag != "" {
+		log.Print("Ignoring --sha, which only applies to --type commit")
+	}
 
-	// Create the HTTP client
-	client := &http.Client{}
+	// The targets in the inputs file are fetched unless the flags pick what to fetch
+	useTargets := len(targets) > 0 && ownerFlag == "" && repoFlag == "" && issueNumberFlag == "" && issuesFileFlag == "" && searchFlag == "" && commentIDFlag == 0 && orgFlag == "" && nodeIDFlag == "" && fromFileFlag == ""
+	if useTargets && typeFlag != "issue" {
+		return usageErrorf("the targets in github-comments-fetcher-inputs.txt only work with --type issue")
+	}
 
-	// Create the request
-	req, err := http.NewRequest("GET", apiURL, nil)
+	if graphqlFlag && typeFlag != "issue" {
+		return usageErrorf("the --graphql flag only supports --type issue")
+	}
+	if !includeHidden && !graphqlFlag {
+		return usageErrorf("the --include-hidden=false flag requires --graphql to know which comments are hidden")
+	}
+
+	// jsonl-gz is NDJSON compressed and written page by page as the comments come in
+	if formatFlag == "jsonl-gz" {
+		if sampleFlag > 0 || sortFlag != "created" || saveRawFlag != "" || mergeFlag || noFileFlag {
+			return usageErrorf("the --format jsonl-gz output is written as the comments come in, so it can't be combined with --sample, --sort reactions, --save-raw, --merge or --no-file")
+		}
+		if graphqlFlag || schemaFlag == "gitea" || resumeFlag != "" {
+			return usageErrorf("the --format jsonl-gz output is written page by page from GitHub's REST API, so it can't be combined with --graphql, --schema gitea or --resume")
+		}
+		formatFlag = "ndjson"
+		gzipFlag = true
+		streamOutput = true
+		if outputFlag == "" {
+			outputFlag = "comments.jsonl"
+		}
+	}
+	if _, ok := formatExtensions[formatFlag]; !ok {
+		return usageErrorf("unknown --format %q; expected text, json, ndjson, jsonl-gz, xml or markdown", formatFlag)
+	}
+	if wrapFlag < 0 {
+		return usageErrorf("the --wrap flag must not be negative")
+	}
+	if tocFlag && formatFlag != "markdown" {
+		return usageErrorf("the --toc flag only works with --format markdown")
+	}
+	if templateFlag != "" && formatFlag != "text" {
+		return usageErrorf("the --template flag only works with --format text")
+	}
+	if fieldsFlag != "" && formatFlag != "json" && formatFlag != "ndjson" {
+		return usageErrorf("the --fields flag only works with --format json or ndjson")
+	}
+	fields, err := parseFields(fieldsFlag)
+	if err != nil {
+		return usageErrorf("%w", err)
+	}
+
+	err = configureTime(timeFormatFlag, timezoneFlag)
 	if err != nil {
-		log.Fatalf("Failed to create request: %s", err)
+		return usageErrorf("%w", err)
+	}
+
+	if searchFlag != "" && (typeFlag != "issue" || issueNumberFlag != "" || issuesFileFlag != "") {
+		return usageErrorf("the --search flag can't be combined with --type commit, -I or --issues-file")
+	}
+	if maxResultsFlag <= 0 {
+		return usageErrorf("the --max-results flag must be positive")
 	}
 
-	// Add the access token to the request header (optional)
-	if accessToken != "" {
-		req.Header.Set("Authorization", "Bearer "+accessToken)
+	if indentFlag < 0 {
+		return usageErrorf("the --indent flag can't be negative")
 	}
 
-	// Replace the placeholders in the API URL with the repository and issue information
-	req.URL.Path = fmt.Sprintf("/repos/%s/%s/issues/%s", owner, repo, issueNumber)
+	accept, ok := bodyMediaTypes[bodyFormatFlag]
+	if !ok {
+		return usageErrorf("unknown --body-format %q; expected raw, text or html", bodyFormatFlag)
+	}
+
+	if followFlag && (typeFlag != "issue" || formatFlag != "text" || templateFlag != "" || gzipFlag || countOnlyFlag) {
+		return usageErrorf("the --follow flag only works with the built-in text output of an issue")
+	}
+	if followFlag && strings.HasPrefix(outputFlag, s3Scheme) {
+		return usageErrorf("the --follow flag can't append to an s3:// output")
+	}
+	if manifestFlag != "" && (strings.HasPrefix(outputFlag, s3Scheme) || outputFlag == "-") {
+		return usageErrorf("the --manifest flag needs local output files to checksum, not s3:// or -")
+	}
+	if followFlag && intervalFlag <= 0 {
+		return usageErrorf("the --interval flag must be positive")
+	}
+
+	if anonymizeMap != "" && !anonymizeFlag {
+		return usageErrorf("the --anonymize-map flag requires --anonymize")
+	}
 
-	// Send the request
-	resp, err := client.Do(req)
+	if minReactionsFlag < 0 {
+		return usageErrorf("the --min-reactions flag can't be negative")
+	}
+	if sinceIDFlag < 0 {
+		return usageErrorf("the --since-comment-id flag can't be negative")
+	}
+	if sampleFlag < 0 {
+		return usageErrorf("the --sample flag can't be negative")
+	}
+	err = applySeed(flag.CommandLine)
 	if err != nil {
-		log.Fatalf("Failed to send request: %s", err)
+		return err
+	}
+	if sortFlag != "created" && sortFlag != "reactions" {
+		return usageErrorf("unknown --sort %q; expected created or reactions", sortFlag)
+	}
+	if followFlag && sortFlag != "created" {
+		return usageErrorf("the --follow flag only works with --sort created")
 	}
-	defer resp.Body.Close()
 
-	// Read the response body
-	body, err := io.ReadAll(resp.Body)
+	if commentIDFlag < 0 {
+		return usageErrorf("invalid --comment-id %d", commentIDFlag)
+	}
+	if commentIDFlag != 0 && (typeFlag != "issue" || searchFlag != "" || issuesFileFlag != "" || countOnlyFlag || followFlag || gzipFlag) {
+		return usageErrorf("the --comment-id flag can't be combined with --type commit, --search, --issues-file, --count-only, --follow or --gzip")
+	}
+
+	if maxBodyBytesFlag < 0 {
+		return usageErrorf("the --max-body-bytes flag can't be negative")
+	}
+	if onOversizeFlag != "truncate" && onOversizeFlag != "fail" {
+		return usageErrorf("unknown --on-oversize %q; expected truncate or fail", onOversizeFlag)
+	}
+
+	if mergeFlag && (typeFlag != "issue" || formatFlag != "text" || templateFlag != "" || gzipFlag || countOnlyFlag || followFlag) {
+		return usageErrorf("the --merge flag only works with the built-in text output of issues")
+	}
+	if mergeFlag && strings.Contains(outputFlag, "{") {
+		return usageErrorf("the --merge flag writes a single file, so --output can't use placeholders")
+	}
+
+	if (orgFlag == "") != (issueTitleFlag == "") {
+		return usageErrorf("the --org and --issue-title flags must be given together")
+	}
+	if orgFlag != "" && (typeFlag != "issue" || issueNumberFlag != "" || issuesFileFlag != "" || searchFlag != "" || commentIDFlag != 0) {
+		return usageErrorf("the --org flag can't be combined with --type commit, -I, --issues-file, --search or --comment-id")
+	}
+	if nodeIDFlag != "" && (typeFlag != "issue" || issueNumberFlag != "" || issuesFileFlag != "" || searchFlag != "" || commentIDFlag != 0 || orgFlag != "") {
+		return usageErrorf("the --node-id flag can't be combined with --type commit, -I, --issues-file, --search, --comment-id or --org")
+	}
+
+	if compactFlag && (formatFlag != "text" || templateFlag != "" || bodyOnlyFlag || followFlag || mergeFlag) {
+		return usageErrorf("the --compact flag only works with the built-in text output, without --body-only, --follow or --merge")
+	}
+
+	if headFlag < 0 {
+		return usageErrorf("the --head flag can't be negative")
+	}
+	if headFlag > 0 && (outputFlag == "-" || countOnlyFlag || mergeFlag || followFlag) {
+		return usageErrorf("the --head flag previews the output file, so it can't be combined with -o -, --count-only, --merge or --follow")
+	}
+	if noFileFlag && (headFlag == 0 || manifestFlag != "" || stateFileFlag != "" || failIfEmptyFlag) {
+		return usageErrorf("the --no-file flag requires --head and can't be combined with --manifest, --state-file or --fail-if-empty")
+	}
+
+	if watchLabelsFlag && (typeFlag != "issue" || !includeIssueFlag) {
+		return usageErrorf("the --watch-labels flag needs the issue, so it can't be combined with --type commit or --include-issue=false")
+	}
+	if resumeFlag != "" && (typeFlag != "issue" || graphqlFlag || schemaFlag != "github" || fromFileFlag != "") {
+		return usageErrorf("the --resume flag only works with issue comments fetched from GitHub's REST API, without --type commit, --graphql, --schema gitea or --from-file")
+	}
+	if onlyUnresolved && !reviewFlag {
+		return usageErrorf("the --only-unresolved flag filters review threads, so it requires --review-threads")
+	}
+	if stripHTMLFlag && bodyFormatFlag != "html" {
+		return usageErrorf("the --strip-html flag converts HTML bodies, so it requires --body-format html")
+	}
+	if printURLFlag && (searchFlag != "" || orgFlag != "" || nodeIDFlag != "" || fromFileFlag != "" || commentIDFlag != 0) {
+		return usageErrorf("the --print-url flag can't be combined with --search, --org, --node-id, --comment-id or --from-file, which need requests to find the issue")
+	}
+	if archiveLayout != "flat" && archiveLayout != "date" {
+		return usageErrorf("unknown --archive-layout %q; expected flat or date", archiveLayout)
+	}
+	if archiveLayout == "date" && (typeFlag != "issue" || !includeIssueFlag || outputFlag == "-" || strings.Contains(outputFlag, "{") || mergeFlag || followFlag || countOnlyFlag) {
+		return usageErrorf("the --archive-layout date flag names the files itself by the issue's creation date under the --output directory, so it only works for issues, without --include-issue=false, -o -, placeholders, --merge, --follow or --count-only")
+	}
+	if participantsCSV != "" && (countOnlyFlag || followFlag) {
+		return usageErrorf("the --participants-csv flag can't be combined with --count-only or --follow")
+	}
+	if groupByFlag != "" && groupByFlag != "author" {
+		return usageErrorf("unknown --group-by %q; expected author", groupByFlag)
+	}
+	if groupByFlag != "" && ((formatFlag != "text" && formatFlag != "markdown") || templateFlag != "" || compactFlag || followFlag || mergeFlag) {
+		return usageErrorf("the --group-by flag only works with the built-in text and markdown output, without --template, --compact, --follow or --merge")
+	}
+	if (translateToFlag == "") != (translateURLFlag == "") || (translateKeyFlag != "" && translateToFlag == "") {
+		return usageErrorf("the --translate-to and --translate-url flags must be given together, and --translate-key needs both")
+	}
+	if closedByFlag && (typeFlag != "issue" || !includeIssueFlag) {
+		return usageErrorf("the --include-closed-by flag needs the issue, so it can't be combined with --type commit or --include-issue=false")
+	}
+	if linkedPRsFlag && (typeFlag != "issue" || !includeIssueFlag) {
+		return usageErrorf("the --linked-prs flag needs the issue, so it can't be combined with --type commit or --include-issue=false")
+	}
+
+	if bodyOnlyFlag && (typeFlag != "issue" || !includeIssueFlag || formatFlag != "text" || templateFlag != "" || countOnlyFlag || followFlag || mergeFlag || failIfEmptyFlag) {
+		return usageErrorf("the --body-only flag only works with the built-in text output of an issue")
+	}
+
+	if fromFileFlag != "" && (prettyAuthorFlag || resolveFlag || followFlag || countOnlyFlag || searchFlag != "" || orgFlag != "" || nodeIDFlag != "" || commentIDFlag != 0 || saveRawFlag != "" || reactorsFlag || linkedPRsFlag || sinceTagFlag != "" || watchLabelsFlag || onlyUnresolved || closedByFlag) {
+		return usageErrorf("the --from-file flag can't be combined with flags that need GitHub")
+	}
+
+	if schemaFlag != "github" && schemaFlag != "gitea" {
+		return usageErrorf("unknown --schema %q; expected github or gitea", schemaFlag)
+	}
+	if schemaFlag == "gitea" && (typeFlag != "issue" || graphqlFlag || bodyFormatFlag != "raw" || searchFlag != "" || orgFlag != "" || nodeIDFlag != "" || linkedPRsFlag || sinceTagFlag != "" || reviewFlag || watchLabelsFlag || closedByFlag) {
+		return usageErrorf("--schema gitea only fetches issue and PR comments, without --type commit, --graphql, --body-format, --search, --org, --node-id, --linked-prs, --since-tag, --review-threads, --watch-labels or --include-closed-by")
+	}
+
+	if rateFlag < 0 {
+		return usageErrorf("the --rate flag can't be negative")
+	}
+	if retryBudgetFlag < 0 {
+		return usageErrorf("the --retry-budget flag can't be negative")
+	}
+
+	if maxIdleConnsFlag < 0 || idleTimeoutFlag < 0 {
+		return usageErrorf("the --max-idle-conns and --idle-conn-timeout flags can't be negative")
+	}
+
+	if cacheTTLFlag < 0 {
+		return usageErrorf("the --cache-ttl flag can't be negative")
+	}
+	if cacheTTLFlag > 0 && cacheFlag == "" {
+		return usageErrorf("the --cache-ttl flag requires --cache")
+	}
+
+	if excludeBotsFlag && onlyBotsFlag {
+		return usageErrorf("the --exclude-bots and --only-bots flags cannot be used together")
+	}
+
+	if headersFlag.header.Get("Authorization") != "" {
+		log.Print("Sending the Authorization header given with --header instead of the token's")
+	}
+
+	// Retrieve access token from the flags, environment or keyring, unless working offline
+	if fromFileFlag == "" && !printURLFlag {
+		accessToken = resolveToken()
+		if accessToken == "" {
+			return authErrorf("GitHub access token not found; pass --token, set GITHUB_ACCESS_TOKEN or run login")
+		}
+	}
+
+	// GitHub repository information, checked before it ends up in a URL
+	owner := currentOwner
+	repo := currentRepo
+	if orgFlag != "" {
+		err = validateOwnerName(orgFlag)
+		if err != nil {
+			return usageErrorf("%w", err)
+		}
+	} else if !useTargets && fromFileFlag == "" && nodeIDFlag == "" {
+		err = validateOwnerName(owner)
+		if err != nil {
+			return usageErrorf("%w", err)
+		}
+		err = validateRepoName(repo)
+		if err != nil {
+			return usageErrorf("%w", err)
+		}
+	}
+
+	// Create the HTTP client
+	client, err := newHTTPClient()
 	if err != nil {
-		log.Fatalf("Failed to read response body: %s", err)
+		return usageErrorf("%w", err)
+	}
+	// Record the API traffic for --har, written out even when the run fails
+	if harFlag != "" {
+		recorder := newHARRecorder(client.Transport)
+		client.Transport = recorder
+		defer func() {
+			err := recorder.save(harFlag)
+			if err != nil {
+				log.Print(err)
+			}
+		}()
+	}
+
+	// Ctrl-C cancels the requests in flight; whatever was fetched is still written
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	f := &fetcher{ctx: ctx, client: client, accessToken: accessToken, accept: accept, stats: newRunStats()}
+
+	// Work from a saved thread instead of GitHub
+	if fromFileFlag != "" {
+		f.offline, err = loadRaw(fromFileFlag)
+		if err != nil {
+			return usageErrorf("%w", err)
+		}
+	}
+
+	// Pick up where the last run left off
+	if stateFileFlag != "" {
+		f.state, err = loadState(stateFileFlag)
+		if err != nil {
+			return err
+		}
+	}
+	if resumeFlag != "" {
+		f.resume, err = loadResume(resumeFlag)
+		if err != nil {
+			return err
+		}
+	}
+	if translateToFlag != "" {
+		commentTranslator = newHTTPTranslator(translateURLFlag, translateKeyFlag)
+	}
+
+	// Keep responses around for the next run
+	if cacheFlag != "" {
+		f.cache, err = newResponseCache(cacheFlag, cacheTTLFlag, accessToken)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Pseudonyms are shared by all issues so the same person keeps the same name
+	var names *anonymizer
+	if anonymizeFlag {
+		names = newAnonymizer()
+	}
+
+	// A single comment skips the listing altogether
+	if commentIDFlag != 0 {
+		return saveCommentBody(f, owner, repo, commentIDFlag, names)
+	}
+
+	// Work out which issues or PRs to fetch
+	var issueNumbers []string
+	if fromFileFlag != "" {
+		// The saved file holds a single thread
+		issueNumbers = []string{""}
+	} else if orgFlag != "" {
+		match, title, err := f.findIssueByTitle(orgFlag, issueTitleFlag)
+		if err != nil {
+			return err
+		}
+		statusf("Found %s/%s#%s: %s\n", match.Owner, match.Repo, match.IssueNumber, title)
+
+		owner, repo = match.Owner, match.Repo
+		issueNumbers = []string{match.IssueNumber}
+	} else if nodeIDFlag != "" {
+		match, err := f.resolveNode(nodeIDFlag)
+		if err != nil {
+			return err
+		}
+		statusf("Node %s is %s/%s#%s.\n", nodeIDFlag, match.Owner, match.Repo, match.IssueNumber)
+
+		owner, repo = match.Owner, match.Repo
+		issueNumbers = []string{match.IssueNumber}
+	} else if typeFlag == "issue" && searchFlag != "" {
+		query := fmt.Sprintf("%s repo:%s/%s", searchFlag, owner, repo)
+		issueNumbers, err = f.searchIssues(query, maxResultsFlag)
+		if err != nil {
+			return err
+		}
+		if len(issueNumbers) == 0 {
+			statusf("No issues match the search.\n")
+			return nil
+		}
+		statusf("The search matched %d issue(s).\n", len(issueNumbers))
+	} else if typeFlag == "issue" && !useTargets {
+		issueNumbers, err = resolveIssueNumbers(currentIssueNumber)
+		if err != nil {
+			return usageErrorf("%w", err)
+		}
+		if len(issueNumbers) == 0 {
+			return usageErrorf("no issue number given; use -I, --issues-file or the inputs file")
+		}
+	}
+
+	// Commits have a single thread that isn't identified by an issue number
+	if typeFlag == "commit" {
+		issueNumbers = []string{""}
 	}
 
-	// Check the response status code
-	if resp.StatusCode != http.StatusOK {
-		log.Fatalf("Request failed with status: %s", resp.Status)
+	// Pair every issue with its repository
+	var jobs []target
+	if useTargets {
+		jobs, err = expandTargets(targets)
+		if err != nil {
+			return usageErrorf("%w", err)
+		}
 	}
+	for _, issueNumber := range issueNumbers {
+		jobs = append(jobs, target{Owner: owner, Repo: repo, IssueNumber: issueNumber})
+	}
+	multiRepo := spansRepos(jobs)
+
+	// Show what would be requested instead of requesting it
+	if printURLFlag {
+		printURLs(os.Stdout, jobs)
+		return nil
+	}
+
+	if saveRawFlag != "" && len(jobs) > 1 {
+		return usageErrorf("the --save-raw flag can only save one issue at a time")
+	}
+	if followFlag && len(jobs) > 1 {
+		return usageErrorf("the --follow flag can only follow one issue at a time")
+	}
+
+	// Keep going when one issue fails so the others still get saved
+	var saved []savedThread
+	var merged []mergedPost
+	var mergedComments []Comment
+	tally := make(participants)
+	failed := 0
+	for _, job := range jobs {
+		owner, repo, issueNumber := job.Owner, job.Repo, job.IssueNumber
+
+		// Tell the threads apart by number, and by repository when there are several
+		label := ""
+		if len(jobs) > 1 {
+			label = issueNumber
+			if multiRepo {
+				label = owner + "-" + repo + "-" + issueNumber
+			}
+		}
+
+		var thread savedThread
+		var threadErr error
+		if countOnlyFlag {
+			// Only print how many comments there are when that's all that's wanted
+			threadErr = printCommentCount(f, owner, repo, issueNumber, label)
+		} else if mergeFlag {
+			// Gather the posts now and write them all out together below
+			postLabel := "#" + issueNumber
+			if multiRepo {
+				postLabel = owner + "/" + repo + postLabel
+			}
+
+			var posts []mergedPost
+			var comments []Comment
+			posts, comments, threadErr = collectPosts(f, owner, repo, issueNumber, postLabel, names)
+			merged = append(merged, posts...)
+			mergedComments = append(mergedComments, comments...)
+			tally.add(comments)
+		} else {
+			outputFile := outputFileName(label)
+
+			startedAt := time.Now()
+
+			thread, threadErr = saveThread(f, owner, repo, issueNumber, outputFile, names, fields)
+			if threadErr == nil {
+				saved = append(saved, thread)
+				tally.addCounts(thread.authors)
+			}
+
+			// Keep watching the issue for new comments
+			if threadErr == nil && followFlag {
+				threadErr = followComments(f, owner, repo, thread, startedAt, names)
+			}
+		}
+
+		if threadErr != nil {
+			// Stop right away when interrupted, pointing at what got written
+			if ctx.Err() != nil {
+				if thread.outputFile != "" && thread.outputFile != "-" {
+					return &exitError{code: exitInterrupted, err: fmt.Errorf("interrupted; partial output saved to %s", thread.outputFile)}
+				}
+				return &exitError{code: exitInterrupted, err: errInterrupted}
+			}
+
+			if len(jobs) == 1 {
+				return threadErr
+			}
 
-	// Parse the response body as an Issue
+			log.Printf("Issue %s/%s#%s: %s", owner, repo, issueNumber, threadErr)
+			failed++
+			err = threadErr
+		}
+	}
+
+	// Write the interleaved report of every issue that could be fetched
+	if mergeFlag && len(merged) > 0 {
+		outputFile := outputFileName("")
+		mergeErr := saveMerged(outputFile, merged)
+		if mergeErr != nil {
+			return mergeErr
+		}
+		thread := savedThread{owner: jobs[0].Owner, repo: jobs[0].Repo, outputFile: outputFile}
+		thread.add(mergedComments)
+		saved = append(saved, thread)
+	}
+
+	// Save how far every issue got for the next run
+	if f.state != nil {
+		stateErr := f.state.save()
+		if stateErr != nil {
+			return stateErr
+		}
+	}
+
+	// Sum up what the run cost
+	if statsFlag {
+		for _, thread := range saved {
+			f.stats.issues++
+			f.stats.comments += thread.count
+		}
+		statusf("%s\n", f.stats.summary())
+	}
+
+	// Describe what was written for archival pipelines
+	if manifestFlag != "" && len(saved) > 0 {
+		manifestErr := writeManifest(manifestFlag, jobs[0].Owner, jobs[0].Repo, saved)
+		if manifestErr != nil {
+			return manifestErr
+		}
+	}
+
+	// Sum up who took part across the issues
+	if participantsCSV != "" {
+		csvErr := tally.writeCSV(participantsCSV)
+		if csvErr != nil {
+			return csvErr
+		}
+	}
+
+	// Save who is behind each pseudonym
+	if names != nil && anonymizeMap != "" && len(saved) > 0 {
+		mapErr := names.writeMap(anonymizeMap)
+		if mapErr != nil {
+			return mapErr
+		}
+	}
+
+	// Let cron jobs be monitored
+	if metricsFileFlag != "" {
+		metricsErr := writeMetrics(metricsFileFlag, f.stats, saved, failed == 0)
+		if metricsErr != nil {
+			return metricsErr
+		}
+	}
+
+	if failed == len(jobs) {
+		return err
+	}
+	if failed > 0 {
+		return &exitError{code: exitPartial, err: fmt.Errorf("%d of %d issues could not be fetched", failed, len(jobs))}
+	}
+
+	// Let monitoring notice threads that came back without comments
+	if failIfEmptyFlag {
+		return checkEmpty(saved)
+	}
+
+	return nil
+}
+
+// checkEmpty fails with exitEmpty when any of the saved threads has no
+// comments, for --fail-if-empty.
+func checkEmpty(saved []savedThread) error {
+	for _, thread := range saved {
+		if thread.count == 0 {
+			return &exitError{code: exitEmpty, err: fmt.Errorf("no comments were written to %s", thread.outputFile)}
+		}
+	}
+	return nil
+}
+
+// resolveIssueNumbers collects the issue numbers given with -I (a comma
+// separated list, or - to read them from stdin) and --issues-file. The saved
+// number from the inputs file is used when neither is given.
+func resolveIssueNumbers(saved string) ([]string, error) {
+	var numbers []string
+
+	switch issueNumberFlag {
+	case "-":
+		fromStdin, err := readIssueNumbers(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		numbers = append(numbers, fromStdin...)
+	case "":
+		if issuesFileFlag == "" {
+			return parseIssueNumbers(saved)
+		}
+	default:
+		fromFlag, err := parseIssueNumbers(issueNumberFlag)
+		if err != nil {
+			return nil, err
+		}
+		numbers = append(numbers, fromFlag...)
+	}
+
+	if issuesFileFlag != "" {
+		fromFile, err := readIssueNumbersFile(issuesFileFlag)
+		if err != nil {
+			return nil, err
+		}
+		numbers = append(numbers, fromFile...)
+	}
+
+	return numbers, nil
+}
+
+// File extension used for each --format
+var formatExtensions = map[string]string{
+	"text":     ".txt",
+	"json":     ".json",
+	"ndjson":   ".ndjson",
+	"xml":      ".xml",
+	"markdown": ".md",
+}
+
+// outputFileName names the output file, including the label (the issue
+// number, with the repository when several are fetched) when several issues
+// are written.
+func outputFileName(label string) string {
+	if outputFlag == "-" {
+		return "-"
+	}
+
+	// Start from --output, or comments with the extension of the format
+	name := outputFlag
+	if name == "" {
+		name = "comments" + formatExtensions[formatFlag]
+	}
+
+	// Names with placeholders are filled in once the issue is known
+	name = strings.TrimSuffix(name, ".gz")
+	if label != "" && !strings.Contains(name, "{") {
+		ext := filepath.Ext(name)
+		name = strings.TrimSuffix(name, ext) + "-" + label + ext
+	}
+
+	if gzipFlag {
+		name += ".gz"
+	}
+	return name
+}
+
+// statusf prints a progress message, on stderr when the output itself goes to stdout.
+func statusf(format string, args ...interface{}) {
+	var w io.Writer = os.Stdout
+	if outputFlag == "-" || countOnlyFlag || headFlag > 0 {
+		w = os.Stderr
+	}
+	fmt.Fprintf(w, format, args...)
+}
+
+// What saveThread wrote for one issue or commit. Streamed threads never hold
+// all their comments at once, so only counts and IDs are kept.
+type savedThread struct {
+	owner       string
+	repo        string
+	issueNumber string
+	outputFile  string
+	count       int            // comments written
+	ids         []int64        // IDs of the comments written, so --follow skips them
+	authors     map[string]int // comments written by login, for --participants-csv
+}
+
+// add counts comments as written to the thread.
+func (t *savedThread) add(comments []Comment) {
+	t.count += len(comments)
+	for _, comment := range comments {
+		t.ids = append(t.ids, comment.ID)
+	}
+	t.authors = commentsByAuthor(t.authors, comments)
+}
+
+// fetchThread fetches an issue (or the commit given with --sha) along with its
+// comments, leaving out the comments filtered away by the flags. When
+// interrupted it returns the comments fetched so far with errInterrupted.
+func fetchThread(f *fetcher, owner, repo, issueNumber string) (Issue, []Comment, error) {
 	var issue Issue
-	err = json.Unmarshal(body, &issue)
+	var comments []Comment
+
+	// Looks up the --since-tag release before anything else is fetched
+	filter, err := f.commentFilter(owner, repo, issueNumber)
+	if err != nil {
+		return Issue{}, nil, err
+	}
+
+	// Fetch the issue and its comments, or the comments of the commit
+	if f.offline != nil {
+		issue, comments = f.offline.thread()
+	} else if typeFlag == "commit" {
+		comments, err = f.fetchCommitComments(owner, repo, shaFlag)
+		if err != nil && !errors.Is(err, errInterrupted) {
+			return Issue{}, nil, err
+		}
+	} else {
+		// Skip the issue request entirely when only the comments are wanted
+		if includeIssueFlag {
+			issue, err = fetchIssueDetails(f, owner, repo, issueNumber)
+			if errors.Is(err, errInterrupted) {
+				return issue, nil, err
+			}
+			if err != nil {
+				return Issue{}, nil, err
+			}
+		}
+
+		// The description is all that's written with --body-only
+		if !bodyOnlyFlag {
+			comments, err = f.fetchComments(owner, repo, issueNumber)
+			if err != nil && !errors.Is(err, errInterrupted) {
+				return Issue{}, nil, err
+			}
+		}
+	}
+
+	// Keep what GitHub returned for later runs with --from-file
+	if saveRawFlag != "" && f.offline == nil && err == nil {
+		rawErr := saveRaw(saveRawFlag, issue, comments)
+		if rawErr != nil {
+			return Issue{}, nil, rawErr
+		}
+	}
+
+	// Leave out the comments the flags drop, the same way --follow does
+	comments = filter.apply(comments, f.state)
+	if dedupFlag != dedupOff {
+		statusf("Removed %d duplicate comment(s).\n", filter.removed)
+	}
+
+	// Cut a huge thread down to a random sample, best first if asked to
+	if sampleFlag > 0 {
+		comments = sampleComments(comments, sampleFlag, seedFlag)
+	}
+	sortComments(comments, sortFlag)
+
+	err = completeComments(f, owner, repo, &issue, comments, err != nil)
+	if err != nil && !errors.Is(err, errInterrupted) {
+		return Issue{}, nil, err
+	}
+
+	// err is either nil or errInterrupted at this point
+	return issue, comments, err
+}
+
+// completeComments adds what the flags ask for to the issue and the comments
+// that were kept: who reacted, capped bodies, display names and what
+// references point to. Once interrupted no more requests are made, and it
+// returns errInterrupted.
+func completeComments(f *fetcher, owner, repo string, issue *Issue, comments []Comment, interrupted bool) error {
+	// Show who reacted, not just how many
+	if reactorsFlag && !interrupted {
+		err := f.fetchDetailedReactions(owner, repo, comments, typeFlag == "commit")
+		if err != nil && !errors.Is(err, errInterrupted) {
+			return err
+		}
+		interrupted = err != nil
+	}
+
+	// Keep oversized bodies in check, cutting them short or giving up
+	if maxBodyBytesFlag > 0 {
+		oversized, err := limitBodies(issue, comments)
+		if err != nil {
+			return err
+		}
+		if oversized > 0 {
+			statusf("Truncated %d oversized body(ies).\n", oversized)
+		}
+	}
+
+	// Look up the display names of the people taking part
+	if prettyAuthorFlag && !interrupted && schemaFlag == "github" {
+		f.resolveNames(issue, comments)
+	}
+
+	// Say what the referenced issues and PRs are about
+	if resolveFlag && !interrupted {
+		issue.Body = f.resolveReferences(owner, repo, issue.Body)
+		for i := range comments {
+			comments[i].Body = f.resolveReferences(owner, repo, comments[i].Body)
+		}
+	}
+
+	if interrupted {
+		return errInterrupted
+	}
+	return nil
+}
+
+// fetchIssueDetails fetches the issue along with what the flags add to it:
+// pull request details, linked pull requests, its events and review threads.
+// When interrupted it returns what it got with errInterrupted.
+func fetchIssueDetails(f *fetcher, owner, repo, issueNumber string) (Issue, error) {
+	issue, err := f.fetchIssue(owner, repo, issueNumber)
 	if err != nil {
-		log.Fatalf("Failed to parse issue response body: %s", err)
+		return Issue{}, err
+	}
+
+	// Closed issues rarely change, so --cache-ttl may reuse their comments without asking
+	if f.cache != nil && issue.State == "closed" {
+		f.cache.markClosed(issueURL(owner, repo, issueNumber))
+	}
+
+	// The merge status of pull requests lives on a separate endpoint
+	if issue.PullRequestLinks != nil {
+		issue.PullRequest, err = f.fetchPullRequest(owner, repo, issueNumber)
+		if err != nil {
+			log.Printf("Leaving out pull request details: %s", err)
+		}
+	}
+
+	// Find out which pull requests resolve the issue
+	if issue.PullRequestLinks == nil && linkedPRsFlag {
+		var linkErr error
+		issue.LinkedPRs, linkErr = f.fetchLinkedPRs(owner, repo, issueNumber)
+		if linkErr != nil {
+			log.Printf("Leaving out linked pull requests: %s", linkErr)
+		}
 	}
 
-	// Create or open the output file
-	file, err := os.Create("comments.txt")
+	// Follow how the issue was triaged, and who closed it
+	if watchLabelsFlag || (closedByFlag && issue.State == "closed") {
+		events, err := f.fetchIssueEvents(owner, repo, issueNumber)
+		if err != nil {
+			return Issue{}, err
+		}
+		if watchLabelsFlag {
+			issue.LabelEvents = labelEvents(events)
+		}
+		if closedByFlag {
+			issue.ClosedBy = findClosure(events, issue.StateReason)
+		}
+	}
+
+	// Review comments of pull requests are listed separately too
+	if issue.PullRequestLinks != nil && !reviewFlag {
+		log.Printf("Target #%s is a pull request; use --review-threads to get its inline review comments", issueNumber)
+	}
+	if issue.PullRequestLinks != nil && reviewFlag {
+		issue.ReviewComments, err = f.fetchReviewComments(owner, repo, issueNumber)
+		if err != nil && !errors.Is(err, errInterrupted) {
+			return Issue{}, err
+		}
+		if err != nil {
+			return issue, err
+		}
+
+		// Leave out the discussions reviewers have settled
+		if onlyUnresolved {
+			resolved, err := f.fetchResolvedThreads(owner, repo, issueNumber)
+			if err != nil {
+				return Issue{}, err
+			}
+			issue.ReviewComments = dropResolvedThreads(issue.ReviewComments, resolved)
+		}
+	}
+
+	return issue, nil
+}
+
+// filteringComments reports whether any flag drops comments, in which case
+// the comment count on the issue can't be trusted.
+func filteringComments() bool {
+	return !includeHidden || excludeBotsFlag || onlyBotsFlag || len(authorFlag) > 0 || len(excludeAuthors) > 0 || dedupFlag != dedupOff || minReactionsFlag > 0 || sinceTagFlag != "" || sinceIDFlag > 0 || sampleFlag > 0
+}
+
+// countComments counts the comments of an issue or commit. The count GitHub
+// keeps on the issue is used when no comments are filtered out, which saves
+// fetching them.
+func countComments(f *fetcher, owner, repo, issueNumber string) (int, error) {
+	if typeFlag == "issue" && includeIssueFlag && !filteringComments() {
+		issue, err := f.fetchIssue(owner, repo, issueNumber)
+		if err != nil {
+			return 0, err
+		}
+		return issue.Comments, nil
+	}
+
+	_, comments, err := fetchThread(f, owner, repo, issueNumber)
 	if err != nil {
-		log.Fatalf("Failed to create file: %s", err)
+		return 0, err
 	}
-	defer file.Close()
+	return len(comments), nil
+}
 
-	// Write the issue details to the file
-	issueLine := fmt.Sprintf("Issue Title: %s\nIssue Body: %s\nIssue Author: %s\nCreated At: %s\nUpdated At: %s\n\n",
-		issue.Title, issue.Body, issue.User.Login, issue.DateTime.Format("2006-01-02 15:04:05"), issue.UpdatedAt.Format("2006-01-02 15:04:05"))
-	_, err = file.WriteString(issueLine)
+// printCommentCount prints the number of comments, prefixed with the label
+// when counting several issues.
+func printCommentCount(f *fetcher, owner, repo, issueNumber, label string) error {
+	count, err := countComments(f, owner, repo, issueNumber)
 	if err != nil {
-		log.Fatalf("Failed to write issue details to file: %s", err)
+		return err
 	}
 
-	// Fetch comments
-	apiCommentsURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/issues/%s/comments", owner, repo, issueNumber)
+	if label != "" {
+		fmt.Printf("%s %d\n", label, count)
+	} else {
+		fmt.Println(count)
+	}
+	return nil
+}
 
-	reqComments, err := http.NewRequest("GET", apiCommentsURL, nil)
+// saveCommentBody fetches one comment and writes just its body to the
+// output file, or stdout with -o -.
+func saveCommentBody(f *fetcher, owner, repo string, id int64, names *anonymizer) error {
+	comment, err := f.fetchComment(owner, repo, id)
 	if err != nil {
-		log.Fatalf("Failed to create comments request: %s", err)
+		return err
 	}
 
-	if accessToken != "" {
-		reqComments.Header.Set("Authorization", "Bearer "+accessToken)
+	comments := []Comment{comment}
+	prepareThread(&Issue{}, comments, names)
+
+	outputFile := outputFileName("")
+	if outputFile == "-" {
+		_, err = fmt.Println(comments[0].Body)
+		return err
 	}
 
-	respComments, err := client.Do(reqComments)
+	dest, err := openOutput(outputFile)
 	if err != nil {
-		log.Fatalf("Failed to send comments request: %s", err)
+		return err
 	}
-	defer respComments.Body.Close()
+	defer dest.Close()
 
-	bodyComments, err := io.ReadAll(respComments.Body)
+	_, err = io.WriteString(dest, comments[0].Body+"\n")
 	if err != nil {
-		log.Fatalf("Failed to read comments response body: %s", err)
+		return fmt.Errorf("failed to write output: %w", err)
 	}
+	err = dest.commit()
+	if err != nil {
+		return err
+	}
+	statusf("Comment %d has been saved to %s.\n", id, outputFile)
+	return nil
+}
 
-	if respComments.StatusCode != http.StatusOK {
-		log.Fatalf("Comments request failed with status: %s", respComments.Status)
+// prepareThread applies the body and author transformations asked for with
+// the flags to the issue and comments in place, returning how many secrets
+// were redacted.
+func prepareThread(issue *Issue, comments []Comment, names *anonymizer) int {
+	// Review comments get the same treatment as the rest
+	redactions := 0
+	if len(issue.ReviewComments) > 0 {
+		redactions = prepareThread(&Issue{}, issue.ReviewComments, names)
 	}
 
-	var comments []Comment
-	err = json.Unmarshal(bodyComments, &comments)
-	if err != nil {
-		log.Fatalf("Failed to parse comments response body: %s", err)
+	// Replace bytes that aren't UTF-8, as some mirrors send, so no broken characters are written
+	issue.Body = strings.ToValidUTF8(issue.Body, "\uFFFD")
+	for i := range comments {
+		comments[i].Body = strings.ToValidUTF8(comments[i].Body, "\uFFFD")
+	}
+
+	// Read the bodies GitHub rendered as plain text
+	if stripHTMLFlag {
+		issue.Body = htmlToText(issue.Body)
+		for i := range comments {
+			comments[i].Body = htmlToText(comments[i].Body)
+		}
 	}
 
-	// Write the comments to the file
-	for i, comment := range comments {
-		if i > 0 {
-			_, err = file.WriteString("\n") // Leave two-line space between comment blocks
+	// Drop the text replies quote from earlier comments
+	if flattenFlag {
+		stripped := 0
+		for i := range comments {
+			var n int
+			comments[i].Body, n = flattenQuotes(comments[i].Body)
+			stripped += n
+		}
+		if stripped > 0 {
+			statusf("Stripped %d quoted line(s) from the comments.\n", stripped)
+		}
+	}
+
+	// Mask secrets, keeping track of how many were found
+	if redactFlag {
+		var n int
+		issue.Body, n = redactSecrets(issue.Body)
+		redactions += n
+
+		for i := range comments {
+			comments[i].Body, n = redactSecrets(comments[i].Body)
+			redactions += n
+		}
+	}
+
+	// Hide who took part in the discussion
+	if names != nil {
+		names.apply(issue, comments)
+	}
+
+	// Hand each comment to the user's processor, keeping the original when it fails
+	if execFlag != "" {
+		for i := range comments {
+			body, err := runFilter(execFlag, comments[i].Body)
 			if err != nil {
-				log.Fatalf("Failed to write space to file: %s", err)
+				log.Printf("Keeping the original body: %s", err)
+				continue
 			}
+			comments[i].Body = body
+		}
+	}
+
+	// Add translations, sent off only once secrets are masked
+	if commentTranslator != nil {
+		translateComments(commentTranslator, translateToFlag, comments)
+	}
+
+	// Tidy up line endings for the text output, JSON stays faithful to GitHub
+	if normalizeFlag && formatFlag == "text" {
+		issue.Body = normalizeNewlines(issue.Body)
+		for i := range comments {
+			comments[i].Body = normalizeNewlines(comments[i].Body)
+			comments[i].Translation = normalizeNewlines(comments[i].Translation)
+		}
+	}
+
+	// Wrap long lines so the text output reads well in a terminal
+	if wrapFlag > 0 && formatFlag == "text" {
+		issue.Body = wrapText(issue.Body, wrapFlag)
+		for i := range comments {
+			comments[i].Body = wrapText(comments[i].Body, wrapFlag)
+			comments[i].Translation = wrapText(comments[i].Translation, wrapFlag)
+		}
+	}
+
+	return redactions
+}
+
+// saveThread fetches an issue (or the commit given with --sha) along with its
+// comments, applies the requested filters and writes the result to outputFile.
+func saveThread(f *fetcher, owner, repo, issueNumber, outputFile string, names *anonymizer, fields []string) (savedThread, error) {
+	if streamOutput {
+		return streamThread(f, owner, repo, issueNumber, outputFile, names, fields)
+	}
+
+	issue, comments, err := fetchThread(f, owner, repo, issueNumber)
+	if err != nil && !errors.Is(err, errInterrupted) {
+		return savedThread{}, err
+	}
+	interrupted := err
+
+	redactions := prepareThread(&issue, comments, names)
+
+	// Placeholders are filled in after anonymizing so the file name can't give anyone away
+	pattern := outputFile
+	outputFile, err = expandOutputName(pattern, issueNumber, issue)
+	if err != nil {
+		return savedThread{}, usageErrorf("%w", err)
+	}
+	if outputFile != pattern && !noFileFlag {
+		err = makeOutputDir(outputFile)
+		if err != nil {
+			return savedThread{}, err
+		}
+	}
+
+	// Sort the issue into the archive by the month it was opened
+	if archiveLayout == "date" && !noFileFlag {
+		outputFile, err = archiveOutput(owner, repo, issueNumber, issue)
+		if err != nil {
+			return savedThread{}, err
+		}
+	}
+
+	if noFileFlag {
+		outputFile = ""
+	} else {
+		err = writeOutputFile(outputFile, issue, comments, fields)
+		if err != nil {
+			return savedThread{}, err
+		}
+
+		// Remember how far this run got
+		if f.state != nil {
+			f.state.record(stateKey(owner, repo, issueNumber), comments)
+		}
+
+		if outputFile != "-" {
+			statusf("Issue details and comments have been fetched and saved to %s.\n", outputFile)
+		}
+	}
+
+	// Show the first comments on the terminal for --head
+	if headFlag > 0 {
+		err = previewThread(os.Stdout, issue, comments, fields)
+		if err != nil {
+			return savedThread{}, err
 		}
+	}
+
+	if redactFlag {
+		statusf("Redacted %d secret(s) from the issue and comments.\n", redactions)
+	}
 
-		commentHeader := fmt.Sprintf("Comment %d by %s at %s", i+1, comment.User.Login, comment.DateTime.Format("2006-01-02 15:04:05"))
+	thread := savedThread{owner: owner, repo: repo, issueNumber: issueNumber, outputFile: outputFile}
+	thread.add(comments)
+	return thread, interrupted
+}
 
-		_, err = file.WriteString(commentHeader + ":\n")
+// writeOutputFile writes the thread to outputFile, or stdout for -, replacing
+// the file only once everything was written.
+func writeOutputFile(outputFile string, issue Issue, comments []Comment, fields []string) error {
+	// Write to an output that replaces the destination once complete, or to stdout
+	var dest output
+	var out io.Writer = os.Stdout
+	var err error
+	if outputFile != "-" {
+		dest, err = openOutput(outputFile)
 		if err != nil {
-			log.Fatalf("Failed to write comment header to file: %s", err)
+			return err
 		}
+		defer dest.Close()
+		out = dest
+	}
+
+	// Only the built-in text format is colorized, and only on terminals
+	colorEnabled = formatFlag == "text" && templateFlag == "" && !gzipFlag && outputFile == "-" && useColor(os.Stdout)
 
-		commentBody := fmt.Sprintf("%s\n", comment.Body)
-		_, err = file.WriteString(commentBody)
+	// Everything is written through out, which compresses on the fly when requested
+	var gzipWriter *gzip.Writer
+	if gzipFlag {
+		gzipWriter = gzip.NewWriter(out)
+		out = gzipWriter
+	}
+
+	err = renderThread(out, issue, comments, fields)
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	// Flush the gzip stream before the file is closed so the archive isn't truncated
+	if gzipWriter != nil {
+		err = gzipWriter.Close()
 		if err != nil {
-			log.Fatalf("Failed to write comment body to file: %s", err)
+			return fmt.Errorf("failed to finish compressed output: %w", err)
 		}
 	}
 
-	fmt.Println("Issue details and comments have been fetched and saved to comments.txt.")
+	// Only now does the new output take the place of the old
+	if dest != nil {
+		err = dest.commit()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderThread writes the issue and comments in the chosen format, using the
+// user's template when one is given.
+func renderThread(out io.Writer, issue Issue, comments []Comment, fields []string) error {
+	switch {
+	case formatFlag == "json" && typeFlag == "commit":
+		return writeJSON(out, nil, shaFlag, comments, fields)
+	case formatFlag == "json" && !includeIssueFlag:
+		return writeJSON(out, nil, "", comments, fields)
+	case formatFlag == "json":
+		return writeJSON(out, &issue, "", comments, fields)
+	case formatFlag == "ndjson" && typeFlag == "commit":
+		return writeNDJSON(out, nil, shaFlag, comments, fields)
+	case formatFlag == "ndjson" && !includeIssueFlag:
+		return writeNDJSON(out, nil, "", comments, fields)
+	case formatFlag == "ndjson":
+		return writeNDJSON(out, &issue, "", comments, fields)
+	case formatFlag == "xml" && typeFlag == "commit":
+		return writeXML(out, nil, shaFlag, comments)
+	case formatFlag == "xml" && !includeIssueFlag:
+		return writeXML(out, nil, "", comments)
+	case formatFlag == "xml":
+		return writeXML(out, &issue, "", comments)
+	case formatFlag == "markdown" && typeFlag == "commit":
+		return writeMarkdown(out, nil, shaFlag, comments, tocFlag)
+	case formatFlag == "markdown" && !includeIssueFlag:
+		return writeMarkdown(out, nil, "", comments, tocFlag)
+	case formatFlag == "markdown":
+		return writeMarkdown(out, &issue, "", comments, tocFlag)
+	case compactFlag:
+		return writeCompact(out, comments)
+	case bodyOnlyFlag:
+		_, err := io.WriteString(out, issue.Body+"\n")
+		return err
+	case templateFlag != "":
+		return renderTemplate(out, templateFlag, issue, comments)
+	case typeFlag == "commit":
+		return writeCommitText(out, shaFlag, comments)
+	case !includeIssueFlag:
+		return writeComments(out, comments)
+	default:
+		return writeText(out, issue, comments)
+	}
+}
+
+// previewThread prints the thread with only its first --head comments to out,
+// which is stdout outside of tests.
+func previewThread(out *os.File, issue Issue, comments []Comment, fields []string) error {
+	if len(comments) > headFlag {
+		comments = comments[:headFlag]
+	}
+
+	colorEnabled = formatFlag == "text" && templateFlag == "" && useColor(out)
+	err := renderThread(out, issue, comments, fields)
+	if err != nil {
+		return fmt.Errorf("failed to write preview: %w", err)
+	}
+	return nil
 }
 
-func readInputsFromFile(filePath string) (owner, repo, issueNumber string) {
+func readInputsFromFile(filePath string) (owner, repo, issueNumber string, targets []target, err error) {
 	// Read the contents of the file
 	fileData, err := os.ReadFile(filePath)
 	if err != nil {
-		panic(fmt.Errorf("failed to read inputs from file: %w", err))
+		return "", "", "", nil, fmt.Errorf("failed to read inputs from file: %w", err)
 	}
 
 	// Unmarshal the JSON data into a struct
 	var inputs struct {
-		Owner       string `json:"owner"`
-		Repo        string `json:"repo"`
-		IssueNumber string `json:"issueNumber"`
+		Owner       string   `json:"owner"`
+		Repo        string   `json:"repo"`
+		IssueNumber string   `json:"issueNumber"`
+		Targets     []target `json:"targets"`
 	}
 	err = json.Unmarshal(fileData, &inputs)
 	if err != nil {
-		panic(fmt.Errorf("failed to parse inputs from file: %w", err))
+		return "", "", "", nil, fmt.Errorf("failed to parse inputs from file: %w", err)
 	}
 
-	return inputs.Owner, inputs.Repo, inputs.IssueNumber
+	return inputs.Owner, inputs.Repo, inputs.IssueNumber, inputs.Targets, nil
 }
 
-func getAbsolutePath(filePath string) string {
+func getAbsolutePath(filePath string) (string, error) {
 	currentDir, err := os.Getwd()
 	if err != nil {
-		panic(fmt.Errorf("failed to get current directory: %w", err))
+		return "", fmt.Errorf("failed to get current directory: %w", err)
 	}
 
-	return filepath.Join(currentDir, filePath)
+	return filepath.Join(currentDir, filePath), nil
 }
 
-func updateInputsInFile(filePath, owner, repo, issueNumber string) {
-	// Create the new inputs struct
+// expandInput replaces ${VAR} and $VAR in a value of the inputs file with
+// environment variables, turning $$ into a single $.
+func expandInput(s string) string {
+	return os.Expand(s, func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		return os.Getenv(name)
+	})
+}
+
+func updateInputsInFile(filePath, owner, repo, issueNumber string, targets []target) error {
+	// Create the new inputs struct, keeping any targets
 	newInputs := struct {
-		Owner       string `json:"owner"`
-		Repo        string `json:"repo"`
-		IssueNumber string `json:"issueNumber"`
+		Owner       string   `json:"owner"`
+		Repo        string   `json:"repo"`
+		IssueNumber string   `json:"issueNumber"`
+		Targets     []target `json:"targets,omitempty"`
 	}{
 		Owner:       owner,
 		Repo:        repo,
 		IssueNumber: issueNumber,
+		Targets:     targets,
 	}
 
 	// Convert to JSON
 	newInputsJSON, err := json.MarshalIndent(newInputs, "", "  ")
 	if err != nil {
-		panic(fmt.Errorf("failed to marshal new inputs: %w", err))
+		return fmt.Errorf("failed to marshal new inputs: %w", err)
 	}
 
 	// Write to the file
 	err = os.WriteFile(filePath, newInputsJSON, 0644)
 	if err != nil {
-		panic(fmt.Errorf("failed to write updated inputs to file: %w", err))
+		return fmt.Errorf("failed to write updated inputs to file: %w", err)
 	}
+
+	return nil
 }
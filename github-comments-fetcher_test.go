@@ -0,0 +1,621 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setFlag changes a flag variable for the rest of the test.
+func setFlag[T any](t *testing.T, p *T, value T) {
+	t.Helper()
+	old := *p
+	*p = value
+	t.Cleanup(func() { *p = old })
+}
+
+// stubAPI answers requests with the canned body for their path.
+type stubAPI map[string]string
+
+func (s stubAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, ok := s[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	io.WriteString(w, body)
+}
+
+// serverClient returns a client sending every request to server, whatever
+// host it was meant for.
+func serverClient(server *httptest.Server) *http.Client {
+	transport := server.Client().Transport
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		req.URL.Scheme = "http"
+		req.URL.Host = server.Listener.Addr().String()
+		return transport.RoundTrip(req)
+	})}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// runStubbed runs the fetcher in a temporary directory for issue o/r#1, against
+// a server answering with responses.
+func runStubbed(t *testing.T, responses http.Handler) error {
+	t.Helper()
+	server := httptest.NewServer(responses)
+	t.Cleanup(server.Close)
+	setFlag(t, &baseURLFlag, server.URL)
+	setFlag(t, &apiBaseURL, apiBaseURL)
+
+	dir := t.TempDir()
+	old, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(old) })
+
+	err = os.WriteFile("github-comments-fetcher-inputs.txt", []byte(`{"owner":"o","repo":"r","issueNumber":"1"}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITHUB_ACCESS_TOKEN", "token")
+	return run()
+}
+
+func TestOutputFileName(t *testing.T) {
+	tests := []struct {
+		name        string
+		issueNumber string
+		format      string
+		gzip        bool
+		want        string
+	}{
+		{name: "single issue", format: "text", want: "comments.txt"},
+		{name: "json", format: "json", want: "comments.json"},
+		{name: "issue number", issueNumber: "12", format: "json", want: "comments-12.json"},
+		{name: "compressed", issueNumber: "12", format: "text", gzip: true, want: "comments-12.txt.gz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &formatFlag, tt.format)
+			setFlag(t, &gzipFlag, tt.gzip)
+
+			if got := outputFileName(tt.issueNumber); got != tt.want {
+				t.Errorf("outputFileName(%q) = %q, want %q", tt.issueNumber, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGzipOutput(t *testing.T) {
+	setFlag(t, &gzipFlag, true)
+	err := runStubbed(t, stubAPI{
+		"/repos/o/r/issues/1":          `{"title":"Crash on start","body":"It crashes."}`,
+		"/repos/o/r/issues/1/comments": `[{"body":"Same here."}]`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open("comments.txt.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("output isn't gzipped: %v", err)
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("compressed output is truncated: %v", err)
+	}
+	for _, want := range []string{"Crash on start", "Same here."} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("decompressed output is missing %q:\n%s", want, content)
+		}
+	}
+}
+
+func TestRunWithoutIssue(t *testing.T) {
+	tests := []struct {
+		format       string
+		includeIssue bool
+		outputFile   string
+		wantIssue    bool
+	}{
+		{format: "text", includeIssue: true, outputFile: "comments.txt", wantIssue: true},
+		{format: "text", includeIssue: false, outputFile: "comments.txt", wantIssue: false},
+		{format: "json", includeIssue: false, outputFile: "comments.json", wantIssue: false},
+		{format: "ndjson", includeIssue: false, outputFile: "comments.ndjson", wantIssue: false},
+		{format: "xml", includeIssue: false, outputFile: "comments.xml", wantIssue: false},
+		{format: "markdown", includeIssue: false, outputFile: "comments.md", wantIssue: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s include-issue=%t", tt.format, tt.includeIssue), func(t *testing.T) {
+			setFlag(t, &formatFlag, tt.format)
+			setFlag(t, &includeIssueFlag, tt.includeIssue)
+
+			err := runStubbed(t, stubAPI{
+				"/repos/o/r/issues/1":          `{"title":"Crash on start","body":"It crashes."}`,
+				"/repos/o/r/issues/1/comments": `[{"user":{"login":"bob"},"body":"Same here."}]`,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out, err := os.ReadFile(tt.outputFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Contains(string(out), "Crash on start"); got != tt.wantIssue {
+				t.Errorf("issue written = %v, want %v:\n%s", got, tt.wantIssue, out)
+			}
+			if !strings.Contains(string(out), "Same here.") {
+				t.Errorf("comment is missing:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestFetchThreadPullRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		issue  string
+		wantPR bool
+	}{
+		{name: "issue", issue: `{"number":1,"title":"Crash"}`, wantPR: false},
+		{name: "pull request", issue: `{"number":1,"title":"Fix crash","pull_request":{"url":"x"}}`, wantPR: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/repos/o/r/issues/1":
+					fmt.Fprint(w, tt.issue)
+				case "/repos/o/r/issues/1/comments":
+					fmt.Fprint(w, `[]`)
+				case "/repos/o/r/pulls/1":
+					fmt.Fprint(w, `{"merged":true,"additions":5,"deletions":1,"base":{"ref":"main"},"head":{"ref":"fix"}}`)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+			setFlag(t, &apiBaseURL, server.URL)
+
+			f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats()}
+			issue, _, err := fetchThread(f, "o", "r", "1")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if (issue.PullRequest != nil) != tt.wantPR {
+				t.Fatalf("pull request details = %+v, want them: %v", issue.PullRequest, tt.wantPR)
+			}
+			if tt.wantPR && (!issue.PullRequest.Merged || issue.PullRequest.Head.Ref != "fix") {
+				t.Errorf("pull request details = %+v", issue.PullRequest)
+			}
+		})
+	}
+}
+
+// chdirTemp runs the test in an empty directory, without a global config.
+func chdirTemp(t *testing.T) string {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	return dir
+}
+
+func TestRunSaveInputs(t *testing.T) {
+	tests := []struct {
+		name       string
+		saveInputs bool
+		wantFile   bool
+	}{
+		{name: "saved by default", saveInputs: true, wantFile: true},
+		{name: "left alone when disabled", saveInputs: false, wantFile: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := chdirTemp(t)
+			setFlag(t, &saveInputsFlag, tt.saveInputs)
+			setFlag(t, &ownerFlag, "octocat")
+			setFlag(t, &repoFlag, "hello-world")
+			setFlag(t, &issueNumberFlag, "1")
+			// An unknown type stops the run right after the inputs are handled
+			setFlag(t, &typeFlag, "unknown")
+
+			err := run()
+			if err == nil || !strings.Contains(err.Error(), "unknown --type") {
+				t.Fatalf("run() error = %v, want the unknown --type error", err)
+			}
+			_, err = os.Stat(filepath.Join(dir, "github-comments-fetcher-inputs.txt"))
+			if gotFile := err == nil; gotFile != tt.wantFile {
+				t.Errorf("inputs file written = %v, want %v", gotFile, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestBodyOnly(t *testing.T) {
+	tests := []struct {
+		name     string
+		bodyOnly bool
+		want     []string
+	}{
+		{name: "body only", bodyOnly: true, want: []string{"/repos/o/r/issues/1"}},
+		{name: "whole thread", bodyOnly: false, want: []string{"/repos/o/r/issues/1", "/repos/o/r/issues/1/comments"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requested []string
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requested = append(requested, r.URL.Path)
+				switch r.URL.Path {
+				case "/repos/o/r/issues/1":
+					fmt.Fprint(w, `{"number":1,"title":"Spec","body":"Do the thing."}`)
+				case "/repos/o/r/issues/1/comments":
+					fmt.Fprint(w, `[{"id":1,"body":"Done.","user":{"login":"bob"}}]`)
+				default:
+					http.NotFound(w, r)
+				}
+			})
+			setFlag(t, &typeFlag, "issue")
+			setFlag(t, &formatFlag, "text")
+			setFlag(t, &includeIssueFlag, true)
+			setFlag(t, &bodyOnlyFlag, tt.bodyOnly)
+
+			err := runStubbed(t, handler)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(requested, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("requested %v, want %v", requested, tt.want)
+			}
+
+			out, err := os.ReadFile("comments.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.bodyOnly && string(out) != "Do the thing.\n" {
+				t.Errorf("output = %q, want only the body", out)
+			}
+			if !tt.bodyOnly && !strings.Contains(string(out), "Done.") {
+				t.Errorf("comment is missing:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestRunNothingToFetch(t *testing.T) {
+	tests := []struct {
+		name       string
+		inputsFile bool
+		saveInputs bool
+		owner      string
+		wantUsage  bool
+	}{
+		{name: "no flags or inputs file", inputsFile: false, saveInputs: true, wantUsage: true},
+		{name: "inputs file ignored", inputsFile: true, saveInputs: false, wantUsage: true},
+		{name: "inputs file", inputsFile: true, saveInputs: true, wantUsage: false},
+		{name: "owner flag", inputsFile: false, saveInputs: true, owner: "octocat", wantUsage: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := chdirTemp(t)
+			if tt.inputsFile {
+				inputs := `{"owner":"octocat","repo":"hello-world","issueNumber":"1"}`
+				err := os.WriteFile(filepath.Join(dir, "github-comments-fetcher-inputs.txt"), []byte(inputs), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			setFlag(t, &saveInputsFlag, tt.saveInputs)
+			setFlag(t, &ownerFlag, tt.owner)
+			setFlag(t, &repoFlag, "")
+			setFlag(t, &issueNumberFlag, "")
+			// An unknown type stops the run before anything is fetched
+			setFlag(t, &typeFlag, "unknown")
+
+			err := run()
+			if err == nil {
+				t.Fatal("run() succeeded, want an error")
+			}
+			gotUsage := strings.Contains(err.Error(), "nothing to fetch; for example:") && strings.Contains(err.Error(), "-O octocat -R hello-world -I 1")
+			if gotUsage != tt.wantUsage {
+				t.Errorf("run() error = %v, want the usage example: %v", err, tt.wantUsage)
+			}
+			if tt.wantUsage && exitCode(err) != exitUsage {
+				t.Errorf("exit code = %d, want %d", exitCode(err), exitUsage)
+			}
+		})
+	}
+}
+
+func TestRunIDs(t *testing.T) {
+	issueJSON := `{"id":123456,"title":"Crash","body":"It crashes.","user":{"login":"alice"}}`
+	commentsJSON := `[{"id":998877,"body":"Same here.","user":{"login":"bob"}},{"body":"No id.","user":{"login":"carol"}}]`
+
+	var issue Issue
+	err := json.Unmarshal([]byte(issueJSON), &issue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var comments []Comment
+	err = json.Unmarshal([]byte(commentsJSON), &comments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.ID != 123456 || comments[0].ID != 998877 {
+		t.Fatalf("parsed IDs = %d, %d, want 123456, 998877", issue.ID, comments[0].ID)
+	}
+
+	tests := []struct {
+		format     string
+		outputFile string
+		want       []string
+	}{
+		{format: "text", outputFile: "comments.txt", want: []string{"[id=123456]", "Comment 1 [id=998877] by bob", "Comment 2 by carol"}},
+		{format: "json", outputFile: "comments.json", want: []string{`"id": 123456`, `"id": 998877`}},
+		{format: "ndjson", outputFile: "comments.ndjson", want: []string{`"id":123456`, `"id":998877`}},
+		{format: "xml", outputFile: "comments.xml", want: []string{`id="123456"`, `id="998877"`}},
+		{format: "markdown", outputFile: "comments.md", want: []string{"Comment 1 [id=998877] by @bob"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			setFlag(t, &formatFlag, tt.format)
+			setFlag(t, &includeIssueFlag, true)
+			setFlag(t, &typeFlag, "issue")
+
+			err := runStubbed(t, stubAPI{
+				"/repos/o/r/issues/1":          issueJSON,
+				"/repos/o/r/issues/1/comments": commentsJSON,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := os.ReadFile(tt.outputFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(out), want) {
+					t.Errorf("output is missing %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
+
+func TestPreviewThread(t *testing.T) {
+	issue := Issue{Title: "Crash", Body: "It crashes.", User: User{Login: "alice"}}
+	var comments []Comment
+	for i := 1; i <= 5; i++ {
+		comments = append(comments, Comment{ID: int64(i), User: User{Login: "bob"}, Body: fmt.Sprintf("Reply %d.", i)})
+	}
+
+	tests := []struct {
+		format string
+		head   int
+		want   int
+	}{
+		{format: "text", head: 2, want: 2},
+		{format: "text", head: 10, want: 5},
+		{format: "ndjson", head: 3, want: 3},
+		{format: "markdown", head: 1, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s head=%d", tt.format, tt.head), func(t *testing.T) {
+			setFlag(t, &formatFlag, tt.format)
+			setFlag(t, &headFlag, tt.head)
+			setFlag(t, &includeIssueFlag, true)
+			setFlag(t, &typeFlag, "issue")
+			setFlag(t, &colorEnabled, false)
+
+			out, err := os.Create(filepath.Join(t.TempDir(), "preview"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+			err = previewThread(out, issue, comments, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(out.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := strings.Count(string(data), "Reply "); got != tt.want {
+				t.Errorf("previewed %d comments, want %d:\n%s", got, tt.want, data)
+			}
+			if !strings.Contains(string(data), "It crashes.") {
+				t.Errorf("issue is missing from the preview:\n%s", data)
+			}
+		})
+	}
+}
+
+func TestRunInputMismatchWarnings(t *testing.T) {
+	tests := []struct {
+		name        string
+		typ         string
+		issueNumber string
+		sha         string
+		want        string
+	}{
+		{name: "issue number with a commit", typ: "commit", issueNumber: "1", sha: "abc123", want: "Ignoring the issue numbers given, as --type commit"},
+		{name: "sha with an issue", typ: "issue", issueNumber: "1", sha: "abc123", want: "Ignoring --sha, which only applies to --type commit"},
+		{name: "matching issue", typ: "issue", issueNumber: "1", want: ""},
+		{name: "matching commit", typ: "commit", sha: "abc123", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chdirTemp(t)
+			setFlag(t, &saveInputsFlag, false)
+			setFlag(t, &ownerFlag, "octocat")
+			setFlag(t, &repoFlag, "hello-world")
+			setFlag(t, &typeFlag, tt.typ)
+			setFlag(t, &issueNumberFlag, tt.issueNumber)
+			setFlag(t, &shaFlag, tt.sha)
+			// An unknown format stops the run right after the warnings
+			setFlag(t, &formatFlag, "unknown")
+
+			var logged strings.Builder
+			log.SetOutput(&logged)
+			defer log.SetOutput(os.Stderr)
+
+			err := run()
+			if err == nil || !strings.Contains(err.Error(), "unknown --format") {
+				t.Fatalf("run() error = %v, want the unknown --format error", err)
+			}
+			if tt.want == "" && strings.Contains(logged.String(), "Ignoring") {
+				t.Errorf("unexpected warning: %s", logged.String())
+			}
+			if !strings.Contains(logged.String(), tt.want) {
+				t.Errorf("log = %q, want %q", logged.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchThreadPullRequestWarning(t *testing.T) {
+	tests := []struct {
+		name   string
+		issue  string
+		review bool
+		want   bool
+	}{
+		{name: "pull request", issue: `{"number":1,"pull_request":{"url":"x"}}`, review: false, want: true},
+		{name: "pull request with review threads", issue: `{"number":1,"pull_request":{"url":"x"}}`, review: true, want: false},
+		{name: "issue", issue: `{"number":1}`, review: false, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/repos/o/r/issues/1":
+					fmt.Fprint(w, tt.issue)
+				case "/repos/o/r/pulls/1":
+					fmt.Fprint(w, `{"merged":false}`)
+				default:
+					fmt.Fprint(w, `[]`)
+				}
+			}))
+			defer server.Close()
+			setFlag(t, &apiBaseURL, server.URL)
+			setFlag(t, &typeFlag, "issue")
+			setFlag(t, &includeIssueFlag, true)
+			setFlag(t, &reviewFlag, tt.review)
+
+			var logged strings.Builder
+			log.SetOutput(&logged)
+			defer log.SetOutput(os.Stderr)
+
+			f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats()}
+			_, _, err := fetchThread(f, "o", "r", "1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := strings.Contains(logged.String(), "Target #1 is a pull request; use --review-threads")
+			if got != tt.want {
+				t.Errorf("warned = %v, want %v (log %q)", got, tt.want, logged.String())
+			}
+		})
+	}
+}
+
+func TestExpandInput(t *testing.T) {
+	t.Setenv("GH_OWNER", "octocat")
+	t.Setenv("GH_REPO", "hello-world")
+	t.Setenv("GH_EMPTY", "")
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "${GH_OWNER}", want: "octocat"},
+		{in: "$GH_OWNER", want: "octocat"},
+		{in: "${GH_OWNER}-${GH_REPO}", want: "octocat-hello-world"},
+		{in: "${GH_EMPTY}", want: ""},
+		{in: "${GH_UNSET_FOR_TEST}", want: ""},
+		{in: "price$$5", want: "price$5"},
+		{in: "$${GH_OWNER}", want: "${GH_OWNER}"},
+		{in: "plain", want: "plain"},
+	}
+
+	for _, tt := range tests {
+		if got := expandInput(tt.in); got != tt.want {
+			t.Errorf("expandInput(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRunKeepsInputReferences(t *testing.T) {
+	dir := chdirTemp(t)
+	t.Setenv("GH_OWNER", "octocat")
+	inputsPath := filepath.Join(dir, "github-comments-fetcher-inputs.txt")
+	inputs := `{"owner":"${GH_OWNER}","repo":"hello-world","issueNumber":"1"}`
+	err := os.WriteFile(inputsPath, []byte(inputs), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setFlag(t, &saveInputsFlag, true)
+	setFlag(t, &ownerFlag, "")
+	setFlag(t, &repoFlag, "")
+	setFlag(t, &issueNumberFlag, "")
+	// An unknown type stops the run right after the inputs are handled
+	setFlag(t, &typeFlag, "unknown")
+
+	err = run()
+	if err == nil || !strings.Contains(err.Error(), "unknown --type") {
+		t.Fatalf("run() error = %v, want the unknown --type error", err)
+	}
+
+	// The inputs file is rewritten with the reference, not its value
+	owner, _, _, _, err := readInputsFromFile(inputsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owner != "${GH_OWNER}" {
+		t.Errorf("saved owner = %q, want the ${GH_OWNER} reference", owner)
+	}
+}
@@ -1,3 +1,17 @@
 module github.com/sbdtu5498/github-comments-fetcher
 
 go 1.20
+
+require (
+	github.com/zalando/go-keyring v0.2.3
+	golang.org/x/net v0.33.0
+	golang.org/x/time v0.5.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/alessio/shellescape v1.4.1 // indirect
+	github.com/danieljoos/wincred v1.2.0 // indirect
+	github.com/godbus/dbus/v5 v5.1.0 // indirect
+	golang.org/x/sys v0.28.0 // indirect
+)
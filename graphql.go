@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// graphqlURL is the GraphQL endpoint belonging to the REST base URL, which
+// lives next to /api/v3 on GitHub Enterprise.
+func graphqlURL() string {
+	if strings.HasSuffix(apiBaseURL, "/api/v3") {
+		return strings.TrimSuffix(apiBaseURL, "/v3") + "/graphql"
+	}
+	return apiBaseURL + "/graphql"
+}
+
+// Query for a page of issue or PR comments, including whether they were minimized
+const commentsQuery = `query($owner: String!, $repo: String!, $number: Int!, $cursor: String) {
+  repository(owner: $owner, name: $repo) {
+    issueOrPullRequest(number: $number) {
+      ... on Issue { comments(first: 100, after: $cursor) { ...commentPage } }
+      ... on PullRequest { comments(first: 100, after: $cursor) { ...commentPage } }
+    }
+  }
+}
+
+fragment commentPage on IssueCommentConnection {
+  nodes {
+    databaseId
+    body
+    bodyText
+    bodyHTML
+    createdAt
+    updatedAt
+    isMinimized
+    minimizedReason
+    reactions { totalCount }
+    author { login __typename }
+  }
+  pageInfo { hasNextPage endCursor }
+}`
+
+// GraphQL comment node
+type graphqlComment struct {
+	DatabaseID      int64     `json:"databaseId"`
+	Body            string    `json:"body"`
+	BodyText        string    `json:"bodyText"`
+	BodyHTML        string    `json:"bodyHTML"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+	IsMinimized     bool      `json:"isMinimized"`
+	MinimizedReason string    `json:"minimizedReason"`
+	Reactions       struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"reactions"`
+	Author *struct {
+		Login    string `json:"login"`
+		TypeName string `json:"__typename"`
+	} `json:"author"`
+}
+
+// postGraphQL runs a GraphQL query and decodes its data into v.
+func (f *fetcher) postGraphQL(query string, variables map[string]interface{}, v interface{}) error {
+	payload, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(f.ctx, "POST", graphqlURL(), bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if f.accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+f.accessToken)
+	}
+
+	resp, err := f.sendRequest(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	f.stats.bytes.Add(int64(len(body)))
+
+	if resp.StatusCode != http.StatusOK {
+		return newResponseError(resp, body)
+	}
+
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return fmt.Errorf("failed to parse response body: %w", err)
+	}
+
+	if len(result.Errors) > 0 {
+		messages := make([]string, len(result.Errors))
+		for i, e := range result.Errors {
+			messages[i] = e.Message
+		}
+		return fmt.Errorf("query failed: %s", strings.Join(messages, "; "))
+	}
+
+	err = json.Unmarshal(result.Data, v)
+	if err != nil {
+		return fmt.Errorf("failed to parse response data: %w", err)
+	}
+
+	return nil
+}
+
+// fetchCommentsGraphQL fetches every comment of an issue or PR through the
+// GraphQL API, which unlike REST tells whether a comment was minimized.
+func (f *fetcher) fetchCommentsGraphQL(owner, repo string, number int) ([]Comment, error) {
+	var comments []Comment
+	var cursor *string
+
+	for {
+		var data struct {
+			Repository struct {
+				IssueOrPullRequest *struct {
+					Comments struct {
+						Nodes    []graphqlComment `json:"nodes"`
+						PageInfo struct {
+							HasNextPage bool   `json:"hasNextPage"`
+							EndCursor   string `json:"endCursor"`
+						} `json:"pageInfo"`
+					} `json:"comments"`
+				} `json:"issueOrPullRequest"`
+			} `json:"repository"`
+		}
+
+		variables := map[string]interface{}{"owner": owner, "repo": repo, "number": number, "cursor": cursor}
+		err := f.postGraphQL(commentsQuery, variables, &data)
+		if err != nil {
+			if f.ctx.Err() != nil {
+				return comments, errInterrupted
+			}
+			return nil, err
+		}
+		f.stats.pages.Add(1)
+
+		target := data.Repository.IssueOrPullRequest
+		if target == nil {
+			return nil, fmt.Errorf("issue or PR #%d not found in %s/%s", number, owner, repo)
+		}
+
+		for _, node := range target.Comments.Nodes {
+			comment := Comment{
+				ID:              node.DatabaseID,
+				Body:            selectBody(node.Body, node.BodyText, node.BodyHTML),
+				DateTime:        node.CreatedAt,
+				UpdatedAt:       node.UpdatedAt,
+				Minimized:       node.IsMinimized,
+				MinimizedReason: strings.ToLower(node.MinimizedReason),
+				Reactions:       Reactions{TotalCount: node.Reactions.TotalCount},
+			}
+			if node.Author != nil {
+				comment.User = User{Login: node.Author.Login, Type: node.Author.TypeName}
+			}
+			comments = append(comments, comment)
+		}
+
+		if !target.Comments.PageInfo.HasNextPage {
+			return comments, nil
+		}
+		endCursor := target.Comments.PageInfo.EndCursor
+		cursor = &endCursor
+	}
+}
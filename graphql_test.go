@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFetchCommentsGraphQL(t *testing.T) {
+	var cursors []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		cursors = append(cursors, request.Variables["cursor"])
+
+		if request.Variables["cursor"] == nil {
+			fmt.Fprint(w, `{"data":{"repository":{"issueOrPullRequest":{"comments":{
+				"nodes":[
+					{"body":"first","author":{"login":"alice","__typename":"User"}},
+					{"body":"spam","isMinimized":true,"minimizedReason":"SPAM","author":{"login":"ci","__typename":"Bot"}}
+				],
+				"pageInfo":{"hasNextPage":true,"endCursor":"c1"}}}}}}`)
+			return
+		}
+		fmt.Fprint(w, `{"data":{"repository":{"issueOrPullRequest":{"comments":{
+			"nodes":[{"body":"ghost","author":null}],
+			"pageInfo":{"hasNextPage":false}}}}}}`)
+	}))
+	defer server.Close()
+
+	f := &fetcher{ctx: context.Background(), client: serverClient(server), stats: newRunStats()}
+	comments, err := f.fetchCommentsGraphQL("o", "r", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(comments) != 3 {
+		t.Fatalf("got %d comments, want 3", len(comments))
+	}
+	if !reflect.DeepEqual(cursors, []interface{}{nil, "c1"}) {
+		t.Errorf("cursors = %v, want [<nil> c1]", cursors)
+	}
+	if !comments[1].Minimized || comments[1].MinimizedReason != "spam" || comments[1].User.Type != "Bot" {
+		t.Errorf("hidden comment = %+v, want it minimized as spam by a bot", comments[1])
+	}
+	if comments[2].User.Login != "" {
+		t.Errorf("comment of a deleted account has login %q", comments[2].User.Login)
+	}
+}
+
+func TestPostGraphQLErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{name: "query errors", body: `{"errors":[{"message":"one"},{"message":"two"}]}`, wantErr: "query failed: one; two"},
+		{name: "missing issue", body: `{"data":{"repository":{"issueOrPullRequest":null}}}`, wantErr: "issue or PR #1 not found in o/r"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			f := &fetcher{ctx: context.Background(), client: serverClient(server), stats: newRunStats()}
+			_, err := f.fetchCommentsGraphQL("o", "r", 1)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("fetchCommentsGraphQL() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
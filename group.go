@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// The comments of one author, for --group-by author
+type commentGroup struct {
+	author   User
+	comments []Comment
+}
+
+// groupByAuthor splits the comments up by author, ordering the authors by
+// their first comment and each author's comments by date.
+func groupByAuthor(comments []Comment) []commentGroup {
+	var groups []commentGroup
+	index := make(map[string]int)
+	for _, comment := range comments {
+		i, ok := index[comment.User.Login]
+		if !ok {
+			i = len(groups)
+			index[comment.User.Login] = i
+			groups = append(groups, commentGroup{author: comment.User})
+		}
+		groups[i].comments = append(groups[i].comments, comment)
+	}
+
+	for _, group := range groups {
+		sort.SliceStable(group.comments, func(a, b int) bool {
+			return group.comments[a].DateTime.Before(group.comments[b].DateTime)
+		})
+	}
+	return groups
+}
+
+// groupHeading names the author of a group and how many comments it holds.
+func groupHeading(group commentGroup) string {
+	noun := "comments"
+	if len(group.comments) == 1 {
+		noun = "comment"
+	}
+	return fmt.Sprintf("%s (%d %s)", markdownAuthor(group.author), len(group.comments), noun)
+}
+
+// writeGroupedComments writes the comments in the built-in text format in a
+// section per author.
+func writeGroupedComments(out io.Writer, comments []Comment) error {
+	for i, group := range groupByAuthor(comments) {
+		separator := ""
+		if i > 0 {
+			separator = "\n\n"
+		}
+		_, err := fmt.Fprintf(out, "%s== %s ==\n\n", separator, groupHeading(group))
+		if err != nil {
+			return fmt.Errorf("failed to write author heading: %w", err)
+		}
+
+		err = writeCommentsFrom(out, group.comments, 1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGroupByAuthor(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC) }
+	comments := []Comment{
+		{ID: 1, User: User{Login: "alice"}, DateTime: day(1)},
+		{ID: 2, User: User{Login: "bob"}, DateTime: day(2)},
+		{ID: 3, User: User{Login: "alice"}, DateTime: day(5)},
+		{ID: 4, User: User{Login: "alice"}, DateTime: day(3)},
+		{ID: 5, User: User{Login: "carol"}, DateTime: day(4)},
+	}
+
+	var authors []string
+	var ids [][]int64
+	var headings []string
+	for _, group := range groupByAuthor(comments) {
+		authors = append(authors, group.author.Login)
+		ids = append(ids, commentIDs(group.comments))
+		headings = append(headings, groupHeading(group))
+	}
+
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{name: "authors by first comment", got: authors, want: []string{"alice", "bob", "carol"}},
+		{name: "comments by date", got: ids, want: [][]int64{{1, 4, 3}, {2}, {5}}},
+		{name: "headings with counts", got: headings, want: []string{"@alice (3 comments)", "@bob (1 comment)", "@carol (1 comment)"}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestWriteMarkdownGroupedByAuthor(t *testing.T) {
+	setFlag(t, &groupByFlag, "author")
+	comments := []Comment{
+		{ID: 1, User: User{Login: "alice"}, Body: "First."},
+		{ID: 2, User: User{Login: "bob"}, Body: "Second."},
+		{ID: 3, User: User{Login: "alice"}, Body: "Third."},
+	}
+
+	var out strings.Builder
+	err := writeMarkdown(&out, nil, "", comments, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Headings and rules in order, with a rule only between an author's comments
+	var got []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if line == "---" || strings.HasPrefix(line, "## ") {
+			got = append(got, line)
+		}
+	}
+	want := []string{"## @alice (2 comments)", "---", "## @bob (1 comment)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q\n%s", got, want, out.String())
+	}
+}
+
+func TestWriteGroupedComments(t *testing.T) {
+	comments := []Comment{
+		{ID: 1, User: User{Login: "alice"}, Body: "First."},
+		{ID: 2, User: User{Login: "bob"}, Body: "Second."},
+		{ID: 3, User: User{Login: "alice"}, Body: "Third."},
+	}
+
+	var out strings.Builder
+	err := writeGroupedComments(&out, comments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each section holds only its author's comments
+	got := strings.Split("\n"+out.String(), "\n== ")[1:]
+	tests := []struct {
+		heading string
+		bodies  []string
+	}{
+		{heading: "@alice (2 comments) ==", bodies: []string{"First.", "Third."}},
+		{heading: "@bob (1 comment) ==", bodies: []string{"Second."}},
+	}
+	if len(got) != len(tests) {
+		t.Fatalf("got %d sections, want %d:\n%s", len(got), len(tests), out.String())
+	}
+	for i, tt := range tests {
+		if !strings.HasPrefix(got[i], tt.heading) {
+			t.Errorf("section %d = %q, want it to start with %q", i+1, got[i], tt.heading)
+		}
+		for _, body := range tt.bodies {
+			if !strings.Contains(got[i], body) {
+				t.Errorf("section %q is missing %q", tt.heading, body)
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHARRecorder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret")
+		fmt.Fprint(w, `{"title":"Crash on start"}`)
+	}))
+	defer server.Close()
+	setFlag(t, &apiBaseURL, server.URL)
+
+	client := server.Client()
+	recorder := newHARRecorder(client.Transport)
+	client.Transport = recorder
+	f := &fetcher{ctx: context.Background(), client: client, accessToken: "ghp_secret", stats: newRunStats()}
+	_, err := f.fetchIssue("o", "r", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "trace.har")
+	err = recorder.save(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "ghp_secret") {
+		t.Errorf("HAR file contains the access token:\n%s", data)
+	}
+	var document harFile
+	err = json.Unmarshal(data, &document)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if document.Log.Version != "1.2" || len(document.Log.Entries) != 1 {
+		t.Fatalf("HAR version %q with %d entries, want 1.2 with one entry", document.Log.Version, len(document.Log.Entries))
+	}
+	entry := document.Log.Entries[0]
+	if entry.Request.URL != server.URL+"/repos/o/r/issues/1" || entry.Response.Status != http.StatusOK {
+		t.Errorf("entry = %s %d, want the issue request", entry.Request.URL, entry.Response.Status)
+	}
+	if entry.Response.Content.Text != `{"title":"Crash on start"}` {
+		t.Errorf("response content = %q", entry.Response.Content.Text)
+	}
+
+	tests := []struct {
+		headers []harNameValue
+		name    string
+		want    string
+	}{
+		{headers: entry.Request.Headers, name: "Authorization", want: "[REDACTED]"},
+		{headers: entry.Response.Headers, name: "Set-Cookie", want: "[REDACTED]"},
+		{headers: entry.Response.Headers, name: "Content-Type", want: "application/json"},
+	}
+	for _, tt := range tests {
+		got := ""
+		for _, header := range tt.headers {
+			if header.Name == tt.name {
+				got = header.Value
+			}
+		}
+		if got != tt.want {
+			t.Errorf("%s header = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
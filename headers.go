@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// headerList is the value of the repeatable --header flag.
+type headerList struct {
+	header http.Header
+}
+
+func (h *headerList) String() string {
+	if h.header == nil {
+		return ""
+	}
+
+	var pairs []string
+	for key, values := range h.header {
+		for _, value := range values {
+			pairs = append(pairs, key+": "+value)
+		}
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func (h *headerList) Set(value string) error {
+	key, val, ok := strings.Cut(value, ":")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" || strings.ContainsAny(key, " \t") {
+		return fmt.Errorf("expected \"Key: Value\"")
+	}
+
+	if h.header == nil {
+		h.header = make(http.Header)
+	}
+	h.header.Add(key, strings.TrimSpace(val))
+	return nil
+}
+
+// apply sets the headers on req, replacing those set by the fetcher. The
+// Authorization header from the token is only replaced when --header names
+// it explicitly.
+func (h *headerList) apply(req *http.Request) {
+	for key, values := range h.header {
+		req.Header[key] = values
+	}
+}
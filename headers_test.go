@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestHeaderListSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    http.Header
+		wantErr bool
+	}{
+		{name: "one header", values: []string{"X-Team: backend"}, want: http.Header{"X-Team": {"backend"}}},
+		{name: "key is canonicalized", values: []string{"x-request-source:  cron "}, want: http.Header{"X-Request-Source": {"cron"}}},
+		{name: "repeated", values: []string{"X-Tag: a", "X-Tag: b"}, want: http.Header{"X-Tag": {"a", "b"}}},
+		{name: "empty value", values: []string{"X-Empty:"}, want: http.Header{"X-Empty": {""}}},
+		{name: "value with colons", values: []string{"X-Time: 12:30"}, want: http.Header{"X-Time": {"12:30"}}},
+		{name: "no colon", values: []string{"X-Team backend"}, wantErr: true},
+		{name: "no key", values: []string{": backend"}, wantErr: true},
+		{name: "space in key", values: []string{"X Team: backend"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers headerList
+			var err error
+			for _, value := range tt.values {
+				err = headers.Set(value)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set() error = %v, want error: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(headers.header, tt.want) {
+				t.Errorf("headers = %v, want %v", headers.header, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeaderListApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []string
+		wantAuth string
+		wantUA   string
+	}{
+		{name: "no headers", wantAuth: "Bearer token", wantUA: "github-comments-fetcher/dev"},
+		{name: "extra header leaves the rest", values: []string{"X-Team: backend"}, wantAuth: "Bearer token", wantUA: "github-comments-fetcher/dev"},
+		{name: "replaces User-Agent", values: []string{"User-Agent: my-bot"}, wantAuth: "Bearer token", wantUA: "my-bot"},
+		{name: "replaces Authorization", values: []string{"Authorization: token other"}, wantAuth: "token other", wantUA: "github-comments-fetcher/dev"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers headerList
+			for _, value := range tt.values {
+				headers.Set(value)
+			}
+
+			req, _ := http.NewRequest("GET", "https://api.github.com", nil)
+			req.Header.Set("Authorization", "Bearer token")
+			req.Header.Set("User-Agent", "github-comments-fetcher/dev")
+			headers.apply(req)
+
+			if got := req.Header.Get("Authorization"); got != tt.wantAuth {
+				t.Errorf("Authorization = %q, want %q", got, tt.wantAuth)
+			}
+			if got := req.Header.Get("User-Agent"); got != tt.wantUA {
+				t.Errorf("User-Agent = %q, want %q", got, tt.wantUA)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Elements that start on a line of their own
+var blockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "hr": true, "pre": true, "blockquote": true,
+	"ul": true, "ol": true, "li": true, "table": true, "tr": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"details": true, "summary": true,
+}
+
+// Runs of blank lines left between blocks
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// htmlToText turns a body rendered as HTML into readable plain text: tags are
+// dropped, entities decoded and links kept as "text (url)".
+func htmlToText(s string) string {
+	var b strings.Builder
+	var href, linkText string
+	inLink, inPre := false, false
+	skip := 0 // depth inside script and style elements
+
+	// Text of a link is collected first so the URL can follow it
+	write := func(text string) {
+		if inLink {
+			linkText += text
+			return
+		}
+		b.WriteString(text)
+	}
+
+	tokenizer := html.NewTokenizer(strings.NewReader(s))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			text := blankLines.ReplaceAllString(b.String(), "\n\n")
+			lines := strings.Split(text, "\n")
+			for i, line := range lines {
+				lines[i] = strings.TrimRight(line, " \t")
+			}
+			return strings.TrimSpace(strings.Join(lines, "\n"))
+		case html.TextToken:
+			if skip > 0 {
+				continue
+			}
+			text := string(tokenizer.Text())
+			if !inPre {
+				// Keep a single space between words split across tags
+				raw := string(tokenizer.Raw())
+				written := b.String()
+				if inLink {
+					written = linkText
+				}
+				spaced := written == "" || strings.HasSuffix(written, " ") || strings.HasSuffix(written, "\n")
+
+				text = strings.Join(strings.Fields(text), " ")
+				if strings.TrimLeft(raw, " \t\n") != raw && !spaced {
+					text = " " + text
+				}
+				if strings.TrimRight(raw, " \t\n") != raw && strings.TrimSpace(text) != "" {
+					text += " "
+				}
+				if text == "" {
+					continue
+				}
+			}
+			write(text)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "script", "style":
+				if token.Type == html.StartTagToken {
+					skip++
+				}
+				continue
+			case "a":
+				href = attribute(token, "href")
+				linkText = ""
+				inLink = true
+				continue
+			case "img":
+				write(attribute(token, "alt"))
+				continue
+			case "pre":
+				inPre = true
+			}
+			if blockElements[token.Data] {
+				b.WriteString("\n")
+				if token.Data == "p" || token.Data == "pre" || strings.HasPrefix(token.Data, "h") && len(token.Data) == 2 {
+					b.WriteString("\n")
+				}
+				if token.Data == "li" {
+					b.WriteString("- ")
+				}
+			}
+		case html.EndTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "script", "style":
+				if skip > 0 {
+					skip--
+				}
+			case "a":
+				inLink = false
+				text := strings.TrimSpace(linkText)
+				b.WriteString(text)
+				// Anchors GitHub adds to headings and bare URLs don't need repeating
+				if href != "" && href != text && !strings.HasPrefix(href, "#") {
+					if text == "" {
+						b.WriteString(href)
+					} else {
+						b.WriteString(" (" + href + ")")
+					}
+				}
+			case "pre":
+				inPre = false
+				b.WriteString("\n\n")
+			default:
+				if blockElements[token.Data] && token.Data != "li" {
+					b.WriteString("\n")
+				}
+			}
+		}
+	}
+}
+
+// attribute returns the value of an attribute of a tag, or "".
+func attribute(token html.Token, name string) string {
+	for _, attr := range token.Attr {
+		if attr.Key == name {
+			return attr.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,70 @@
+package main
+
+import "testing"
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "paragraphs and entities",
+			in:   "<p>Fish &amp; chips &lt;3</p>\n<p>Second &quot;paragraph&quot;</p>",
+			want: "Fish & chips <3\n\nSecond \"paragraph\"",
+		},
+		{
+			name: "link kept with its URL",
+			in:   `<p>See <a href="https://example.com/docs">the docs</a> for more.</p>`,
+			want: "See the docs (https://example.com/docs) for more.",
+		},
+		{
+			name: "bare URL not repeated",
+			in:   `<p><a href="https://example.com">https://example.com</a></p>`,
+			want: "https://example.com",
+		},
+		{
+			name: "heading anchor dropped",
+			in:   `<h2><a href="#usage">Usage</a></h2><p>Run it.</p>`,
+			want: "Usage\n\nRun it.",
+		},
+		{
+			name: "list items",
+			in:   "<ul>\n<li>one</li>\n<li>two</li>\n</ul>",
+			want: "- one\n- two",
+		},
+		{
+			name: "preformatted code kept",
+			in:   "<pre><code>if x {\n    y()\n}</code></pre>",
+			want: "if x {\n    y()\n}",
+		},
+		{
+			name: "inline formatting keeps spaces",
+			in:   "<p>This is <strong>really</strong> <em>broken</em>.</p>",
+			want: "This is really broken.",
+		},
+		{
+			name: "no doubled spaces",
+			in:   "<p><b>a </b> c <i> d</i></p>",
+			want: "a c d",
+		},
+		{
+			name: "image alt text",
+			in:   `<p><img src="x.png" alt="screenshot"></p>`,
+			want: "screenshot",
+		},
+		{
+			name: "script and style dropped",
+			in:   "<style>p { color: red }</style><p>Visible</p><script>alert(1)</script>",
+			want: "Visible",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := htmlToText(tt.in); got != tt.want {
+				t.Errorf("htmlToText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
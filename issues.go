@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// parseIssueNumbers splits a comma separated list of issue numbers.
+func parseIssueNumbers(value string) ([]string, error) {
+	var numbers []string
+	for _, number := range strings.Split(value, ",") {
+		number = strings.TrimPrefix(strings.TrimSpace(number), "#")
+		if number == "" {
+			continue
+		}
+
+		err := checkIssueNumber(number)
+		if err != nil {
+			return nil, err
+		}
+		numbers = append(numbers, number)
+	}
+	return numbers, nil
+}
+
+// readIssueNumbers reads one issue number per line. Blank lines are skipped
+// and anything after a # is treated as a comment.
+func readIssueNumbers(r io.Reader) ([]string, error) {
+	var numbers []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+
+		number := strings.TrimSpace(line)
+		if number == "" {
+			continue
+		}
+
+		err := checkIssueNumber(number)
+		if err != nil {
+			return nil, err
+		}
+		numbers = append(numbers, number)
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read issue numbers: %w", err)
+	}
+
+	return numbers, nil
+}
+
+// readIssueNumbersFile reads issue numbers from a file, see readIssueNumbers.
+func readIssueNumbersFile(filePath string) ([]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open issues file: %w", err)
+	}
+	defer file.Close()
+
+	return readIssueNumbers(file)
+}
+
+// checkIssueNumber makes sure the value is a positive number.
+func checkIssueNumber(number string) error {
+	n, err := strconv.Atoi(number)
+	if err != nil || n <= 0 {
+		return fmt.Errorf("invalid issue number %q", number)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseIssueNumbers(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{value: "1,2,3", want: []string{"1", "2", "3"}},
+		{value: " #12 , 34,", want: []string{"12", "34"}},
+		{value: "", want: nil},
+		{value: "1,abc", wantErr: true},
+		{value: "0", wantErr: true},
+		{value: "-4", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseIssueNumbers(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseIssueNumbers(%q) error = %v, want error: %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseIssueNumbers(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestReadIssueNumbers(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "one per line", input: "1\n2\n3\n", want: []string{"1", "2", "3"}},
+		{name: "blank lines and comments", input: "# backlog\n\n 12  # crash\n34\n", want: []string{"12", "34"}},
+		{name: "CRLF", input: "5\r\n6\r\n", want: []string{"5", "6"}},
+		{name: "empty", input: "", want: nil},
+		{name: "invalid", input: "7\nseven\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readIssueNumbers(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readIssueNumbers() error = %v, want error: %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readIssueNumbers() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/zalando/go-keyring"
+)
+
+// Service name tokens are stored under in the OS keyring
+const keyringService = "github-comments-fetcher"
+
+// keyringToken returns the token saved for baseURL, or "" when there is none
+// or no keyring is available.
+func keyringToken(baseURL string) string {
+	token, err := keyring.Get(keyringService, baseURL)
+	if err != nil {
+		if !errors.Is(err, keyring.ErrNotFound) {
+			log.Printf("Keyring unavailable, not looking for a saved token: %s", err)
+		}
+		return ""
+	}
+	return token
+}
+
+// resolveToken finds the access token, looking at --token, then the
+// GITHUB_ACCESS_TOKEN environment variable, the --token-file and finally the
+// OS keyring.
+func resolveToken() string {
+	if tokenFlag != "" {
+		return tokenFlag
+	}
+	if token := os.Getenv("GITHUB_ACCESS_TOKEN"); token != "" {
+		return token
+	}
+	if tokenFileFlag != "" {
+		data, err := os.ReadFile(tokenFileFlag)
+		if err != nil {
+			log.Printf("Not using the token file: %s", err)
+		} else if token := strings.TrimSpace(string(data)); token != "" {
+			return token
+		}
+	}
+	return keyringToken(apiBaseURL)
+}
+
+// login saves a token in the OS keyring for the current base URL, taking it
+// from --token, the OAuth device flow with --device, or asking for it on stdin.
+func login() error {
+	token := tokenFlag
+	if deviceFlag {
+		if clientIDFlag == "" {
+			return usageErrorf("the --device flag requires --client-id")
+		}
+
+		var err error
+		token, err = deviceLogin(clientIDFlag)
+		if err != nil {
+			return err
+		}
+	}
+	if token == "" {
+		fmt.Fprint(os.Stderr, "Paste your GitHub access token: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return usageErrorf("failed to read token: %w", err)
+		}
+		token = strings.TrimSpace(line)
+	}
+	if token == "" {
+		return usageErrorf("no token given")
+	}
+
+	err := keyring.Set(keyringService, apiBaseURL, token)
+	if err != nil {
+		return fmt.Errorf("failed to save token in the keyring: %w", err)
+	}
+
+	fmt.Printf("Token saved for %s.\n", apiBaseURL)
+	return nil
+}
+
+// logout removes the token saved for the current base URL.
+func logout() error {
+	err := keyring.Delete(keyringService, apiBaseURL)
+	if errors.Is(err, keyring.ErrNotFound) {
+		fmt.Printf("No token was saved for %s.\n", apiBaseURL)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove token from the keyring: %w", err)
+	}
+
+	fmt.Printf("Token removed for %s.\n", apiBaseURL)
+	return nil
+}
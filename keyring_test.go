@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+func TestResolveToken(t *testing.T) {
+	keyring.MockInit()
+	setFlag(t, &apiBaseURL, "https://ghe.example.com/api/v3")
+	err := keyring.Set(keyringService, apiBaseURL, "from-keyring")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		flag string
+		env  string
+		want string
+	}{
+		{name: "flag first", flag: "from-flag", env: "from-env", want: "from-flag"},
+		{name: "environment next", env: "from-env", want: "from-env"},
+		{name: "keyring last", want: "from-keyring"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &tokenFlag, tt.flag)
+			t.Setenv("GITHUB_ACCESS_TOKEN", tt.env)
+
+			if got := resolveToken(); got != tt.want {
+				t.Errorf("resolveToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginLogout(t *testing.T) {
+	keyring.MockInit()
+	setFlag(t, &apiBaseURL, "https://ghe.example.com/api/v3")
+	setFlag(t, &tokenFlag, "secret")
+
+	err := login()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := keyringToken(apiBaseURL); got != "secret" {
+		t.Errorf("token after login = %q, want %q", got, "secret")
+	}
+
+	// Tokens are kept per server
+	if got := keyringToken(defaultBaseURL); got != "" {
+		t.Errorf("token for %s = %q, want none", defaultBaseURL, got)
+	}
+
+	for i := 0; i < 2; i++ {
+		err = logout()
+		if err != nil {
+			t.Fatalf("logout #%d: %v", i+1, err)
+		}
+	}
+	if got := keyringToken(apiBaseURL); got != "" {
+		t.Errorf("token after logout = %q, want none", got)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// A label being added to or removed from an issue
+type LabelEvent struct {
+	Label string    `json:"label"`
+	Added bool      `json:"added"`
+	Actor string    `json:"actor"`
+	At    time.Time `json:"created_at"`
+}
+
+// Issue event as listed by the events endpoint
+type issueEvent struct {
+	Event string `json:"event"`
+	Actor User   `json:"actor"`
+	Label *struct {
+		Name string `json:"name"`
+	} `json:"label"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// fetchIssueEvents fetches the events of an issue, oldest first.
+func (f *fetcher) fetchIssueEvents(owner, repo, issueNumber string) ([]issueEvent, error) {
+	events, err := fetchPaged[issueEvent](f, issueURL(owner, repo, issueNumber)+"/events")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch issue events: %w", permissionError(err, owner, repo))
+	}
+	return events, nil
+}
+
+// labelEvents keeps the labeled and unlabeled events.
+func labelEvents(events []issueEvent) []LabelEvent {
+	var labelEvents []LabelEvent
+	for _, event := range events {
+		if (event.Event != "labeled" && event.Event != "unlabeled") || event.Label == nil {
+			continue
+		}
+		labelEvents = append(labelEvents, LabelEvent{
+			Label: event.Label.Name,
+			Added: event.Event == "labeled",
+			Actor: event.Actor.Login,
+			At:    event.CreatedAt,
+		})
+	}
+	return labelEvents
+}
+
+// change is the label prefixed with + when it was added or - when removed.
+func (e LabelEvent) change() string {
+	if e.Added {
+		return "+" + e.Label
+	}
+	return "-" + e.Label
+}
+
+// writeLabelHistory writes the label changes of an issue, one per line,
+// after a summary such as "+bug, +p1, -triage".
+func writeLabelHistory(out io.Writer, events []LabelEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	changes := make([]string, len(events))
+	lines := make([]string, len(events))
+	for i, event := range events {
+		changes[i] = event.change()
+		lines[i] = fmt.Sprintf("  %s %s by %s\n", colorize(formatTime(event.At), colorYellow), event.change(),
+			colorize(displayLogin(event.Actor), colorCyan))
+	}
+
+	_, err := fmt.Fprintf(out, "Label History: %s\n%s\n", strings.Join(changes, ", "), strings.Join(lines, ""))
+	if err != nil {
+		return fmt.Errorf("failed to write label history: %w", err)
+	}
+	return nil
+}
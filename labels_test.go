@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetchLabelEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/o/r/issues/1/events" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `[
+			{"event":"labeled","actor":{"login":"alice"},"label":{"name":"bug"},"created_at":"2024-03-01T09:00:00Z"},
+			{"event":"assigned","actor":{"login":"alice"},"created_at":"2024-03-01T09:01:00Z"},
+			{"event":"labeled","actor":{"login":"bob"},"label":{"name":"p1"},"created_at":"2024-03-02T09:00:00Z"},
+			{"event":"unlabeled","actor":{"login":"bob"},"label":{"name":"triage"},"created_at":"2024-03-02T09:05:00Z"},
+			{"event":"labeled","actor":{"login":"bob"},"created_at":"2024-03-02T09:06:00Z"}
+		]`)
+	}))
+	defer server.Close()
+	setFlag(t, &apiBaseURL, server.URL)
+	setFlag(t, &schemaFlag, "github")
+
+	f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats()}
+	events, err := f.fetchIssueEvents("o", "r", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []LabelEvent{
+		{Label: "bug", Added: true, Actor: "alice", At: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)},
+		{Label: "p1", Added: true, Actor: "bob", At: time.Date(2024, 3, 2, 9, 0, 0, 0, time.UTC)},
+		{Label: "triage", Added: false, Actor: "bob", At: time.Date(2024, 3, 2, 9, 5, 0, 0, time.UTC)},
+	}
+	if got := labelEvents(events); !reflect.DeepEqual(got, want) {
+		t.Errorf("labelEvents() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWriteLabelHistory(t *testing.T) {
+	at := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		events []LabelEvent
+		want   string
+	}{
+		{
+			name: "added and removed",
+			events: []LabelEvent{
+				{Label: "bug", Added: true, Actor: "alice", At: at},
+				{Label: "triage", Added: false, Actor: "", At: at},
+			},
+			want: "Label History: +bug, -triage\n" +
+				"  " + formatTime(at) + " +bug by alice\n" +
+				"  " + formatTime(at) + " -triage by (ghost)\n\n",
+		},
+		{name: "no label events", events: nil, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			err := writeLabelHistory(&out, tt.events)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("writeLabelHistory() = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Query for the pull requests that close or mention an issue
+const linkedPRsQuery = `query($owner: String!, $repo: String!, $number: Int!) {
+  repository(owner: $owner, name: $repo) {
+    issue(number: $number) {
+      closedByPullRequestsReferences(first: 25, includeClosedPrs: true) {
+        nodes { ...linkedPR }
+      }
+      timelineItems(first: 100, itemTypes: [CROSS_REFERENCED_EVENT]) {
+        nodes {
+          ... on CrossReferencedEvent {
+            willCloseTarget
+            source { ... on PullRequest { ...linkedPR } }
+          }
+        }
+      }
+    }
+  }
+}
+
+fragment linkedPR on PullRequest {
+  number
+  title
+  merged
+  repository { nameWithOwner }
+}`
+
+// A pull request linked to an issue
+type LinkedPR struct {
+	Repository string `json:"repository"`
+	Number     int    `json:"number"`
+	Title      string `json:"title"`
+	Merged     bool   `json:"merged"`
+	Closes     bool   `json:"closes"` // merging it closes the issue
+}
+
+// PR node of the linked pull requests query
+type graphqlLinkedPR struct {
+	Number     int    `json:"number"`
+	Title      string `json:"title"`
+	Merged     bool   `json:"merged"`
+	Repository struct {
+		NameWithOwner string `json:"nameWithOwner"`
+	} `json:"repository"`
+}
+
+// fetchLinkedPRs finds the pull requests that close or reference an issue,
+// closing ones first.
+func (f *fetcher) fetchLinkedPRs(owner, repo, issueNumber string) ([]LinkedPR, error) {
+	number, err := strconv.Atoi(issueNumber)
+	if err != nil {
+		return nil, usageErrorf("invalid issue number %q: %w", issueNumber, err)
+	}
+
+	var data struct {
+		Repository struct {
+			Issue *struct {
+				ClosedBy struct {
+					Nodes []graphqlLinkedPR `json:"nodes"`
+				} `json:"closedByPullRequestsReferences"`
+				TimelineItems struct {
+					Nodes []struct {
+						WillCloseTarget bool             `json:"willCloseTarget"`
+						Source          *graphqlLinkedPR `json:"source"`
+					} `json:"nodes"`
+				} `json:"timelineItems"`
+			} `json:"issue"`
+		} `json:"repository"`
+	}
+	variables := map[string]interface{}{"owner": owner, "repo": repo, "number": number}
+	err = f.postGraphQL(linkedPRsQuery, variables, &data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch linked pull requests: %w", err)
+	}
+	if data.Repository.Issue == nil {
+		return nil, fmt.Errorf("issue #%d not found in %s/%s", number, owner, repo)
+	}
+
+	// The same PR can both close and mention the issue
+	var linked []LinkedPR
+	seen := make(map[string]int)
+	add := func(node graphqlLinkedPR, closes bool) {
+		key := fmt.Sprintf("%s#%d", node.Repository.NameWithOwner, node.Number)
+		if i, ok := seen[key]; ok {
+			linked[i].Closes = linked[i].Closes || closes
+			return
+		}
+		seen[key] = len(linked)
+		linked = append(linked, LinkedPR{
+			Repository: node.Repository.NameWithOwner,
+			Number:     node.Number,
+			Title:      node.Title,
+			Merged:     node.Merged,
+			Closes:     closes,
+		})
+	}
+
+	for _, node := range data.Repository.Issue.ClosedBy.Nodes {
+		add(node, true)
+	}
+	for _, item := range data.Repository.Issue.TimelineItems.Nodes {
+		// Mentions from issues have an empty source here
+		if item.Source != nil && item.Source.Number != 0 {
+			add(*item.Source, item.WillCloseTarget)
+		}
+	}
+	return linked, nil
+}
+
+// linkedPRLine describes a linked pull request and whether it was merged.
+func linkedPRLine(pr LinkedPR) string {
+	state := "not merged"
+	if pr.Merged {
+		state = "merged"
+	}
+	if pr.Closes {
+		state += ", closes the issue"
+	}
+	return fmt.Sprintf("%s#%d %s (%s)", pr.Repository, pr.Number, pr.Title, state)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestFetchLinkedPRs(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     []LinkedPR
+	}{
+		{
+			name: "closing PR also cross-referenced",
+			response: `{"data":{"repository":{"issue":{
+				"closedByPullRequestsReferences":{"nodes":[
+					{"number":5,"title":"Fix crash","merged":true,"repository":{"nameWithOwner":"o/r"}}
+				]},
+				"timelineItems":{"nodes":[
+					{"willCloseTarget":true,"source":{"number":5,"title":"Fix crash","merged":true,"repository":{"nameWithOwner":"o/r"}}},
+					{"willCloseTarget":false,"source":{"number":9,"title":"Try a fix","merged":false,"repository":{"nameWithOwner":"fork/r"}}},
+					{"willCloseTarget":false,"source":{}}
+				]}}}}}`,
+			want: []LinkedPR{
+				{Repository: "o/r", Number: 5, Title: "Fix crash", Merged: true, Closes: true},
+				{Repository: "fork/r", Number: 9, Title: "Try a fix", Merged: false, Closes: false},
+			},
+		},
+		{
+			name: "closing cross-reference only",
+			response: `{"data":{"repository":{"issue":{
+				"closedByPullRequestsReferences":{"nodes":[]},
+				"timelineItems":{"nodes":[
+					{"willCloseTarget":true,"source":{"number":7,"title":"Closes #1","merged":false,"repository":{"nameWithOwner":"o/r"}}}
+				]}}}}}`,
+			want: []LinkedPR{{Repository: "o/r", Number: 7, Title: "Closes #1", Merged: false, Closes: true}},
+		},
+		{
+			name:     "no linked PRs",
+			response: `{"data":{"repository":{"issue":{"closedByPullRequestsReferences":{"nodes":[]},"timelineItems":{"nodes":[]}}}}}`,
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.response)
+			}))
+			defer server.Close()
+			setFlag(t, &apiBaseURL, server.URL)
+
+			f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats()}
+			got, err := f.fetchLinkedPRs("o", "r", "1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fetchLinkedPRs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkedPRLine(t *testing.T) {
+	tests := []struct {
+		pr   LinkedPR
+		want string
+	}{
+		{pr: LinkedPR{Repository: "o/r", Number: 5, Title: "Fix crash", Merged: true, Closes: true}, want: "o/r#5 Fix crash (merged, closes the issue)"},
+		{pr: LinkedPR{Repository: "o/r", Number: 9, Title: "Try a fix"}, want: "o/r#9 Try a fix (not merged)"},
+	}
+
+	for _, tt := range tests {
+		if got := linkedPRLine(tt.pr); got != tt.want {
+			t.Errorf("linkedPRLine(%+v) = %q, want %q", tt.pr, got, tt.want)
+		}
+	}
+}
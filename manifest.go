@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Run description written with --manifest
+type manifest struct {
+	Owner       string           `json:"owner"`
+	Repo        string           `json:"repo"`
+	FetchedAt   time.Time        `json:"fetched_at"`
+	ToolVersion string           `json:"tool_version"`
+	Outputs     []manifestOutput `json:"outputs"`
+}
+
+// One output file listed in the manifest
+type manifestOutput struct {
+	Repository   string `json:"repository,omitempty"`
+	IssueNumber  string `json:"issue_number,omitempty"`
+	Commit       string `json:"commit,omitempty"`
+	File         string `json:"file"`
+	CommentCount int    `json:"comment_count"`
+	SHA256       string `json:"sha256"`
+}
+
+// writeManifest records the provenance and checksums of the saved threads.
+func writeManifest(filePath, owner, repo string, saved []savedThread) error {
+	m := manifest{
+		Owner:       owner,
+		Repo:        repo,
+		FetchedAt:   time.Now().UTC(),
+		ToolVersion: version,
+	}
+
+	for _, thread := range saved {
+		checksum, err := fileSHA256(thread.outputFile)
+		if err != nil {
+			return err
+		}
+
+		output := manifestOutput{
+			IssueNumber:  thread.issueNumber,
+			File:         thread.outputFile,
+			CommentCount: thread.count,
+			SHA256:       checksum,
+		}
+		if typeFlag == "commit" {
+			output.Commit = shaFlag
+		}
+		// Threads from the inputs file targets can come from other repositories
+		if thread.owner != owner || thread.repo != repo {
+			output.Repository = thread.owner + "/" + thread.repo
+		}
+		m.Outputs = append(m.Outputs, output)
+	}
+
+	manifestJSON, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	err = writeFileAtomic(filePath, manifestJSON)
+	if err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return nil
+}
+
+// fileSHA256 returns the hex encoded SHA-256 checksum of a file.
+func fileSHA256(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s for checksum: %w", filePath, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", fmt.Errorf("failed to checksum %s: %w", filePath, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
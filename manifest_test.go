@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteManifest(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "comments-1.txt")
+	second := filepath.Join(dir, "comments-2.txt")
+	os.WriteFile(first, []byte("hello\n"), 0644)
+	os.WriteFile(second, []byte(""), 0644)
+
+	saved := []savedThread{
+		{owner: "o", repo: "r", issueNumber: "1", outputFile: first, count: 3},
+		{owner: "other", repo: "repo", issueNumber: "2", outputFile: second},
+	}
+	manifestFile := filepath.Join(dir, "manifest.json")
+	err := writeManifest(manifestFile, "o", "r", saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(manifestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m manifest
+	err = json.Unmarshal(data, &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []manifestOutput{
+		{IssueNumber: "1", File: first, CommentCount: 3, SHA256: "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"},
+		{Repository: "other/repo", IssueNumber: "2", File: second, SHA256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+	}
+	if m.Owner != "o" || m.Repo != "r" || len(m.Outputs) != len(want) {
+		t.Fatalf("manifest = %+v", m)
+	}
+	for i := range want {
+		if m.Outputs[i] != want[i] {
+			t.Errorf("output %d = %+v, want %+v", i, m.Outputs[i], want[i])
+		}
+	}
+
+	// Nothing is left behind from the atomic write
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 3 {
+		t.Errorf("directory holds %d files, want 3", len(entries))
+	}
+}
+
+func TestWriteManifestMissingOutput(t *testing.T) {
+	dir := t.TempDir()
+	saved := []savedThread{{owner: "o", repo: "r", issueNumber: "1", outputFile: filepath.Join(dir, "missing.txt")}}
+	err := writeManifest(filepath.Join(dir, "manifest.json"), "o", "r", saved)
+	if err == nil {
+		t.Fatal("writeManifest succeeded without the output file")
+	}
+	if _, statErr := os.Stat(filepath.Join(dir, "manifest.json")); statErr == nil {
+		t.Error("manifest written despite the error")
+	}
+}
+
+func TestRunManifestToStdout(t *testing.T) {
+	setFlag(t, &manifestFlag, "manifest.json")
+	setFlag(t, &outputFlag, "-")
+
+	err := runStubbed(t, stubAPI{})
+	if exitCode(err) != exitUsage || !strings.Contains(err.Error(), "--manifest") {
+		t.Errorf("run() error = %v, want a usage error about --manifest", err)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// One post in the --merge report: an issue being opened or a comment on it
+type mergedPost struct {
+	label string // issue the post belongs to, e.g. #12
+	id    int64
+	title string // only set for the issue itself
+	user  User
+	at    time.Time
+	body  string
+}
+
+// collectPosts fetches an issue and its comments as posts for the merged report.
+func collectPosts(f *fetcher, owner, repo, issueNumber, label string, names *anonymizer) ([]mergedPost, []Comment, error) {
+	issue, comments, err := fetchThread(f, owner, repo, issueNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+	prepareThread(&issue, comments, names)
+
+	// The report is written before the state is saved, so the posts count as seen
+	if f.state != nil {
+		f.state.record(stateKey(owner, repo, issueNumber), comments)
+	}
+
+	var posts []mergedPost
+	if includeIssueFlag {
+		posts = append(posts, mergedPost{label: label, id: issue.ID, title: issue.Title, user: issue.User, at: issue.DateTime, body: issue.Body})
+	}
+	for _, comment := range comments {
+		posts = append(posts, mergedPost{label: label, id: comment.ID, user: comment.User, at: comment.DateTime, body: comment.Body})
+	}
+	return posts, comments, nil
+}
+
+// writeMerged writes the posts of several issues in the order they were
+// made, each prefixed with the issue it belongs to.
+func writeMerged(out io.Writer, posts []mergedPost) error {
+	sort.SliceStable(posts, func(i, j int) bool {
+		return posts[i].at.Before(posts[j].at)
+	})
+
+	for i, post := range posts {
+		if i > 0 {
+			_, err := io.WriteString(out, "\n") // Leave two-line space between posts
+			if err != nil {
+				return fmt.Errorf("failed to write space: %w", err)
+			}
+		}
+
+		header := fmt.Sprintf("[%s] Comment%s by %s at %s:\n", post.label, idTag(post.id),
+			colorize(displayAuthor(post.user), colorCyan), colorize(formatTime(post.at), colorYellow))
+		if post.title != "" {
+			header = fmt.Sprintf("[%s] Issue%s opened by %s at %s: %s\n", post.label, idTag(post.id),
+				colorize(displayAuthor(post.user), colorCyan), colorize(formatTime(post.at), colorYellow), colorize(post.title, colorBold))
+		}
+
+		_, err := io.WriteString(out, header+post.body+"\n")
+		if err != nil {
+			return fmt.Errorf("failed to write post: %w", err)
+		}
+	}
+	return nil
+}
+
+// saveMerged writes the merged report to outputFile, or stdout for -.
+func saveMerged(outputFile string, posts []mergedPost) error {
+	var dest output
+	var out io.Writer = os.Stdout
+	if outputFile != "-" {
+		var err error
+		dest, err = openOutput(outputFile)
+		if err != nil {
+			return err
+		}
+		defer dest.Close()
+		out = dest
+	}
+	colorEnabled = outputFile == "-" && useColor(os.Stdout)
+
+	err := writeMerged(out, posts)
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	if dest != nil {
+		err = dest.commit()
+		if err != nil {
+			return err
+		}
+	}
+
+	if outputFile != "-" {
+		statusf("Merged %d post(s) into %s.\n", len(posts), outputFile)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteMerged(t *testing.T) {
+	at := func(hour int) time.Time { return time.Date(2024, 3, 1, hour, 0, 0, 0, time.UTC) }
+	posts := []mergedPost{
+		{label: "#1", id: 10, title: "Crash", user: User{Login: "alice"}, at: at(9), body: "It crashes."},
+		{label: "#1", id: 11, user: User{Login: "bob"}, at: at(12), body: "Same here."},
+		{label: "#2", id: 20, title: "Slow start", user: User{Login: "carol"}, at: at(10), body: "Takes ages."},
+		{label: "#2", id: 21, user: User{Login: "dave"}, at: at(12), body: "Confirmed."},
+	}
+
+	var out strings.Builder
+	err := writeMerged(&out, posts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Posts are interleaved by time, ties keeping their issue order
+	want := []string{
+		"[#1] Issue [id=10] opened by alice at 2024-03-01 09:00:00: Crash",
+		"It crashes.",
+		"",
+		"[#2] Issue [id=20] opened by carol at 2024-03-01 10:00:00: Slow start",
+		"Takes ages.",
+		"",
+		"[#1] Comment [id=11] by bob at 2024-03-01 12:00:00:",
+		"Same here.",
+		"",
+		"[#2] Comment [id=21] by dave at 2024-03-01 12:00:00:",
+		"Confirmed.",
+		"",
+	}
+	if got := out.String(); got != strings.Join(want, "\n") {
+		t.Errorf("writeMerged() =\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metric holding the time of the last run without failures
+const lastSuccessMetric = "gcf_last_success_timestamp_seconds"
+
+// writeMetrics writes the run's metrics for the node_exporter textfile
+// collector. A failed run keeps the last success time of the previous file.
+func writeMetrics(path string, stats *runStats, saved []savedThread, succeeded bool) error {
+	comments := 0
+	for _, thread := range saved {
+		comments += thread.count
+	}
+
+	lastSuccess := previousMetric(path, lastSuccessMetric)
+	if succeeded {
+		lastSuccess = float64(time.Now().Unix())
+	}
+
+	var b strings.Builder
+	writeMetric(&b, "gcf_comments_fetched", "gauge", "Comments written by the last run.", float64(comments))
+	writeMetric(&b, "gcf_api_requests_total", "counter", "API requests sent by the last run.", float64(stats.requests.Load()))
+	if remaining := stats.rateRemaining.Load(); remaining >= 0 {
+		writeMetric(&b, "gcf_rate_limit_remaining", "gauge", "Requests left in the rate limit window after the last run.", float64(remaining))
+	}
+	if lastSuccess > 0 {
+		writeMetric(&b, lastSuccessMetric, "gauge", "Unix time of the last run without failures.", lastSuccess)
+	}
+
+	err := writeFileAtomic(path, []byte(b.String()))
+	if err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	return nil
+}
+
+// writeMetric writes one metric with its HELP and TYPE lines.
+func writeMetric(b *strings.Builder, name, kind, help string, value float64) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", name, help, name, kind, name, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// previousMetric reads a metric from an earlier metrics file, or 0 when
+// there is none.
+func previousMetric(path, name string) float64 {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == name {
+			value, err := strconv.ParseFloat(fields[1], 64)
+			if err == nil {
+				return value
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	saved := []savedThread{
+		{count: 2},
+		{count: 1},
+	}
+
+	tests := []struct {
+		name          string
+		previous      string
+		rateRemaining int64
+		succeeded     bool
+		want          map[string]string
+	}{
+		{
+			name:          "success",
+			rateRemaining: 4990,
+			succeeded:     true,
+			want:          map[string]string{"gcf_comments_fetched": "3", "gcf_api_requests_total": "7", "gcf_rate_limit_remaining": "4990"},
+		},
+		{
+			name:          "failure keeps the last success",
+			previous:      "gcf_last_success_timestamp_seconds 1700000000\n",
+			rateRemaining: -1,
+			succeeded:     false,
+			want:          map[string]string{"gcf_comments_fetched": "3", "gcf_api_requests_total": "7", lastSuccessMetric: "1700000000"},
+		},
+		{
+			name:          "first failure",
+			rateRemaining: -1,
+			succeeded:     false,
+			want:          map[string]string{"gcf_comments_fetched": "3", "gcf_api_requests_total": "7"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "gcf.prom")
+			if tt.previous != "" {
+				err := os.WriteFile(path, []byte(tt.previous), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			stats := newRunStats()
+			stats.requests.Store(7)
+			stats.rateRemaining.Store(tt.rateRemaining)
+
+			started := time.Now().Unix()
+			err := writeMetrics(path, stats, saved, tt.succeeded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Parse the samples, checking every metric has its HELP and TYPE lines
+			got := make(map[string]string)
+			for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+				if strings.HasPrefix(line, "#") {
+					continue
+				}
+				fields := strings.Fields(line)
+				if len(fields) != 2 {
+					t.Fatalf("malformed sample %q", line)
+				}
+				if !strings.Contains(string(data), "# TYPE "+fields[0]+" ") || !strings.Contains(string(data), "# HELP "+fields[0]+" ") {
+					t.Errorf("%s has no HELP or TYPE line", fields[0])
+				}
+				got[fields[0]] = fields[1]
+			}
+
+			if tt.succeeded {
+				lastSuccess, err := strconv.ParseInt(got[lastSuccessMetric], 10, 64)
+				if err != nil || lastSuccess < started {
+					t.Errorf("%s = %q, want the time of this run", lastSuccessMetric, got[lastSuccessMetric])
+				}
+				delete(got, lastSuccessMetric)
+			}
+			for name, value := range tt.want {
+				if got[name] != value {
+					t.Errorf("%s = %q, want %q", name, got[name], value)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("metrics = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
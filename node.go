@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Query resolving a global node ID to the repository and number it belongs to
+const nodeQuery = `query($id: ID!) {
+  node(id: $id) {
+    __typename
+    ... on Issue { number repository { ...nodeRepository } }
+    ... on PullRequest { number repository { ...nodeRepository } }
+    ... on Discussion { number repository { ...nodeRepository } }
+  }
+}
+
+fragment nodeRepository on Repository {
+  name
+  owner { login }
+}`
+
+// resolveNode looks up the issue or PR with the given GraphQL node ID.
+func (f *fetcher) resolveNode(id string) (target, error) {
+	var data struct {
+		Node *struct {
+			TypeName   string `json:"__typename"`
+			Number     int    `json:"number"`
+			Repository struct {
+				Name  string `json:"name"`
+				Owner struct {
+					Login string `json:"login"`
+				} `json:"owner"`
+			} `json:"repository"`
+		} `json:"node"`
+	}
+	err := f.postGraphQL(nodeQuery, map[string]interface{}{"id": id}, &data)
+	if err != nil {
+		return target{}, fmt.Errorf("failed to resolve node %s: %w", id, err)
+	}
+
+	node := data.Node
+	if node == nil {
+		return target{}, &exitError{code: exitNotFound, err: fmt.Errorf("node %s not found", id)}
+	}
+	switch node.TypeName {
+	case "Issue", "PullRequest":
+	case "Discussion":
+		return target{}, usageErrorf("node %s is discussion #%d in %s/%s; only issues and PRs can be fetched",
+			id, node.Number, node.Repository.Owner.Login, node.Repository.Name)
+	default:
+		return target{}, usageErrorf("node %s is a %s, not an issue or PR", id, node.TypeName)
+	}
+
+	return target{Owner: node.Repository.Owner.Login, Repo: node.Repository.Name, IssueNumber: strconv.Itoa(node.Number)}, nil
+}
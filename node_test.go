@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResolveNode(t *testing.T) {
+	repository := `"repository":{"name":"hello-world","owner":{"login":"octocat"}}`
+	tests := []struct {
+		name     string
+		response string
+		want     target
+		wantCode int
+		wantErr  string
+	}{
+		{
+			name:     "issue",
+			response: `{"data":{"node":{"__typename":"Issue","number":7,` + repository + `}}}`,
+			want:     target{Owner: "octocat", Repo: "hello-world", IssueNumber: "7"},
+		},
+		{
+			name:     "pull request",
+			response: `{"data":{"node":{"__typename":"PullRequest","number":8,` + repository + `}}}`,
+			want:     target{Owner: "octocat", Repo: "hello-world", IssueNumber: "8"},
+		},
+		{
+			name:     "discussion",
+			response: `{"data":{"node":{"__typename":"Discussion","number":9,` + repository + `}}}`,
+			wantCode: exitUsage,
+			wantErr:  "discussion #9 in octocat/hello-world",
+		},
+		{
+			name:     "other node",
+			response: `{"data":{"node":{"__typename":"User"}}}`,
+			wantCode: exitUsage,
+			wantErr:  "is a User",
+		},
+		{
+			name:     "not found",
+			response: `{"data":{"node":null}}`,
+			wantCode: exitNotFound,
+			wantErr:  "not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotID interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var request struct {
+					Variables map[string]interface{} `json:"variables"`
+				}
+				json.NewDecoder(r.Body).Decode(&request)
+				gotID = request.Variables["id"]
+				fmt.Fprint(w, tt.response)
+			}))
+			defer server.Close()
+			setFlag(t, &apiBaseURL, server.URL)
+
+			f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats()}
+			got, err := f.resolveNode("I_kwDOA")
+			if gotID != "I_kwDOA" {
+				t.Errorf("queried node %v, want I_kwDOA", gotID)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || exitCode(err) != tt.wantCode {
+					t.Fatalf("resolveNode() error = %v (exit code %d), want %q (exit code %d)", err, exitCode(err), tt.wantErr, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("resolveNode() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Comments updated later than this after being posted are shown as edited,
+// smaller differences come from GitHub's own bookkeeping
+const editThreshold = time.Minute
+
+// Data handed to user supplied output templates
+type templateData struct {
+	Issue    Issue
+	Comments []Comment
+}
+
+// displayBody stands in for an issue description that is missing or empty.
+func displayBody(body string) string {
+	if strings.TrimSpace(body) == "" {
+		return "(no description provided)"
+	}
+	return body
+}
+
+// displayLogin stands in for the login of a deleted account.
+func displayLogin(login string) string {
+	if login == "" {
+		return "(ghost)"
+	}
+	return login
+}
+
+// idTag marks a header with the API id of the issue or comment, when known.
+func idTag(id int64) string {
+	if id == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" [id=%d]", id)
+}
+
+// displayAuthor shows the user's display name next to the login when known.
+func displayAuthor(user User) string {
+	if user.Name == "" {
+		return displayLogin(user.Login)
+	}
+	return fmt.Sprintf("%s (@%s)", user.Name, user.Login)
+}
+
+// writeText writes the issue followed by its comments in the built-in plain text format.
+func writeText(out io.Writer, issue Issue, comments []Comment) error {
+	// Write the issue details
+	issueLine := fmt.Sprintf("Issue Title: %s%s\nIssue Body: %s\nIssue Author: %s\nCreated At: %s\nUpdated At: %s\n",
+		colorize(issue.Title, colorBold), idTag(issue.ID), renderBody(displayBody(issue.Body)), colorize(displayAuthor(issue.User), colorCyan),
+		colorize(formatTime(issue.DateTime), colorYellow), colorize(formatTime(issue.UpdatedAt), colorYellow))
+	if issue.State != "" {
+		issueLine += stateLine(issue) + "\n"
+	}
+	if issue.ClosedBy != nil {
+		issueLine += closedByLine(issue.ClosedBy) + "\n"
+	}
+	if issue.PullRequest != nil {
+		issueLine += pullRequestLine(issue.PullRequest) + "\n"
+	}
+	if len(issue.LinkedPRs) > 0 {
+		issueLine += "Linked Pull Requests:\n"
+		for _, pr := range issue.LinkedPRs {
+			issueLine += "  " + linkedPRLine(pr) + "\n"
+		}
+	}
+	issueLine += "\n"
+
+	_, err := io.WriteString(out, issueLine)
+	if err != nil {
+		return fmt.Errorf("failed to write issue details: %w", err)
+	}
+
+	err = writeLabelHistory(out, issue.LabelEvents)
+	if err != nil {
+		return err
+	}
+
+	err = writeComments(out, comments)
+	if err != nil {
+		return err
+	}
+
+	// Review threads of pull requests follow the conversation
+	return writeReviewThreads(out, issue.ReviewComments)
+}
+
+// stateLine shows whether the issue is open or closed, with the reason it
+// was closed when GitHub gives one.
+func stateLine(issue Issue) string {
+	if issue.StateReason == "" || issue.State == "open" {
+		return "State: " + issue.State
+	}
+	return fmt.Sprintf("State: %s (%s)", issue.State, issue.StateReason)
+}
+
+// pullRequestLine describes the merge state, branches and size of a pull request.
+func pullRequestLine(pr *PullRequest) string {
+	state := "not merged"
+	if pr.Merged {
+		state = "merged"
+	}
+	if pr.Draft {
+		state += ", draft"
+	}
+
+	return fmt.Sprintf("Pull Request: %s, %s → %s, +%d -%d", state, pr.Base.Ref, pr.Head.Ref, pr.Additions, pr.Deletions)
+}
+
+// writeCommitText writes the comments of a commit in the built-in plain text format.
+func writeCommitText(out io.Writer, sha string, comments []Comment) error {
+	_, err := fmt.Fprintf(out, "Commit: %s\n\n", sha)
+	if err != nil {
+		return fmt.Errorf("failed to write commit details: %w", err)
+	}
+
+	return writeComments(out, comments)
+}
+
+// writeComments writes each comment as a header line followed by its body,
+// in a section per author with --group-by author.
+func writeComments(out io.Writer, comments []Comment) error {
+	if groupByFlag == "author" {
+		return writeGroupedComments(out, comments)
+	}
+	return writeCommentsFrom(out, comments, 1)
+}
+
+// writeCommentsFrom works like writeComments, numbering the comments from
+// first so more can be appended to earlier output.
+func writeCommentsFrom(out io.Writer, comments []Comment, first int) error {
+	var err error
+	for i, comment := range comments {
+		number := first + i
+		if number > 1 {
+			_, err = io.WriteString(out, "\n") // Leave two-line space between comment blocks
+			if err != nil {
+				return fmt.Errorf("failed to write space: %w", err)
+			}
+		}
+
+		author := colorize(displayAuthor(comment.User), colorCyan)
+		if isBot(comment.User) && !onlyBotsFlag {
+			author += " (bot)"
+		}
+
+		commentHeader := fmt.Sprintf("Comment %d%s by %s at %s", number, idTag(comment.ID), author, colorize(formatTime(comment.DateTime), colorYellow))
+
+		// Point out comments that were changed after being posted
+		if comment.UpdatedAt.Sub(comment.DateTime) > editThreshold {
+			commentHeader += fmt.Sprintf(" (edited %s)", colorize(formatTime(comment.UpdatedAt), colorYellow))
+		}
+
+		// Point out comments that GitHub shows collapsed
+		if comment.Minimized {
+			reason := comment.MinimizedReason
+			if reason == "" {
+				reason = "minimized"
+			}
+			commentHeader += fmt.Sprintf(" [hidden: %s]", reason)
+		}
+
+		// Commit comments can be attached to a line of a file
+		if comment.Path != "" {
+			commentHeader += " on " + comment.Path
+			if comment.Position != nil {
+				commentHeader += fmt.Sprintf(":%d", *comment.Position)
+			}
+		}
+
+		_, err = io.WriteString(out, commentHeader+":\n")
+		if err != nil {
+			return fmt.Errorf("failed to write comment header: %w", err)
+		}
+
+		commentBody := fmt.Sprintf("%s\n", renderBody(comment.Body))
+		_, err = io.WriteString(out, commentBody)
+		if err != nil {
+			return fmt.Errorf("failed to write comment body: %w", err)
+		}
+
+		if comment.Translation != "" {
+			_, err = fmt.Fprintf(out, "Translation (%s):\n%s\n", translateToFlag, comment.Translation)
+			if err != nil {
+				return fmt.Errorf("failed to write translation: %w", err)
+			}
+		}
+
+		// Who reacted, when fetched with --detailed-reactions
+		if len(comment.Reactors) > 0 {
+			_, err = io.WriteString(out, reactorsLine(comment.Reactors)+"\n")
+			if err != nil {
+				return fmt.Errorf("failed to write reactions: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Longest body excerpt written by --compact, in characters
+const compactWidth = 80
+
+// writeCompact writes each comment on a single line, with the start of its
+// body flattened onto that line.
+func writeCompact(out io.Writer, comments []Comment) error {
+	for i, comment := range comments {
+		_, err := fmt.Fprintf(out, "[%d] @%s %s: %s\n", i+1, displayLogin(comment.User.Login),
+			colorize(formatTime(comment.DateTime), colorYellow), excerpt(comment.Body, compactWidth))
+		if err != nil {
+			return fmt.Errorf("failed to write comment: %w", err)
+		}
+	}
+	return nil
+}
+
+// excerpt joins the lines of s with spaces and cuts it down to max
+// characters, marking the cut with an ellipsis.
+func excerpt(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return strings.TrimRight(string(runes[:max]), " ") + "…"
+}
+
+// renderTemplate renders the issue and comments through the text/template at templatePath.
+func renderTemplate(out io.Writer, templatePath string, issue Issue, comments []Comment) error {
+	funcs := template.FuncMap{
+		// date formats a timestamp, using --time-format when no layout is given
+		"date": func(t time.Time, layout ...string) string {
+			if len(layout) > 0 {
+				return t.In(displayLocation).Format(layout[0])
+			}
+			return formatTime(t)
+		},
+	}
+
+	tmpl, err := template.New(filepath.Base(templatePath)).Funcs(funcs).ParseFiles(templatePath)
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	err = tmpl.Execute(out, templateData{Issue: issue, Comments: comments})
+	if err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Issue as written in the JSON output
+type jsonIssue struct {
+	ID        int64  `json:"id,omitempty"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	Author    string `json:"author"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+
+	State       string `json:"state,omitempty"`
+	StateReason string `json:"state_reason,omitempty"`
+
+	PullRequest *PullRequest `json:"pull_request,omitempty"`
+	LinkedPRs   []LinkedPR   `json:"linked_prs,omitempty"`
+	LabelEvents []LabelEvent `json:"label_events,omitempty"`
+	ClosedBy    *Closure     `json:"closed_by,omitempty"`
+}
+
+// Comment as written in the JSON output
+type jsonComment struct {
+	ID              int64  `json:"id,omitempty"`
+	Author          string `json:"author"`
+	Body            string `json:"body"`
+	CreatedAt       string `json:"created_at"`
+	UpdatedAt       string `json:"updated_at,omitempty"`
+	Path            string `json:"path,omitempty"`
+	Position        *int   `json:"position,omitempty"`
+	Minimized       bool   `json:"minimized,omitempty"`
+	MinimizedReason string `json:"minimized_reason,omitempty"`
+	Translation     string `json:"translation,omitempty"`
+
+	Reactions map[string][]string `json:"reactions,omitempty"`
+}
+
+// Keys that can be picked with --fields
+var jsonFields = []string{"author", "body", "closed_by", "created_at", "id", "label_events", "linked_prs", "minimized", "minimized_reason", "path", "position", "pull_request", "reactions", "state", "state_reason", "title", "translation", "updated_at"}
+
+// parseFields splits a comma separated --fields value, rejecting unknown names.
+func parseFields(value string) ([]string, error) {
+	var fields []string
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		i := sort.SearchStrings(jsonFields, field)
+		if i == len(jsonFields) || jsonFields[i] != field {
+			return nil, fmt.Errorf("unknown field %q; valid fields are: %s", field, strings.Join(jsonFields, ", "))
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// newJSONIssue converts an issue to its JSON output form.
+func newJSONIssue(issue Issue) jsonIssue {
+	return jsonIssue{
+		ID:        issue.ID,
+		Title:     issue.Title,
+		Body:      issue.Body,
+		Author:    issue.User.Login,
+		CreatedAt: issue.DateTime.In(displayLocation).Format(time.RFC3339),
+		UpdatedAt: issue.UpdatedAt.In(displayLocation).Format(time.RFC3339),
+
+		State:       issue.State,
+		StateReason: issue.StateReason,
+
+		PullRequest: issue.PullRequest,
+		LinkedPRs:   issue.LinkedPRs,
+		LabelEvents: issue.LabelEvents,
+		ClosedBy:    issue.ClosedBy,
+	}
+}
+
+// newJSONComment converts a comment to its JSON output form.
+func newJSONComment(comment Comment) jsonComment {
+	return jsonComment{
+		ID:              comment.ID,
+		Author:          comment.User.Login,
+		Body:            comment.Body,
+		CreatedAt:       comment.DateTime.In(displayLocation).Format(time.RFC3339),
+		UpdatedAt:       formatOptionalTime(comment.UpdatedAt),
+		Path:            comment.Path,
+		Position:        comment.Position,
+		Minimized:       comment.Minimized,
+		MinimizedReason: comment.MinimizedReason,
+		Translation:     comment.Translation,
+
+		Reactions: comment.Reactors,
+	}
+}
+
+// project keeps only the given keys of v's JSON form. With no fields v is
+// returned unchanged.
+func project(v interface{}, fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return v, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var all map[string]json.RawMessage
+	err = json.Unmarshal(data, &all)
+	if err != nil {
+		return nil, err
+	}
+
+	projected := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			projected[field] = value
+		}
+	}
+	return projected, nil
+}
+
+// writeJSON writes the issue (or commit) and its comments as a single JSON
+// document, keeping only the given fields of each object when any are set.
+func writeJSON(out io.Writer, issue *Issue, commitSHA string, comments []Comment, fields []string) error {
+	var document struct {
+		Issue    interface{}   `json:"issue,omitempty"`
+		Commit   string        `json:"commit,omitempty"`
+		Comments []interface{} `json:"comments"`
+	}
+
+	var err error
+	if issue != nil {
+		document.Issue, err = project(newJSONIssue(*issue), fields)
+		if err != nil {
+			return fmt.Errorf("failed to convert issue: %w", err)
+		}
+	}
+	document.Commit = commitSHA
+
+	document.Comments = make([]interface{}, 0, len(comments))
+	for _, comment := range comments {
+		projected, err := project(newJSONComment(comment), fields)
+		if err != nil {
+			return fmt.Errorf("failed to convert comment: %w", err)
+		}
+		document.Comments = append(document.Comments, projected)
+	}
+
+	documentJSON, err := marshalOutput(document)
+	if err != nil {
+		return fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	_, err = out.Write(append(documentJSON, '\n'))
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
+
+// formatOptionalTime formats t as RFC 3339, or returns "" when it isn't set.
+func formatOptionalTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(displayLocation).Format(time.RFC3339)
+}
+
+// marshalOutput encodes v as indented JSON, or compact JSON with --pretty=false.
+func marshalOutput(v interface{}) ([]byte, error) {
+	if !prettyFlag {
+		return json.Marshal(v)
+	}
+	return json.MarshalIndent(v, "", strings.Repeat(" ", indentFlag))
+}
+
+// writeNDJSON writes one JSON object per line: the issue (or commit) first,
+// then every comment, each tagged with its "type" so lines can be handled on
+// their own.
+func writeNDJSON(out io.Writer, issue *Issue, commitSHA string, comments []Comment, fields []string) error {
+	if issue != nil {
+		err := writeNDJSONLine(out, "issue", newJSONIssue(*issue), fields)
+		if err != nil {
+			return err
+		}
+	}
+	if commitSHA != "" {
+		err := writeNDJSONLine(out, "commit", struct {
+			SHA string `json:"sha"`
+		}{commitSHA}, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, comment := range comments {
+		err := writeNDJSONLine(out, "comment", newJSONComment(comment), fields)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeNDJSONLine writes v, limited to the given fields, as a single line
+// with its "type" added.
+func writeNDJSONLine(out io.Writer, kind string, v interface{}, fields []string) error {
+	projected, err := project(v, fields)
+	if err != nil {
+		return fmt.Errorf("failed to convert %s: %w", kind, err)
+	}
+	data, err := json.Marshal(projected)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", kind, err)
+	}
+
+	var record map[string]json.RawMessage
+	err = json.Unmarshal(data, &record)
+	if err != nil {
+		return fmt.Errorf("failed to convert %s: %w", kind, err)
+	}
+	record["type"], err = json.Marshal(kind)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", kind, err)
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", kind, err)
+	}
+	_, err = out.Write(append(line, '\n'))
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
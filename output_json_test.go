@@ -0,0 +1,177 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{value: "author,body", want: []string{"author", "body"}},
+		{value: " id , created_at ,", want: []string{"id", "created_at"}},
+		{value: "", want: nil},
+		{value: "author,nickname", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseFields(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseFields(%q) error = %v, want error: %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseFields(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	issue := &Issue{ID: 7, Title: "Crash", Body: "It crashes.", User: User{Login: "alice"}, DateTime: created, UpdatedAt: created}
+	comments := []Comment{{ID: 1, User: User{Login: "bob"}, Body: "Same.", DateTime: created}}
+
+	tests := []struct {
+		name      string
+		issue     *Issue
+		commitSHA string
+		comments  []Comment
+		fields    []string
+		want      string
+	}{
+		{
+			name:     "issue and comments",
+			issue:    issue,
+			comments: comments,
+			want:     `{"issue":{"id":7,"title":"Crash","body":"It crashes.","author":"alice","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"},"comments":[{"id":1,"author":"bob","body":"Same.","created_at":"2024-03-01T12:30:00Z"}]}`,
+		},
+		{
+			name:     "fields",
+			issue:    issue,
+			comments: comments,
+			fields:   []string{"author", "title"},
+			want:     `{"issue":{"author":"alice","title":"Crash"},"comments":[{"author":"bob"}]}`,
+		},
+		{
+			name:      "commit",
+			commitSHA: "abc123",
+			comments:  comments,
+			fields:    []string{"id"},
+			want:      `{"commit":"abc123","comments":[{"id":1}]}`,
+		},
+		{
+			name: "no comments",
+			want: `{"comments":[]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &prettyFlag, false)
+
+			var out strings.Builder
+			err := writeJSON(&out, tt.issue, tt.commitSHA, tt.comments, tt.fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSuffix(out.String(), "\n"); got != tt.want {
+				t.Errorf("writeJSON() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshalOutput(t *testing.T) {
+	v := map[string]interface{}{"a": 1, "b": []int{2}}
+	tests := []struct {
+		name   string
+		pretty bool
+		indent int
+		want   string
+	}{
+		{name: "compact", pretty: false, indent: 2, want: `{"a":1,"b":[2]}`},
+		{name: "two spaces", pretty: true, indent: 2, want: "{\n  \"a\": 1,\n  \"b\": [\n    2\n  ]\n}"},
+		{name: "four spaces", pretty: true, indent: 4, want: "{\n    \"a\": 1,\n    \"b\": [\n        2\n    ]\n}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &prettyFlag, tt.pretty)
+			setFlag(t, &indentFlag, tt.indent)
+
+			got, err := marshalOutput(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("marshalOutput() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	issue := &Issue{ID: 7, Title: "Crash", User: User{Login: "alice"}, DateTime: created, UpdatedAt: created}
+	comments := []Comment{
+		{ID: 1, User: User{Login: "bob"}, Body: "line one\nline two", DateTime: created},
+		{ID: 2, User: User{Login: "carol"}, Body: "ok", DateTime: created},
+	}
+
+	tests := []struct {
+		name      string
+		issue     *Issue
+		commitSHA string
+		fields    []string
+		want      []string
+	}{
+		{
+			name:   "issue and comments",
+			issue:  issue,
+			fields: []string{"id", "author"},
+			want: []string{
+				`{"author":"alice","id":7,"type":"issue"}`,
+				`{"author":"bob","id":1,"type":"comment"}`,
+				`{"author":"carol","id":2,"type":"comment"}`,
+			},
+		},
+		{
+			name:      "commit",
+			commitSHA: "abc123",
+			fields:    []string{"body"},
+			want: []string{
+				`{"sha":"abc123","type":"commit"}`,
+				`{"body":"line one\nline two","type":"comment"}`,
+				`{"body":"ok","type":"comment"}`,
+			},
+		},
+		{
+			name:   "comments only",
+			fields: []string{"id"},
+			want: []string{
+				`{"id":1,"type":"comment"}`,
+				`{"id":2,"type":"comment"}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			err := writeNDJSON(&out, tt.issue, tt.commitSHA, comments, tt.fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("writeNDJSON() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
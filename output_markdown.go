@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writeMarkdown writes the issue (or commit) and its comments as a Markdown
+// document, with a linked table of contents of the comments when toc is set.
+func writeMarkdown(out io.Writer, issue *Issue, commitSHA string, comments []Comment, toc bool) error {
+	var b strings.Builder
+
+	// Start with the issue, or the commit the comments belong to
+	switch {
+	case issue != nil:
+		fmt.Fprintf(&b, "# %s\n\n", issue.Title)
+		fmt.Fprintf(&b, "Opened by %s at %s", markdownAuthor(issue.User), formatTime(issue.DateTime))
+		if issue.State != "" {
+			fmt.Fprintf(&b, " · %s", stateLine(*issue))
+		}
+		if issue.ClosedBy != nil {
+			fmt.Fprintf(&b, " · %s", closedByLine(issue.ClosedBy))
+		}
+		if issue.PullRequest != nil {
+			fmt.Fprintf(&b, " · %s", pullRequestLine(issue.PullRequest))
+		}
+		fmt.Fprintf(&b, "\n\n")
+		if len(issue.LinkedPRs) > 0 {
+			b.WriteString("Linked pull requests:\n\n")
+			for _, pr := range issue.LinkedPRs {
+				fmt.Fprintf(&b, "- %s\n", linkedPRLine(pr))
+			}
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s\n\n", displayBody(issue.Body))
+	case commitSHA != "":
+		fmt.Fprintf(&b, "# Commit %s\n\n", commitSHA)
+	}
+
+	// Put each author's comments together, under a heading of their own
+	var groups []commentGroup
+	commentLevel := "##"
+	if groupByFlag == "author" {
+		groups = groupByAuthor(comments)
+		comments = nil
+		for _, group := range groups {
+			comments = append(comments, group.comments...)
+		}
+		commentLevel = "###"
+	}
+
+	// List the comments with links to their headings
+	if toc && len(comments) > 0 {
+		b.WriteString("## Contents\n\n")
+		for i, comment := range comments {
+			fmt.Fprintf(&b, "%d. [%s — %s](#%s)\n", i+1, markdownAuthor(comment.User),
+				comment.DateTime.In(displayLocation).Format("2006-01-02"), commentAnchor(i+1))
+		}
+		b.WriteString("\n")
+	}
+
+	// Rule off each comment, except where an author's heading opens a group
+	grouped := len(groups) > 0
+	groupEnd := 0
+	for i, comment := range comments {
+		if grouped && i == groupEnd {
+			fmt.Fprintf(&b, "## %s\n\n", groupHeading(groups[0]))
+			groupEnd += len(groups[0].comments)
+			groups = groups[1:]
+		} else {
+			b.WriteString("---\n\n")
+		}
+		fmt.Fprintf(&b, "<a id=\"%s\"></a>\n\n%s Comment %d%s by %s at %s\n\n%s\n\n",
+			commentAnchor(i+1), commentLevel, i+1, idTag(comment.ID), markdownAuthor(comment.User), formatTime(comment.DateTime), comment.Body)
+		if comment.Translation != "" {
+			fmt.Fprintf(&b, "_Translation (%s):_\n\n%s\n\n", translateToFlag, comment.Translation)
+		}
+		if len(comment.Reactors) > 0 {
+			fmt.Fprintf(&b, "_%s_\n\n", reactorsLine(comment.Reactors))
+		}
+	}
+
+	_, err := io.WriteString(out, b.String())
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// commentAnchor is the id of the heading of the numbered comment.
+func commentAnchor(number int) string {
+	return fmt.Sprintf("comment-%d", number)
+}
+
+// markdownAuthor shows a user as an @-mention, with the display name when known.
+func markdownAuthor(user User) string {
+	if user.Login == "" {
+		return displayLogin(user.Login)
+	}
+	if user.Name != "" {
+		return fmt.Sprintf("%s (@%s)", user.Name, user.Login)
+	}
+	return "@" + user.Login
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteMarkdownTOC(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 12, 0, 0, 0, time.UTC) }
+	issue := Issue{Title: "Long thread", User: User{Login: "alice"}, Body: "Start.", DateTime: day(1)}
+	comments := []Comment{
+		{User: User{Login: "alice"}, Body: "First.", DateTime: day(2)},
+		{User: User{Login: "bob", Name: "Bob Smith"}, Body: "Second.", DateTime: day(3)},
+		{User: User{Login: "carol"}, Body: "Third.", DateTime: day(4)},
+	}
+
+	tests := []struct {
+		name     string
+		toc      bool
+		comments []Comment
+		want     []string
+	}{
+		{
+			name:     "contents",
+			toc:      true,
+			comments: comments,
+			want: []string{
+				"1. [@alice — 2024-01-02](#comment-1)",
+				"2. [Bob Smith (@bob) — 2024-01-03](#comment-2)",
+				"3. [@carol — 2024-01-04](#comment-3)",
+			},
+		},
+		{name: "no contents", toc: false, comments: comments, want: nil},
+		{name: "no comments", toc: true, comments: nil, want: nil},
+	}
+
+	entry := regexp.MustCompile(`(?m)^\d+\. \[.*\]\(#(.*)\)$`)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &displayLocation, time.UTC)
+
+			var out strings.Builder
+			err := writeMarkdown(&out, &issue, "", tt.comments, tt.toc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, line := range strings.Split(out.String(), "\n") {
+				if entry.MatchString(line) {
+					got = append(got, line)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("contents = %q, want %q", got, tt.want)
+			}
+			wantHeading := tt.want != nil
+			if got := strings.Contains(out.String(), "## Contents"); got != wantHeading {
+				t.Errorf("contents heading written = %v, want %v", got, wantHeading)
+			}
+
+			// Every entry links to the anchor of a comment heading
+			for _, match := range entry.FindAllStringSubmatch(out.String(), -1) {
+				if !strings.Contains(out.String(), `<a id="`+match[1]+`"></a>`) {
+					t.Errorf("no heading with the anchor %q:\n%s", match[1], out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestMarkdownAuthor(t *testing.T) {
+	tests := []struct {
+		user User
+		want string
+	}{
+		{user: User{Login: "alice"}, want: "@alice"},
+		{user: User{Login: "bob", Name: "Bob Smith"}, want: "Bob Smith (@bob)"},
+		{user: User{}, want: "(ghost)"},
+	}
+
+	for _, tt := range tests {
+		if got := markdownAuthor(tt.user); got != tt.want {
+			t.Errorf("markdownAuthor(%+v) = %q, want %q", tt.user, got, tt.want)
+		}
+	}
+}
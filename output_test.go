@@ -0,0 +1,309 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	issue := Issue{Title: "Crash on start", User: User{Login: "alice"}}
+	comments := []Comment{
+		{User: User{Login: "bob"}, Body: "Same here.", DateTime: time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)},
+		{User: User{Login: "carol"}, Body: "Fixed in main.", DateTime: time.Date(2024, 3, 2, 8, 0, 0, 0, time.UTC)},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "fields",
+			template: `{{.Issue.Title}} by {{.Issue.User.Login}}`,
+			want:     "Crash on start by alice",
+		},
+		{
+			name:     "comments",
+			template: `{{range .Comments}}{{.User.Login}}: {{.Body}};{{end}}`,
+			want:     "bob: Same here.;carol: Fixed in main.;",
+		},
+		{
+			name:     "default date",
+			template: `{{date (index .Comments 0).DateTime}}`,
+			want:     "2024-03-01 12:30:00",
+		},
+		{
+			name:     "date layout",
+			template: `{{date (index .Comments 1).DateTime "Jan 2"}}`,
+			want:     "Mar 2",
+		},
+		{
+			name:     "parse error",
+			template: `{{.Issue.Title`,
+			wantErr:  "failed to parse template",
+		},
+		{
+			name:     "execution error",
+			template: `{{.Missing}}`,
+			wantErr:  "failed to render template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templatePath := filepath.Join(t.TempDir(), "report.tmpl")
+			err := os.WriteFile(templatePath, []byte(tt.template), 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out strings.Builder
+			err = renderTemplate(&out, templatePath, issue, comments)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("renderTemplate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("renderTemplate() = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteCommitText(t *testing.T) {
+	position := 12
+	tests := []struct {
+		name    string
+		comment Comment
+		want    string
+	}{
+		{name: "whole commit", comment: Comment{User: User{Login: "alice"}, Body: "LGTM"}, want: "by alice at 2024-03-01 12:30:00:\n"},
+		{name: "file", comment: Comment{User: User{Login: "alice"}, Path: "main.go", Body: "typo"}, want: "on main.go:\n"},
+		{name: "line", comment: Comment{User: User{Login: "alice"}, Path: "main.go", Position: &position, Body: "typo"}, want: "on main.go:12:\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.comment.DateTime = time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+			var out strings.Builder
+			err := writeCommitText(&out, "abc123", []Comment{tt.comment})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.HasPrefix(out.String(), "Commit: abc123\n\n") {
+				t.Errorf("output doesn't start with the commit:\n%s", out.String())
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("output is missing %q:\n%s", tt.want, out.String())
+			}
+		})
+	}
+}
+
+// commentHeader writes a single comment in the text format and returns its header line.
+func commentHeader(t *testing.T, comment Comment) string {
+	t.Helper()
+	var out strings.Builder
+	err := writeComments(&out, []Comment{comment})
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, _, _ := strings.Cut(out.String(), "\n")
+	return header
+}
+
+func TestCommentHeaderEdited(t *testing.T) {
+	posted := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		updated time.Time
+		want    string
+	}{
+		{name: "never updated", want: "Comment 1 by alice at 2024-03-01 12:00:00:"},
+		{name: "updated right away", updated: posted.Add(30 * time.Second), want: "Comment 1 by alice at 2024-03-01 12:00:00:"},
+		{name: "edited later", updated: posted.Add(2 * time.Hour), want: "Comment 1 by alice at 2024-03-01 12:00:00 (edited 2024-03-01 14:00:00):"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := commentHeader(t, Comment{User: User{Login: "alice"}, DateTime: posted, UpdatedAt: tt.updated})
+			if got != tt.want {
+				t.Errorf("header = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDisplayBody(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{body: "It crashes.", want: "It crashes."},
+		{body: "", want: "(no description provided)"},
+		{body: " \n\t", want: "(no description provided)"},
+	}
+
+	for _, tt := range tests {
+		if got := displayBody(tt.body); got != tt.want {
+			t.Errorf("displayBody(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestDisplayLogin(t *testing.T) {
+	tests := []struct {
+		login string
+		want  string
+	}{
+		{login: "alice", want: "alice"},
+		{login: "", want: "(ghost)"},
+	}
+
+	for _, tt := range tests {
+		if got := displayLogin(tt.login); got != tt.want {
+			t.Errorf("displayLogin(%q) = %q, want %q", tt.login, got, tt.want)
+		}
+	}
+
+	// Comments of deleted accounts say so in their header
+	if got := commentHeader(t, Comment{DateTime: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}); got != "Comment 1 by (ghost) at 2024-03-01 12:00:00:" {
+		t.Errorf("header = %q, want the author shown as (ghost)", got)
+	}
+}
+
+func TestPullRequestLine(t *testing.T) {
+	pr := func(merged, draft bool) *PullRequest {
+		p := &PullRequest{Merged: merged, Draft: draft, Additions: 12, Deletions: 3}
+		p.Base.Ref = "main"
+		p.Head.Ref = "fix-crash"
+		return p
+	}
+
+	tests := []struct {
+		name string
+		pr   *PullRequest
+		want string
+	}{
+		{name: "open", pr: pr(false, false), want: "Pull Request: not merged, main → fix-crash, +12 -3"},
+		{name: "merged", pr: pr(true, false), want: "Pull Request: merged, main → fix-crash, +12 -3"},
+		{name: "draft", pr: pr(false, true), want: "Pull Request: not merged, draft, main → fix-crash, +12 -3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pullRequestLine(tt.pr); got != tt.want {
+				t.Errorf("pullRequestLine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDisplayAuthor(t *testing.T) {
+	tests := []struct {
+		user User
+		want string
+	}{
+		{user: User{Login: "alice", Name: "Alice Liddell"}, want: "Alice Liddell (@alice)"},
+		{user: User{Login: "bob"}, want: "bob"},
+		{user: User{}, want: "(ghost)"},
+	}
+
+	for _, tt := range tests {
+		if got := displayAuthor(tt.user); got != tt.want {
+			t.Errorf("displayAuthor(%+v) = %q, want %q", tt.user, got, tt.want)
+		}
+	}
+}
+
+func TestStateLine(t *testing.T) {
+	tests := []struct {
+		state  string
+		reason string
+		want   string
+	}{
+		{state: "open", want: "State: open"},
+		{state: "open", reason: "reopened", want: "State: open"},
+		{state: "closed", reason: "completed", want: "State: closed (completed)"},
+		{state: "closed", reason: "not_planned", want: "State: closed (not_planned)"},
+		{state: "closed", want: "State: closed"},
+	}
+
+	for _, tt := range tests {
+		if got := stateLine(Issue{State: tt.state, StateReason: tt.reason}); got != tt.want {
+			t.Errorf("stateLine(%s, %q) = %q, want %q", tt.state, tt.reason, got, tt.want)
+		}
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		max  int
+		want string
+	}{
+		{name: "short", in: "Looks good.", max: 80, want: "Looks good."},
+		{name: "newlines collapsed", in: "First line\n\n  second line", max: 80, want: "First line second line"},
+		{name: "cut with ellipsis", in: "abcdefghij", max: 5, want: "abcde…"},
+		{name: "cut on rune boundary", in: "日本語のテキスト", max: 3, want: "日本語…"},
+		{name: "space before the cut trimmed", in: "abcd efgh", max: 5, want: "abcd…"},
+		{name: "exactly max", in: "abcde", max: 5, want: "abcde"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := excerpt(tt.in, tt.max); got != tt.want {
+				t.Errorf("excerpt(%q, %d) = %q, want %q", tt.in, tt.max, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteCompact(t *testing.T) {
+	created := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	comments := []Comment{
+		{User: User{Login: "alice"}, Body: "Short.", DateTime: created},
+		{User: User{Login: "bob"}, Body: "Line one\nline two\n" + strings.Repeat("x", 100), DateTime: created},
+		{Body: "Deleted account.", DateTime: created},
+	}
+
+	var out strings.Builder
+	err := writeCompact(&out, comments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != len(comments) {
+		t.Fatalf("got %d lines, want one per comment (%d):\n%s", len(lines), len(comments), out.String())
+	}
+	tests := []struct {
+		line   string
+		prefix string
+		suffix string
+	}{
+		{line: lines[0], prefix: "[1] @alice " + formatTime(created) + ": ", suffix: "Short."},
+		{line: lines[1], prefix: "[2] @bob " + formatTime(created) + ": Line one line two x", suffix: "x…"},
+		{line: lines[2], prefix: "[3] @(ghost) ", suffix: "Deleted account."},
+	}
+	for _, tt := range tests {
+		if !strings.HasPrefix(tt.line, tt.prefix) || !strings.HasSuffix(tt.line, tt.suffix) {
+			t.Errorf("line = %q, want it to start with %q and end with %q", tt.line, tt.prefix, tt.suffix)
+		}
+	}
+	if body := strings.SplitN(lines[1], ": ", 2)[1]; utf8.RuneCountInString(body) != compactWidth+1 {
+		t.Errorf("excerpt has %d characters, want %d and the ellipsis", utf8.RuneCountInString(body)-1, compactWidth)
+	}
+}
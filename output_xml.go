@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Root element of the XML output
+type xmlThread struct {
+	XMLName  xml.Name     `xml:"thread"`
+	Commit   string       `xml:"commit,attr,omitempty"`
+	Issue    *xmlIssue    `xml:"issue,omitempty"`
+	Comments []xmlComment `xml:"comment"`
+}
+
+// Issue as written in the XML output
+type xmlIssue struct {
+	ID        int64  `xml:"id,attr,omitempty"`
+	Title     string `xml:"title,attr"`
+	Author    string `xml:"author,attr"`
+	CreatedAt string `xml:"date,attr"`
+	UpdatedAt string `xml:"updated,attr,omitempty"`
+
+	State       string `xml:"state,attr,omitempty"`
+	StateReason string `xml:"state_reason,attr,omitempty"`
+
+	Body string `xml:",chardata"`
+}
+
+// Comment as written in the XML output
+type xmlComment struct {
+	ID        int64  `xml:"id,attr,omitempty"`
+	Author    string `xml:"author,attr"`
+	CreatedAt string `xml:"date,attr"`
+	UpdatedAt string `xml:"updated,attr,omitempty"`
+	Path      string `xml:"path,attr,omitempty"`
+	Body      string `xml:",chardata"`
+}
+
+// writeXML writes the issue (or commit) and its comments as a single XML
+// document. Bodies are escaped by encoding/xml.
+func writeXML(out io.Writer, issue *Issue, commitSHA string, comments []Comment) error {
+	document := xmlThread{Commit: commitSHA}
+	if issue != nil {
+		document.Issue = &xmlIssue{
+			ID:        issue.ID,
+			Title:     issue.Title,
+			Author:    issue.User.Login,
+			CreatedAt: issue.DateTime.In(displayLocation).Format(time.RFC3339),
+			UpdatedAt: formatOptionalTime(issue.UpdatedAt),
+
+			State:       issue.State,
+			StateReason: issue.StateReason,
+
+			Body: issue.Body,
+		}
+	}
+	for _, comment := range comments {
+		document.Comments = append(document.Comments, xmlComment{
+			ID:        comment.ID,
+			Author:    comment.User.Login,
+			CreatedAt: comment.DateTime.In(displayLocation).Format(time.RFC3339),
+			UpdatedAt: formatOptionalTime(comment.UpdatedAt),
+			Path:      comment.Path,
+			Body:      comment.Body,
+		})
+	}
+
+	var documentXML []byte
+	var err error
+	if prettyFlag {
+		documentXML, err = xml.MarshalIndent(document, "", strings.Repeat(" ", indentFlag))
+	} else {
+		documentXML, err = xml.Marshal(document)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	_, err = io.WriteString(out, xml.Header+string(documentXML)+"\n")
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteXML(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	issue := &Issue{ID: 7, Title: `Crash with "quotes"`, Body: "a < b && c > d", User: User{Login: "alice"}, DateTime: created}
+	comments := []Comment{{ID: 1, User: User{Login: "bob"}, Body: "<script>alert(1)</script>", DateTime: created}}
+
+	tests := []struct {
+		name      string
+		issue     *Issue
+		commitSHA string
+		want      string
+	}{
+		{
+			name:  "issue",
+			issue: issue,
+			want:  `<thread><issue id="7" title="Crash with &#34;quotes&#34;" author="alice" date="2024-03-01T12:30:00Z">a &lt; b &amp;&amp; c &gt; d</issue><comment id="1" author="bob" date="2024-03-01T12:30:00Z">&lt;script&gt;alert(1)&lt;/script&gt;</comment></thread>`,
+		},
+		{
+			name:      "commit",
+			commitSHA: "abc123",
+			want:      `<thread commit="abc123"><comment id="1" author="bob" date="2024-03-01T12:30:00Z">&lt;script&gt;alert(1)&lt;/script&gt;</comment></thread>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &prettyFlag, false)
+
+			var out strings.Builder
+			err := writeXML(&out, tt.issue, tt.commitSHA, comments)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := xml.Header + tt.want + "\n"
+			if out.String() != want {
+				t.Errorf("writeXML() =\n%s\nwant\n%s", out.String(), want)
+			}
+
+			// What was escaped reads back as it was
+			var thread xmlThread
+			err = xml.Unmarshal([]byte(out.String()), &thread)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if thread.Comments[0].Body != comments[0].Body {
+				t.Errorf("comment body reads back as %q", thread.Comments[0].Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// How much one login took part in the fetched threads, for --participants-csv
+type participation struct {
+	comments int
+	issues   int
+}
+
+// participants adds up the comments of every fetched thread by login.
+type participants map[string]*participation
+
+// add counts the comments of one thread, and the thread once for each of
+// their authors.
+func (p participants) add(comments []Comment) {
+	p.addCounts(commentsByAuthor(nil, comments))
+}
+
+// addCounts takes the comments of one thread already counted by login, and
+// counts the thread once for each of those logins.
+func (p participants) addCounts(counts map[string]int) {
+	for login, n := range counts {
+		entry := p[login]
+		if entry == nil {
+			entry = &participation{}
+			p[login] = entry
+		}
+		entry.comments += n
+		entry.issues++
+	}
+}
+
+// commentsByAuthor adds the comments to the counts by login, starting new
+// counts when counts is nil.
+func commentsByAuthor(counts map[string]int, comments []Comment) map[string]int {
+	if counts == nil {
+		counts = make(map[string]int)
+	}
+	for _, comment := range comments {
+		counts[displayLogin(comment.User.Login)]++
+	}
+	return counts
+}
+
+// writeCSV writes a row per login with its comment and issue counts, the
+// most active participants first.
+func (p participants) writeCSV(filePath string) error {
+	logins := make([]string, 0, len(p))
+	for login := range p {
+		logins = append(logins, login)
+	}
+	sort.Slice(logins, func(a, b int) bool {
+		if p[logins[a]].comments != p[logins[b]].comments {
+			return p[logins[a]].comments > p[logins[b]].comments
+		}
+		return logins[a] < logins[b]
+	})
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to create participants file: %w", err)
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	w.Write([]string{"login", "comments", "issues"})
+	for _, login := range logins {
+		w.Write([]string{login, strconv.Itoa(p[login].comments), strconv.Itoa(p[login].issues)})
+	}
+	w.Flush()
+
+	err = w.Error()
+	if err != nil {
+		return fmt.Errorf("failed to write participants file: %w", err)
+	}
+
+	return file.Close()
+}
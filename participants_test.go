@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParticipantsWriteCSV(t *testing.T) {
+	byLogins := func(logins ...string) []Comment {
+		comments := make([]Comment, len(logins))
+		for i, login := range logins {
+			comments[i].User.Login = login
+		}
+		return comments
+	}
+
+	tests := []struct {
+		name    string
+		threads [][]Comment
+		paged   bool // counted a comment at a time, like streamed output
+		want    string
+	}{
+		{
+			name: "no comments",
+			want: "login,comments,issues\n",
+		},
+		{
+			name:    "one thread",
+			threads: [][]Comment{byLogins("bob", "alice", "bob")},
+			want:    "login,comments,issues\nbob,2,1\nalice,1,1\n",
+		},
+		{
+			name:    "several threads",
+			threads: [][]Comment{byLogins("bob", "alice"), byLogins("alice", "alice"), byLogins("carol")},
+			want:    "login,comments,issues\nalice,3,2\nbob,1,1\ncarol,1,1\n",
+		},
+		{
+			name:    "several threads, page by page",
+			threads: [][]Comment{byLogins("bob", "alice"), byLogins("alice", "alice"), byLogins("carol")},
+			paged:   true,
+			want:    "login,comments,issues\nalice,3,2\nbob,1,1\ncarol,1,1\n",
+		},
+		{
+			name:    "deleted users",
+			threads: [][]Comment{byLogins("", "bob"), byLogins("")},
+			want:    "login,comments,issues\n(ghost),2,2\nbob,1,1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := make(participants)
+			for _, comments := range tt.threads {
+				if !tt.paged {
+					p.add(comments)
+					continue
+				}
+				var thread savedThread
+				for i := range comments {
+					thread.add(comments[i : i+1])
+				}
+				p.addCounts(thread.authors)
+			}
+
+			filePath := filepath.Join(t.TempDir(), "participants.csv")
+			err := p.writeCSV(filePath)
+			if err != nil {
+				t.Fatalf("writeCSV() error = %v", err)
+			}
+			got, err := os.ReadFile(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("writeCSV() wrote\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParticipantsWriteCSVError(t *testing.T) {
+	p := make(participants)
+	err := p.writeCSV(filepath.Join(t.TempDir(), "missing", "participants.csv"))
+	if err == nil {
+		t.Error("writeCSV() to a missing directory succeeded")
+	}
+}
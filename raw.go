@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// API data of a thread as saved with --save-raw and read back with --from-file
+type rawThread struct {
+	Issue          *Issue       `json:"issue,omitempty"`
+	PullRequest    *PullRequest `json:"pull_request_details,omitempty"`
+	ReviewComments []Comment    `json:"review_comments,omitempty"`
+	Comments       []Comment    `json:"comments"`
+}
+
+// saveRaw writes the issue and comments as GitHub returned them, before
+// any filtering.
+func saveRaw(path string, issue Issue, comments []Comment) error {
+	raw := rawThread{PullRequest: issue.PullRequest, ReviewComments: issue.ReviewComments, Comments: comments}
+	if typeFlag != "commit" && includeIssueFlag {
+		raw.Issue = &issue
+	}
+
+	data, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal raw data: %w", err)
+	}
+
+	err = writeFileAtomic(path, append(data, '\n'))
+	if err != nil {
+		return fmt.Errorf("failed to save raw data: %w", err)
+	}
+	return nil
+}
+
+// loadRaw reads a file saved with --save-raw.
+func loadRaw(path string) (*rawThread, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var raw rawThread
+	err = json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &raw, nil
+}
+
+// thread returns copies of the saved issue and comments, so filtering them
+// leaves the originals alone.
+func (r *rawThread) thread() (Issue, []Comment) {
+	var issue Issue
+	if r.Issue != nil {
+		issue = *r.Issue
+	}
+	issue.PullRequest = r.PullRequest
+	issue.ReviewComments = append([]Comment(nil), r.ReviewComments...)
+
+	return issue, append([]Comment(nil), r.Comments...)
+}
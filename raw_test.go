@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveRawRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+	}{
+		{name: "text", format: "text"},
+		{name: "json", format: "json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/repos/o/r/issues/1":
+					fmt.Fprint(w, `{"id":10,"title":"Crash on start","body":"It crashes.","user":{"login":"alice"},"created_at":"2024-03-01T09:30:00Z"}`)
+				case "/repos/o/r/issues/1/comments":
+					fmt.Fprint(w, `[{"id":1,"body":"Same here.","user":{"login":"bob"},"created_at":"2024-03-02T10:00:00Z"}]`)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+			dir := t.TempDir()
+			rawPath := filepath.Join(dir, "raw.json")
+			setFlag(t, &apiBaseURL, server.URL)
+			setFlag(t, &typeFlag, "issue")
+			setFlag(t, &includeIssueFlag, true)
+			setFlag(t, &formatFlag, tt.format)
+			setFlag(t, &saveRawFlag, rawPath)
+
+			// Fetch once, saving what the API returned
+			online := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats()}
+			_, err := saveThread(online, "o", "r", "1", filepath.Join(dir, "online"), nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Then render again from the saved file without a client
+			server.Close()
+			raw, err := loadRaw(rawPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			offline := &fetcher{ctx: context.Background(), stats: newRunStats(), offline: raw}
+			_, err = saveThread(offline, "o", "r", "1", filepath.Join(dir, "offline"), nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want, _ := os.ReadFile(filepath.Join(dir, "online"))
+			got, _ := os.ReadFile(filepath.Join(dir, "offline"))
+			if string(got) != string(want) {
+				t.Errorf("offline output differs:\n%s\nwant:\n%s", got, want)
+			}
+			if !strings.Contains(string(got), "Same here.") {
+				t.Errorf("comment is missing:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestLoadRawErrors(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.json")
+	err := os.WriteFile(invalid, []byte("{not json"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "missing", path: filepath.Join(dir, "missing.json"), want: "failed to read"},
+		{name: "invalid", path: invalid, want: "failed to parse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadRaw(tt.path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("loadRaw() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
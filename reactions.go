@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Reactions lists are fetched for this many comments at a time
+const reactionWorkers = 4
+
+// Reaction contents in the order GitHub shows them, with their emoji
+var reactionEmoji = []struct {
+	content string
+	emoji   string
+}{
+	{"+1", "👍"},
+	{"-1", "👎"},
+	{"laugh", "😄"},
+	{"hooray", "🎉"},
+	{"confused", "😕"},
+	{"heart", "❤️"},
+	{"rocket", "🚀"},
+	{"eyes", "👀"},
+}
+
+// A single reaction as listed by the reactions endpoints
+type reaction struct {
+	Content string `json:"content"`
+	User    User   `json:"user"`
+}
+
+// Preview media type older GitHub Enterprise servers need before they
+// include reactions in comments or list them
+const reactionsPreview = "application/vnd.github.squirrel-girl-preview+json"
+
+// acceptHeader is the Accept header for REST requests: the --body-format
+// media type, plus the reactions preview on GitHub Enterprise until the
+// server turns it down.
+func (f *fetcher) acceptHeader() string {
+	if apiBaseURL == defaultBaseURL || schemaFlag != "github" || f.noReactions.Load() {
+		return f.accept
+	}
+	if f.accept == "" {
+		return reactionsPreview
+	}
+	return f.accept + ", " + reactionsPreview
+}
+
+// fetchReactors fetches who reacted to a comment, as logins by reaction content.
+func (f *fetcher) fetchReactors(url string) (map[string][]string, error) {
+	reactions, err := fetchPaged[reaction](f, url)
+	if err != nil {
+		return nil, err
+	}
+
+	reactors := make(map[string][]string)
+	for _, r := range reactions {
+		reactors[r.Content] = append(reactors[r.Content], displayLogin(r.User.Login))
+	}
+	return reactors, nil
+}
+
+// fetchDetailedReactions fills in the reactors of every comment that has
+// reactions, a few comments at a time. Commit comments live on their own
+// endpoint. Once a request fails no new ones are started.
+func (f *fetcher) fetchDetailedReactions(owner, repo string, comments []Comment, commit bool) error {
+	endpoint := "issues/comments"
+	if commit {
+		endpoint = "comments"
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	slots := make(chan struct{}, reactionWorkers)
+
+	for i := range comments {
+		if comments[i].Reactions.TotalCount == 0 || comments[i].ID == 0 {
+			continue
+		}
+
+		slots <- struct{}{}
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			<-slots
+			break
+		}
+
+		wg.Add(1)
+		go func(comment *Comment) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			url := fmt.Sprintf("%s/repos/%s/%s/%s/%d/reactions", apiBaseURL, owner, repo, endpoint, comment.ID)
+			reactors, err := f.fetchReactors(url)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			comment.Reactors = reactors
+		}(&comments[i])
+	}
+	wg.Wait()
+
+	if errors.Is(firstErr, errInterrupted) || f.ctx.Err() != nil {
+		return errInterrupted
+	}
+	if firstErr != nil {
+		return fmt.Errorf("failed to fetch reactions: %w", permissionError(firstErr, owner, repo))
+	}
+	return nil
+}
+
+// reactorsLine lists who reacted to a comment, grouped by emoji, as in
+// "Reactions: 👍 alice, bob; 🎉 carol".
+func reactorsLine(reactors map[string][]string) string {
+	var groups []string
+	for _, r := range reactionEmoji {
+		if logins := reactors[r.content]; len(logins) > 0 {
+			groups = append(groups, r.emoji+" "+strings.Join(logins, ", "))
+		}
+	}
+	return "Reactions: " + strings.Join(groups, "; ")
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAcceptHeader(t *testing.T) {
+	tests := []struct {
+		name        string
+		baseURL     string
+		schema      string
+		accept      string
+		noReactions bool
+		want        string
+	}{
+		{name: "github.com", baseURL: defaultBaseURL, schema: "github", accept: "application/vnd.github.full+json", want: "application/vnd.github.full+json"},
+		{name: "enterprise", baseURL: "https://ghe.example.com/api/v3", schema: "github", want: reactionsPreview},
+		{name: "enterprise with body format", baseURL: "https://ghe.example.com/api/v3", schema: "github", accept: "application/vnd.github.text+json", want: "application/vnd.github.text+json, " + reactionsPreview},
+		{name: "preview rejected", baseURL: "https://ghe.example.com/api/v3", schema: "github", noReactions: true, want: ""},
+		{name: "gitea", baseURL: "https://gitea.example.com/api/v1", schema: "gitea", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &apiBaseURL, tt.baseURL)
+			setFlag(t, &schemaFlag, tt.schema)
+			f := &fetcher{accept: tt.accept}
+			f.noReactions.Store(tt.noReactions)
+
+			if got := f.acceptHeader(); got != tt.want {
+				t.Errorf("acceptHeader() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetJSONDropsRejectedPreview(t *testing.T) {
+	var accepts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accepts = append(accepts, r.Header.Get("Accept"))
+		if strings.Contains(r.Header.Get("Accept"), reactionsPreview) {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		fmt.Fprint(w, `{"id":1}`)
+	}))
+	defer server.Close()
+	setFlag(t, &apiBaseURL, server.URL)
+	setFlag(t, &schemaFlag, "github")
+
+	f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats()}
+	for i := 0; i < 2; i++ {
+		var v struct{ ID int64 }
+		err := f.getJSON(server.URL+"/repos/o/r/issues/1", &v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.ID != 1 {
+			t.Errorf("decoded id %d, want 1", v.ID)
+		}
+	}
+
+	// The preview is asked for once, then left out for the rest of the run
+	want := []string{reactionsPreview, "", ""}
+	if strings.Join(accepts, "|") != strings.Join(want, "|") {
+		t.Errorf("Accept headers = %q, want %q", accepts, want)
+	}
+	if !f.noReactions.Load() {
+		t.Error("noReactions isn't set after the preview was rejected")
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import "regexp"
+
+// Placeholder written in place of anything that looks like a secret
+const redactedPlaceholder = "***REDACTED***"
+
+// Patterns for credentials that commonly get pasted into issues by mistake
+var secretPatterns = []*regexp.Regexp{
+	// GitHub personal access, OAuth, user-to-server, server-to-server and refresh tokens
+	regexp.MustCompile(`gh[pousr]_[A-Za-z0-9]{36}`),
+	// GitHub fine-grained personal access tokens
+	regexp.MustCompile(`github_pat_[A-Za-z0-9_]{82}`),
+	// AWS access key IDs
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	// AWS secret access keys assigned to a well-known variable name
+	regexp.MustCompile(`(?i)aws_secret_access_key\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}["']?`),
+}
+
+// redactSecrets replaces anything matching secretPatterns with the placeholder
+// and returns the redacted text along with the number of replacements made.
+func redactSecrets(s string) (string, int) {
+	count := 0
+	for _, pattern := range secretPatterns {
+		s = pattern.ReplaceAllStringFunc(s, func(string) string {
+			count++
+			return redactedPlaceholder
+		})
+	}
+	return s, count
+}
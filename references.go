@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+)
+
+// #123 or owner/repo#123 references, at the start of a word
+var referencePattern = regexp.MustCompile(`(^|[\s(\[])((?:([\w.-]+)/([\w.-]+))?#(\d+))\b`)
+
+// Shown in place of the title of a reference that can't be looked up
+const missingReference = "deleted or inaccessible"
+
+// issueTitle returns the title of an issue or PR, looking each one up once.
+// Failed lookups are logged and remembered as having no title, except for
+// issues that are gone or hidden, which are counted and marked as such.
+func (f *fetcher) issueTitle(owner, repo, number string) string {
+	key := owner + "/" + repo + "#" + number
+	if title, ok := f.titles[key]; ok {
+		return title
+	}
+
+	var issue Issue
+	err := f.getJSON(issueURL(owner, repo, number), &issue)
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusGone):
+		log.Printf("Reference %s points to a deleted or inaccessible issue", key)
+		f.stats.missingRefs++
+		issue.Title = missingReference
+	case err != nil:
+		log.Printf("Leaving %s unresolved: %s", key, err)
+	}
+
+	f.titles[key] = issue.Title
+	return issue.Title
+}
+
+// resolveReferences adds the title after every issue or PR reference in s,
+// as in "#123 (Fix login bug)". Bare numbers refer to owner/repo.
+func (f *fetcher) resolveReferences(owner, repo, s string) string {
+	if f.titles == nil {
+		f.titles = make(map[string]string)
+	}
+
+	return referencePattern.ReplaceAllStringFunc(s, func(match string) string {
+		groups := referencePattern.FindStringSubmatch(match)
+		refOwner, refRepo := owner, repo
+		if groups[3] != "" {
+			refOwner, refRepo = groups[3], groups[4]
+		}
+
+		title := f.issueTitle(refOwner, refRepo, groups[5])
+		if title == "" {
+			return match
+		}
+		return match + " (" + title + ")"
+	})
+}
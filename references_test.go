@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolveReferences(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/o/r/issues/1":
+			fmt.Fprint(w, `{"title":"Fix login bug"}`)
+		case "/repos/other/lib/issues/7":
+			fmt.Fprint(w, `{"title":"Bump version"}`)
+		case "/repos/o/r/issues/2":
+			w.WriteHeader(http.StatusNotFound)
+		case "/repos/o/r/issues/3":
+			w.WriteHeader(http.StatusGone)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	setFlag(t, &apiBaseURL, server.URL)
+
+	tests := []struct {
+		name        string
+		in          string
+		want        string
+		wantMissing int
+	}{
+		{name: "same repository", in: "Fixed by #1.", want: "Fixed by #1 (Fix login bug)."},
+		{name: "other repository", in: "See other/lib#7", want: "See other/lib#7 (Bump version)"},
+		{name: "in parentheses", in: "(#1)", want: "(#1 (Fix login bug))"},
+		{name: "several", in: "#1 and other/lib#7", want: "#1 (Fix login bug) and other/lib#7 (Bump version)"},
+		{name: "not a reference", in: "color#1 and C#", want: "color#1 and C#"},
+		{name: "lookup fails", in: "See #99", want: "See #99"},
+		{name: "deleted", in: "See #2", want: "See #2 (deleted or inaccessible)", wantMissing: 1},
+		{name: "gone", in: "#3 and #3", want: "#3 (deleted or inaccessible) and #3 (deleted or inaccessible)", wantMissing: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats()}
+			if got := f.resolveReferences("o", "r", tt.in); got != tt.want {
+				t.Errorf("resolveReferences(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if f.stats.missingRefs != tt.wantMissing {
+				t.Errorf("missingRefs = %d, want %d", f.stats.missingRefs, tt.wantMissing)
+			}
+		})
+	}
+}
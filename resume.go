@@ -0,0 +1,197 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// How far each thread got, kept in the --resume file until the thread has
+// been fetched in full
+type resumeState struct {
+	path    string
+	Threads map[string]*resumeThread `json:"threads"`
+}
+
+// Progress of one thread: the next page to fetch, and the file the pages
+// before it were appended to, one JSON array per line
+type resumeThread struct {
+	Next  string `json:"next"`
+	Pages string `json:"pages"`
+}
+
+// loadResume reads the resume file, starting afresh when it doesn't exist yet.
+func loadResume(path string) (*resumeState, error) {
+	state := &resumeState{path: path, Threads: make(map[string]*resumeThread)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resume file: %w", err)
+	}
+
+	err = json.Unmarshal(data, state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse resume file: %w", err)
+	}
+	if state.Threads == nil {
+		state.Threads = make(map[string]*resumeThread)
+	}
+	return state, nil
+}
+
+// save writes the progress back, or removes the file once nothing is left
+// to resume.
+func (s *resumeState) save() error {
+	if len(s.Threads) == 0 {
+		err := os.Remove(s.path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove resume file: %w", err)
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to marshal resume state: %w", err)
+	}
+
+	err = writeFileAtomic(s.path, append(data, '\n'))
+	if err != nil {
+		return fmt.Errorf("failed to save resume file: %w", err)
+	}
+	return nil
+}
+
+// pagesPath names the file the pages of a thread are appended to, next to
+// the resume file.
+func (s *resumeState) pagesPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return fmt.Sprintf("%s.%x.pages", s.path, sum[:8])
+}
+
+// appendPage adds a fetched page to the end of the pages file.
+func appendPage(path string, page []Comment) error {
+	data, err := json.Marshal(page)
+	if err != nil {
+		return fmt.Errorf("failed to marshal comments: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open resume pages: %w", err)
+	}
+	_, err = file.Write(append(data, '\n'))
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save resume pages: %w", err)
+	}
+	return nil
+}
+
+// readPages reads back the pages appended so far. A page cut short by a
+// crash is dropped from the file, as its URL was never saved as done and it
+// is fetched again.
+func readPages(path string) ([]Comment, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resume pages: %w", err)
+	}
+	defer file.Close()
+
+	var comments []Comment
+	decoder := json.NewDecoder(file)
+	for {
+		var page []Comment
+		err = decoder.Decode(&page)
+		if err == io.EOF {
+			return comments, nil
+		}
+		if err != nil {
+			break
+		}
+		comments = append(comments, page...)
+	}
+
+	err = os.Truncate(path, decoder.InputOffset())
+	if err != nil {
+		return nil, fmt.Errorf("failed to repair resume pages: %w", err)
+	}
+	return comments, nil
+}
+
+// fetchCommentPagesResumable fetches the comments at url like
+// fetchCommentPages, saving progress after every page so that a failed run
+// picks up where it stopped. Each page is appended to a file of its own, and
+// only the next page's URL goes into the resume file. Comments already
+// fetched are never added twice, even if a page was fetched again or new
+// comments shifted the pages in between.
+func (f *fetcher) fetchCommentPagesResumable(url, key string) ([]Comment, error) {
+	thread, ok := f.resume.Threads[key]
+	var fetched []Comment
+	if ok {
+		var err error
+		fetched, err = readPages(thread.Pages)
+		if err != nil {
+			return nil, err
+		}
+		statusf("Resuming %s after %d comment(s) fetched earlier\n", key, len(fetched))
+	} else {
+		// Pages left by a run that stopped before its first checkpoint are stale
+		thread = &resumeThread{Next: firstPageURL(url), Pages: f.resume.pagesPath(key)}
+		err := os.Remove(thread.Pages)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to remove resume pages: %w", err)
+		}
+	}
+
+	checkpoint := func(next string, page []Comment) error {
+		err := appendPage(thread.Pages, page)
+		if err != nil {
+			return err
+		}
+		thread.Next = next
+		f.resume.Threads[key] = thread
+		return f.resume.save()
+	}
+	comments, err := fetchPagesFrom(f, thread.Next, fetched, checkpoint)
+	if err != nil && !errors.Is(err, errInterrupted) {
+		return nil, err
+	}
+	if err == nil {
+		delete(f.resume.Threads, key)
+		saveErr := f.resume.save()
+		if saveErr != nil {
+			return nil, saveErr
+		}
+		removeErr := os.Remove(thread.Pages)
+		if removeErr != nil && !errors.Is(removeErr, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to remove resume pages: %w", removeErr)
+		}
+	}
+
+	seen := make(map[int64]bool)
+	unique := comments[:0]
+	for _, comment := range comments {
+		if seen[comment.ID] {
+			continue
+		}
+		seen[comment.ID] = true
+		comment.Body = selectBody(comment.Body, comment.BodyText, comment.BodyHTML)
+		unique = append(unique, comment)
+	}
+
+	return unique, err
+}
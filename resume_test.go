@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFetchCommentsResume(t *testing.T) {
+	var failing bool
+	var queries []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query().Get("page"))
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/o/r/issues/1/comments?per_page=100&page=2>; rel="next"`, server.URL))
+			fmt.Fprint(w, `[{"id":1},{"id":2}]`)
+		case "2":
+			if failing {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			// A comment was deleted in between, so comment 2 moved onto this page
+			fmt.Fprint(w, `[{"id":2},{"id":3}]`)
+		}
+	}))
+	defer server.Close()
+	setFlag(t, &apiBaseURL, server.URL)
+	setFlag(t, &schemaFlag, "github")
+	setFlag(t, &graphqlFlag, false)
+	setFlag(t, &maxRetriesFlag, 0)
+	path := filepath.Join(t.TempDir(), "resume.json")
+
+	tests := []struct {
+		name        string
+		failing     bool
+		wantErr     bool
+		wantQueries []string
+		wantIDs     []int64
+		wantFile    bool
+	}{
+		{name: "fails on page 2", failing: true, wantErr: true, wantQueries: []string{"", "2"}, wantIDs: []int64{}, wantFile: true},
+		{name: "resumes from page 2", failing: false, wantQueries: []string{"2"}, wantIDs: []int64{1, 2, 3}, wantFile: false},
+	}
+
+	// The steps run in order, each as a new run reading the resume file
+	for _, tt := range tests {
+		failing = tt.failing
+		queries = nil
+
+		resume, err := loadResume(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats(), resume: resume}
+		comments, err := f.fetchComments("o", "r", "1")
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: fetchComments() error = %v, want an error: %v", tt.name, err, tt.wantErr)
+		}
+
+		if !reflect.DeepEqual(queries, tt.wantQueries) {
+			t.Errorf("%s: fetched pages %q, want %q", tt.name, queries, tt.wantQueries)
+		}
+		if got := commentIDs(comments); !reflect.DeepEqual(got, tt.wantIDs) {
+			t.Errorf("%s: comment IDs = %v, want %v", tt.name, got, tt.wantIDs)
+		}
+		if _, err := os.Stat(path); (err == nil) != tt.wantFile {
+			t.Errorf("%s: resume file kept = %v, want %v", tt.name, err == nil, tt.wantFile)
+		}
+		if _, err := os.Stat(resume.pagesPath("o/r#1")); (err == nil) != tt.wantFile {
+			t.Errorf("%s: pages file kept = %v, want %v", tt.name, err == nil, tt.wantFile)
+		}
+
+		// Only the cursor goes into the resume file, the comments are in the pages file
+		if tt.wantFile {
+			data, _ := os.ReadFile(path)
+			var saved map[string]map[string]map[string]string
+			err = json.Unmarshal(data, &saved)
+			if err != nil {
+				t.Fatalf("%s: resume file %s: %v", tt.name, data, err)
+			}
+			want := server.URL + "/repos/o/r/issues/1/comments?per_page=100&page=2"
+			if thread := saved["threads"]["o/r#1"]; len(thread) != 2 || thread["next"] != want {
+				t.Errorf("%s: resume file holds %v, want only the next page %s and the pages file", tt.name, thread, want)
+			}
+		}
+	}
+}
+
+func TestReadPages(t *testing.T) {
+	tests := []struct {
+		name     string
+		pages    string
+		wantIDs  []int64
+		wantFile string
+	}{
+		{name: "missing", wantIDs: []int64{}},
+		{name: "whole pages", pages: "[{\"id\":1},{\"id\":2}]\n[{\"id\":3}]\n", wantIDs: []int64{1, 2, 3}, wantFile: "[{\"id\":1},{\"id\":2}]\n[{\"id\":3}]\n"},
+		{name: "page cut short", pages: "[{\"id\":1}]\n[{\"id\":2},{\"i", wantIDs: []int64{1}, wantFile: "[{\"id\":1}]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "resume.json.pages")
+			if tt.pages != "" {
+				err := os.WriteFile(path, []byte(tt.pages), 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			comments, err := readPages(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := commentIDs(comments); !reflect.DeepEqual(got, tt.wantIDs) {
+				t.Errorf("readPages() IDs = %v, want %v", got, tt.wantIDs)
+			}
+
+			// Later pages are appended after what could be read back
+			got, _ := os.ReadFile(path)
+			if string(got) != tt.wantFile {
+				t.Errorf("pages file = %q, want %q", got, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestLoadResume(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.json")
+	err := os.WriteFile(invalid, []byte("{not json"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty.json")
+	err = os.WriteFile(empty, []byte("{}"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{name: "missing file starts afresh", path: filepath.Join(dir, "missing.json")},
+		{name: "no threads", path: empty},
+		{name: "invalid", path: invalid, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := loadResume(tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("loadResume() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if state.Threads == nil || len(state.Threads) != 0 {
+				t.Errorf("threads = %v, want none", state.Threads)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// fetchReviewComments fetches the comments left on the diff of a pull request.
+func (f *fetcher) fetchReviewComments(owner, repo, number string) ([]Comment, error) {
+	comments, err := f.fetchCommentPages(fmt.Sprintf("%s/repos/%s/%s/pulls/%s/comments", apiBaseURL, owner, repo, number))
+	if errors.Is(err, errInterrupted) {
+		return comments, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch review comments: %w", err)
+	}
+	return comments, nil
+}
+
+// Query for a page of review threads and the comment each one starts with
+const reviewThreadsQuery = `query($owner: String!, $repo: String!, $number: Int!, $cursor: String) {
+  repository(owner: $owner, name: $repo) {
+    pullRequest(number: $number) {
+      reviewThreads(first: 100, after: $cursor) {
+        nodes {
+          isResolved
+          comments(first: 1) { nodes { databaseId } }
+        }
+        pageInfo { hasNextPage endCursor }
+      }
+    }
+  }
+}`
+
+// fetchResolvedThreads finds the review threads of a pull request marked as
+// resolved, by the ID of the comment starting each of them.
+func (f *fetcher) fetchResolvedThreads(owner, repo, number string) (map[int64]bool, error) {
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return nil, usageErrorf("invalid pull request number %q: %w", number, err)
+	}
+
+	resolved := make(map[int64]bool)
+	var cursor *string
+	for {
+		var data struct {
+			Repository struct {
+				PullRequest *struct {
+					ReviewThreads struct {
+						Nodes []struct {
+							IsResolved bool `json:"isResolved"`
+							Comments   struct {
+								Nodes []struct {
+									DatabaseID int64 `json:"databaseId"`
+								} `json:"nodes"`
+							} `json:"comments"`
+						} `json:"nodes"`
+						PageInfo struct {
+							HasNextPage bool   `json:"hasNextPage"`
+							EndCursor   string `json:"endCursor"`
+						} `json:"pageInfo"`
+					} `json:"reviewThreads"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		}
+
+		variables := map[string]interface{}{"owner": owner, "repo": repo, "number": n, "cursor": cursor}
+		err = f.postGraphQL(reviewThreadsQuery, variables, &data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch review threads: %w", err)
+		}
+		pr := data.Repository.PullRequest
+		if pr == nil {
+			return nil, fmt.Errorf("pull request #%d not found in %s/%s", n, owner, repo)
+		}
+
+		for _, thread := range pr.ReviewThreads.Nodes {
+			if thread.IsResolved && len(thread.Comments.Nodes) > 0 {
+				resolved[thread.Comments.Nodes[0].DatabaseID] = true
+			}
+		}
+
+		if !pr.ReviewThreads.PageInfo.HasNextPage {
+			return resolved, nil
+		}
+		endCursor := pr.ReviewThreads.PageInfo.EndCursor
+		cursor = &endCursor
+	}
+}
+
+// dropResolvedThreads keeps the review comments of the threads that are
+// still open.
+func dropResolvedThreads(comments []Comment, resolved map[int64]bool) []Comment {
+	var open []Comment
+	for _, thread := range groupReviewThreads(comments) {
+		if resolved[thread[0].ID] {
+			continue
+		}
+		for _, comment := range thread {
+			open = append(open, comment.Comment)
+		}
+	}
+	return open
+}
+
+// One review comment placed in its thread
+type threadedComment struct {
+	Comment
+	depth int // 0 for the comment starting the thread
+}
+
+// groupReviewThreads orders review comments into threads by following
+// in_reply_to_id, each thread starting with its root comment and followed
+// by the replies in posting order.
+func groupReviewThreads(comments []Comment) [][]threadedComment {
+	byID := make(map[int64]Comment, len(comments))
+	for _, comment := range comments {
+		byID[comment.ID] = comment
+	}
+
+	// Walk up the replies to find the root and how deep each comment sits
+	root := func(comment Comment) (int64, int) {
+		depth := 0
+		for comment.InReplyToID != 0 && depth < len(comments) {
+			parent, ok := byID[comment.InReplyToID]
+			if !ok {
+				break
+			}
+			comment = parent
+			depth++
+		}
+		return comment.ID, depth
+	}
+
+	var threads [][]threadedComment
+	index := make(map[int64]int)
+	for _, comment := range comments {
+		rootID, depth := root(comment)
+		i, ok := index[rootID]
+		if !ok {
+			i = len(threads)
+			index[rootID] = i
+			threads = append(threads, nil)
+		}
+		threads[i] = append(threads[i], threadedComment{Comment: comment, depth: depth})
+	}
+	return threads
+}
+
+// writeReviewThreads writes the review comments grouped in threads, with the
+// replies indented under the comment they answer.
+func writeReviewThreads(out io.Writer, comments []Comment) error {
+	for _, thread := range groupReviewThreads(comments) {
+		first := thread[0]
+		header := "\nReview thread"
+		if first.Path != "" {
+			header += " on " + first.Path
+			if line := first.reviewLine(); line != nil {
+				header += fmt.Sprintf(":%d", *line)
+			}
+		}
+
+		_, err := io.WriteString(out, header+":\n")
+		if err != nil {
+			return fmt.Errorf("failed to write review thread header: %w", err)
+		}
+
+		for _, comment := range thread {
+			indent := strings.Repeat("  ", comment.depth+1)
+			text := fmt.Sprintf("%s%s at %s:\n", indent,
+				colorize(displayAuthor(comment.User), colorCyan), colorize(formatTime(comment.DateTime), colorYellow))
+			for _, line := range strings.Split(comment.Body, "\n") {
+				text += indent + "  " + line + "\n"
+			}
+
+			_, err = io.WriteString(out, text)
+			if err != nil {
+				return fmt.Errorf("failed to write review comment: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// reviewLine is the line of the file a review comment is attached to.
+func (c Comment) reviewLine() *int {
+	if c.Line != nil {
+		return c.Line
+	}
+	return c.Position
+}
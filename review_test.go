@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGroupReviewThreads(t *testing.T) {
+	tests := []struct {
+		name     string
+		comments []Comment
+		want     [][]int64
+		depths   [][]int
+	}{
+		{
+			name:     "replies follow their root",
+			comments: []Comment{{ID: 1}, {ID: 2}, {ID: 3, InReplyToID: 1}, {ID: 4, InReplyToID: 2}, {ID: 5, InReplyToID: 3}},
+			want:     [][]int64{{1, 3, 5}, {2, 4}},
+			depths:   [][]int{{0, 1, 2}, {0, 1}},
+		},
+		{
+			name:     "reply to a missing comment starts its own thread",
+			comments: []Comment{{ID: 7, InReplyToID: 6}, {ID: 8, InReplyToID: 7}},
+			want:     [][]int64{{7, 8}},
+			depths:   [][]int{{0, 1}},
+		},
+		{
+			name:     "no comments",
+			comments: nil,
+			want:     nil,
+			depths:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][]int64
+			var depths [][]int
+			for _, thread := range groupReviewThreads(tt.comments) {
+				var ids []int64
+				var levels []int
+				for _, comment := range thread {
+					ids = append(ids, comment.ID)
+					levels = append(levels, comment.depth)
+				}
+				got = append(got, ids)
+				depths = append(depths, levels)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("threads = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(depths, tt.depths) {
+				t.Errorf("depths = %v, want %v", depths, tt.depths)
+			}
+		})
+	}
+}
+
+func TestWriteReviewThreads(t *testing.T) {
+	line := 12
+	position := 4
+	created := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		comments []Comment
+		want     []string
+	}{
+		{
+			name: "reply indented under its root",
+			comments: []Comment{
+				{ID: 1, Path: "main.go", Line: &line, User: User{Login: "alice"}, Body: "Why this?", DateTime: created},
+				{ID: 2, InReplyToID: 1, User: User{Login: "bob"}, Body: "Because.", DateTime: created},
+			},
+			want: []string{
+				"Review thread on main.go:12:",
+				"  alice at " + formatTime(created) + ":",
+				"    Why this?",
+				"    bob at " + formatTime(created) + ":",
+				"      Because.",
+			},
+		},
+		{
+			name:     "position when the line is unknown",
+			comments: []Comment{{ID: 3, Path: "go.mod", Position: &position, User: User{Login: "carol"}, Body: "ok", DateTime: created}},
+			want:     []string{"Review thread on go.mod:4:"},
+		},
+		{
+			name:     "no path",
+			comments: []Comment{{ID: 4, User: User{Login: "dave"}, Body: "ok", DateTime: created}},
+			want:     []string{"Review thread:"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			err := writeReviewThreads(&out, tt.comments)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want+"\n") {
+					t.Errorf("output is missing %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestFetchReviewCommentsFollowsPages(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/o/r/pulls/1/comments" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/o/r/pulls/1/comments?page=2>; rel="next"`, server.URL))
+			fmt.Fprint(w, `[{"id":1,"path":"main.go"},{"id":2,"in_reply_to_id":1}]`)
+		default:
+			fmt.Fprint(w, `[{"id":3,"in_reply_to_id":2}]`)
+		}
+	}))
+	defer server.Close()
+	setFlag(t, &apiBaseURL, server.URL)
+
+	f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats()}
+	comments, err := f.fetchReviewComments("o", "r", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := commentIDs(comments); !reflect.DeepEqual(got, []int64{1, 2, 3}) {
+		t.Errorf("review comment IDs = %v, want [1 2 3]", got)
+	}
+}
+
+func TestFetchResolvedThreads(t *testing.T) {
+	var cursors []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		cursors = append(cursors, request.Variables["cursor"])
+
+		if request.Variables["cursor"] == nil {
+			fmt.Fprint(w, `{"data":{"repository":{"pullRequest":{"reviewThreads":{
+				"nodes":[
+					{"isResolved":true,"comments":{"nodes":[{"databaseId":1}]}},
+					{"isResolved":false,"comments":{"nodes":[{"databaseId":2}]}}
+				],
+				"pageInfo":{"hasNextPage":true,"endCursor":"c1"}}}}}}`)
+			return
+		}
+		fmt.Fprint(w, `{"data":{"repository":{"pullRequest":{"reviewThreads":{
+			"nodes":[{"isResolved":true,"comments":{"nodes":[{"databaseId":5}]}},{"isResolved":true,"comments":{"nodes":[]}}],
+			"pageInfo":{"hasNextPage":false}}}}}}`)
+	}))
+	defer server.Close()
+	setFlag(t, &apiBaseURL, server.URL)
+
+	f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats()}
+	resolved, err := f.fetchResolvedThreads("o", "r", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[int64]bool{1: true, 5: true}; !reflect.DeepEqual(resolved, want) {
+		t.Errorf("resolved threads = %v, want %v", resolved, want)
+	}
+	if want := []interface{}{nil, "c1"}; !reflect.DeepEqual(cursors, want) {
+		t.Errorf("cursors = %v, want %v", cursors, want)
+	}
+}
+
+func TestDropResolvedThreads(t *testing.T) {
+	comments := []Comment{
+		{ID: 1, Path: "main.go"},
+		{ID: 2, Path: "go.mod"},
+		{ID: 3, InReplyToID: 1},
+		{ID: 4, InReplyToID: 2},
+		{ID: 5, InReplyToID: 3},
+	}
+
+	tests := []struct {
+		name     string
+		resolved map[int64]bool
+		want     []int64
+	}{
+		{name: "one thread resolved", resolved: map[int64]bool{1: true}, want: []int64{2, 4}},
+		{name: "none resolved", resolved: map[int64]bool{}, want: []int64{1, 3, 5, 2, 4}},
+		{name: "all resolved", resolved: map[int64]bool{1: true, 2: true}, want: []int64{}},
+		{name: "reply id doesn't resolve its thread", resolved: map[int64]bool{3: true}, want: []int64{1, 3, 5, 2, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := commentIDs(dropResolvedThreads(comments, tt.resolved)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dropResolvedThreads() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Output destinations starting with this are uploaded to S3
+const s3Scheme = "s3://"
+
+// output is where the rendered output goes. Nothing reaches the destination
+// until commit; closing an uncommitted output throws it away.
+type output interface {
+	io.WriteCloser
+	commit() error
+}
+
+// openOutput opens dest for writing, as an s3://bucket/key object, a named
+// pipe or a local file.
+func openOutput(dest string) (output, error) {
+	if strings.HasPrefix(dest, s3Scheme) {
+		return newS3Object(dest)
+	}
+	if info, err := os.Stat(dest); err == nil && info.Mode()&os.ModeNamedPipe != 0 {
+		return openPipe(dest)
+	}
+
+	file, err := createAtomic(dest)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Client for uploads, separate from the one configured for GitHub
+var s3Client = &http.Client{}
+
+// s3Object collects the output in memory and uploads it on commit
+type s3Object struct {
+	bytes.Buffer
+	bucket string
+	key    string
+}
+
+func newS3Object(dest string) (*s3Object, error) {
+	bucket, key, ok := strings.Cut(strings.TrimPrefix(dest, s3Scheme), "/")
+	if !ok || bucket == "" || key == "" {
+		return nil, fmt.Errorf("invalid S3 destination %q; expected s3://bucket/key", dest)
+	}
+	return &s3Object{bucket: bucket, key: key}, nil
+}
+
+// Close drops the buffered output.
+func (o *s3Object) Close() error {
+	o.Reset()
+	return nil
+}
+
+// commit uploads the output with a PUT signed with the AWS credentials from
+// the environment. --s3-endpoint points it at S3-compatible servers such as
+// MinIO, which are addressed path-style.
+func (o *s3Object) commit() error {
+	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return authErrorf("uploading to S3 needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	objectURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", o.bucket, region, s3EscapePath(o.key))
+	if s3EndpointFlag != "" {
+		objectURL = fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(s3EndpointFlag, "/"), o.bucket, s3EscapePath(o.key))
+	}
+
+	req, err := http.NewRequest("PUT", objectURL, bytes.NewReader(o.Bytes()))
+	if err != nil {
+		return fmt.Errorf("failed to create upload request: %w", err)
+	}
+	signS3Request(req, o.Bytes(), accessKey, secretKey, os.Getenv("AWS_SESSION_TOKEN"), region, time.Now().UTC())
+
+	// The TLS and proxy flags are meant for GitHub, so the upload doesn't use them
+	err = waitForRequest(req.Context())
+	if err != nil {
+		return err
+	}
+	resp, err := s3Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload to %s%s/%s: %w", s3Scheme, o.bucket, o.key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to upload to %s%s/%s: %s: %s", s3Scheme, o.bucket, o.key, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	o.Reset()
+	return nil
+}
+
+// signS3Request adds an AWS Signature Version 4 to a request for S3.
+func signS3Request(req *http.Request, payload []byte, accessKey, secretKey, sessionToken, region string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+
+	// Headers are signed by their lowercase names, in order
+	headers := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	values := []string{req.URL.Host, payloadHash, amzDate}
+	if sessionToken != "" {
+		headers = append(headers, "x-amz-security-token")
+		values = append(values, sessionToken)
+	}
+	var canonicalHeaders strings.Builder
+	for i, name := range headers {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, values[i])
+	}
+	signedHeaders := strings.Join(headers, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+// s3EscapePath escapes an object key the way SigV4 expects, keeping only
+// unreserved characters and slashes as they are.
+func s3EscapePath(key string) string {
+	var b strings.Builder
+	for _, c := range []byte(key) {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The search API allows far fewer requests per minute than the rest of the
+// API, so successive result pages are spaced out by this much
+var searchPageDelay = 2 * time.Second
+
+// searchIssues runs an issue search and returns the numbers of up to limit results.
+func (f *fetcher) searchIssues(query string, limit int) ([]string, error) {
+	perPage := limit
+	if perPage > 100 {
+		perPage = 100
+	}
+
+	var numbers []string
+	next := fmt.Sprintf("%s/search/issues?q=%s&per_page=%d", apiBaseURL, url.QueryEscape(query), perPage)
+	for next != "" && len(numbers) < limit {
+		if len(numbers) > 0 {
+			err := sleepContext(f.ctx, searchPageDelay)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var page struct {
+			Items []struct {
+				Number int `json:"number"`
+			} `json:"items"`
+		}
+
+		var err error
+		next, err = f.getJSONPage(next, &page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search issues: %w", err)
+		}
+
+		for _, item := range page.Items {
+			if len(numbers) == limit {
+				break
+			}
+			numbers = append(numbers, strconv.Itoa(item.Number))
+		}
+	}
+
+	return numbers, nil
+}
+
+// findIssueByTitle searches the repositories of an organization for the
+// issue or PR whose title best matches title.
+func (f *fetcher) findIssueByTitle(org, title string) (target, string, error) {
+	query := fmt.Sprintf("org:%s in:title %q", org, title)
+
+	var page struct {
+		Items []struct {
+			Number        int    `json:"number"`
+			Title         string `json:"title"`
+			RepositoryURL string `json:"repository_url"`
+		} `json:"items"`
+	}
+	err := f.getJSON(fmt.Sprintf("%s/search/issues?q=%s&per_page=1", apiBaseURL, url.QueryEscape(query)), &page)
+	if err != nil {
+		return target{}, "", fmt.Errorf("failed to search issues: %w", err)
+	}
+	if len(page.Items) == 0 {
+		return target{}, "", &exitError{code: exitNotFound, err: fmt.Errorf("no issue in %s has a title matching %q", org, title)}
+	}
+
+	// The repository comes as an API URL ending in /repos/{owner}/{repo}
+	best := page.Items[0]
+	parts := strings.Split(strings.TrimSuffix(best.RepositoryURL, "/"), "/")
+	if len(parts) < 2 {
+		return target{}, "", fmt.Errorf("unexpected repository URL %q in search results", best.RepositoryURL)
+	}
+
+	match := target{Owner: parts[len(parts)-2], Repo: parts[len(parts)-1], IssueNumber: strconv.Itoa(best.Number)}
+	return match, best.Title, nil
+}
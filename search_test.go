@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSearchIssues(t *testing.T) {
+	tests := []struct {
+		name        string
+		limit       int
+		wantPerPage string
+		want        []string
+	}{
+		{name: "fewer than a page", limit: 2, wantPerPage: "2", want: []string{"11", "12"}},
+		{name: "all results", limit: 10, wantPerPage: "10", want: []string{"11", "12", "13"}},
+		{name: "page size capped", limit: 500, wantPerPage: "100", want: []string{"11", "12", "13"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query, perPage string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query = r.URL.Query().Get("q")
+				perPage = r.URL.Query().Get("per_page")
+				fmt.Fprint(w, `{"items":[{"number":11},{"number":12},{"number":13}]}`)
+			}))
+			defer server.Close()
+			setFlag(t, &apiBaseURL, server.URL)
+
+			f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats()}
+			got, err := f.searchIssues("repo:o/r is:open label:bug", tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("searchIssues() = %v, want %v", got, tt.want)
+			}
+			if query != "repo:o/r is:open label:bug" || perPage != tt.wantPerPage {
+				t.Errorf("searched for %q with per_page=%s, want per_page=%s", query, perPage, tt.wantPerPage)
+			}
+		})
+	}
+}
+
+func TestSearchIssuesFollowsPages(t *testing.T) {
+	var pages []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pages = append(pages, r.URL.Query().Get("page"))
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/search/issues?q=is%%3Aopen&per_page=2&page=2>; rel="next"`, server.URL))
+			fmt.Fprint(w, `{"items":[{"number":11},{"number":12}]}`)
+		case "2":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/search/issues?q=is%%3Aopen&per_page=2&page=3>; rel="next"`, server.URL))
+			fmt.Fprint(w, `{"items":[{"number":13},{"number":14}]}`)
+		default:
+			fmt.Fprint(w, `{"items":[{"number":15}]}`)
+		}
+	}))
+	defer server.Close()
+	setFlag(t, &apiBaseURL, server.URL)
+	setFlag(t, &searchPageDelay, time.Millisecond)
+
+	tests := []struct {
+		name      string
+		limit     int
+		want      []string
+		wantPages []string
+	}{
+		{name: "every page", limit: 10, want: []string{"11", "12", "13", "14", "15"}, wantPages: []string{"", "2", "3"}},
+		{name: "stops at the limit", limit: 3, want: []string{"11", "12", "13"}, wantPages: []string{"", "2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages = nil
+			f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats()}
+			got, err := f.searchIssues("is:open", tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("searchIssues() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(pages, tt.wantPages) {
+				t.Errorf("fetched pages %q, want %q", pages, tt.wantPages)
+			}
+		})
+	}
+}
+
+func TestFindIssueByTitle(t *testing.T) {
+	tests := []struct {
+		name      string
+		response  string
+		want      target
+		wantTitle string
+		wantExit  int
+	}{
+		{
+			name:      "best match",
+			response:  `{"items":[{"number":42,"title":"Login fails on Safari","repository_url":"https://api.github.com/repos/octo-org/web"}]}`,
+			want:      target{Owner: "octo-org", Repo: "web", IssueNumber: "42"},
+			wantTitle: "Login fails on Safari",
+		},
+		{
+			name:     "no match",
+			response: `{"items":[]}`,
+			wantExit: exitNotFound,
+		},
+		{
+			name:     "unexpected repository URL",
+			response: `{"items":[{"number":1,"title":"x","repository_url":"web"}]}`,
+			wantExit: exitUsage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query = r.URL.Query().Get("q")
+				fmt.Fprint(w, tt.response)
+			}))
+			defer server.Close()
+			setFlag(t, &apiBaseURL, server.URL)
+
+			f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats()}
+			got, title, err := f.findIssueByTitle("octo-org", "login fails")
+			if exitCode(err) != tt.wantExit {
+				t.Fatalf("findIssueByTitle() error = %v, want exit code %d", err, tt.wantExit)
+			}
+			if got != tt.want || title != tt.wantTitle {
+				t.Errorf("findIssueByTitle() = %+v, %q, want %+v, %q", got, title, tt.want, tt.wantTitle)
+			}
+			if query != `org:octo-org in:title "login fails"` {
+				t.Errorf("searched for %q", query)
+			}
+		})
+	}
+}
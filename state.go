@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// Last comment ID written for each thread, kept in --state-file between runs
+type runState struct {
+	path   string
+	LastID map[string]int64 `json:"last_comment_id"`
+}
+
+// loadState reads the state file, starting afresh when it doesn't exist yet.
+func loadState(path string) (*runState, error) {
+	state := &runState{path: path, LastID: make(map[string]int64)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	err = json.Unmarshal(data, state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse state file: %w", err)
+	}
+	if state.LastID == nil {
+		state.LastID = make(map[string]int64)
+	}
+	return state, nil
+}
+
+// stateKey identifies an issue, or the commit given with --sha, in the state file.
+func stateKey(owner, repo, issueNumber string) string {
+	if typeFlag == "commit" {
+		return owner + "/" + repo + "@" + shaFlag
+	}
+	return owner + "/" + repo + "#" + issueNumber
+}
+
+// newComments keeps the comments posted after the last run.
+func (s *runState) newComments(key string, comments []Comment) []Comment {
+	last := s.LastID[key]
+	fresh := comments[:0]
+	for _, comment := range comments {
+		if comment.ID > last {
+			fresh = append(fresh, comment)
+		}
+	}
+	return fresh
+}
+
+// record remembers the highest comment ID that was written.
+func (s *runState) record(key string, comments []Comment) {
+	for _, comment := range comments {
+		s.recordID(key, comment.ID)
+	}
+}
+
+// recordID is record for a written comment known only by its ID.
+func (s *runState) recordID(key string, id int64) {
+	if id > s.LastID[key] {
+		s.LastID[key] = id
+	}
+}
+
+// save writes the state back, replacing the old file in one step.
+func (s *runState) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	err = writeFileAtomic(s.path, append(data, '\n'))
+	if err != nil {
+		return fmt.Errorf("failed to save state file: %w", err)
+	}
+	return nil
+}
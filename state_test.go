@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRunStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	// The first run starts without a state file
+	state, err := loadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	comments := []Comment{{ID: 3}, {ID: 9}, {ID: 5}}
+	if got := commentIDs(state.newComments("o/r#1", append([]Comment(nil), comments...))); !reflect.DeepEqual(got, []int64{3, 9, 5}) {
+		t.Errorf("first run keeps %v, want every comment", got)
+	}
+	state.record("o/r#1", comments)
+	err = state.save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The next run only keeps what came after
+	state, err = loadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		key  string
+		want []int64
+	}{
+		{key: "o/r#1", want: []int64{10, 12}},
+		{key: "o/r#2", want: []int64{1, 9, 10, 12}},
+	}
+	for _, tt := range tests {
+		later := []Comment{{ID: 1}, {ID: 9}, {ID: 10}, {ID: 12}}
+		if got := commentIDs(state.newComments(tt.key, later)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("newComments(%s) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestLoadStateErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "empty object", content: `{}`},
+		{name: "saved state", content: `{"last_comment_id":{"o/r#1":7}}`},
+		{name: "not JSON", content: `last=7`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "state.json")
+			os.WriteFile(path, []byte(tt.content), 0600)
+
+			state, err := loadState(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadState() error = %v, want error: %v", err, tt.wantErr)
+			}
+			if err == nil && state.LastID == nil {
+				t.Error("loadState() left LastID nil")
+			}
+		})
+	}
+}
+
+func TestStateKey(t *testing.T) {
+	tests := []struct {
+		typ  string
+		sha  string
+		want string
+	}{
+		{typ: "issue", want: "o/r#12"},
+		{typ: "commit", sha: "abc123", want: "o/r@abc123"},
+	}
+
+	for _, tt := range tests {
+		setFlag(t, &typeFlag, tt.typ)
+		setFlag(t, &shaFlag, tt.sha)
+		if got := stateKey("o", "r", "12"); got != tt.want {
+			t.Errorf("stateKey() for a %s = %q, want %q", tt.typ, got, tt.want)
+		}
+	}
+}
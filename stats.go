@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// runStats collects what a run cost, for the --stats summary. Requests,
+// bytes and pages are counted atomically as some requests run concurrently.
+type runStats struct {
+	started  time.Time
+	requests atomic.Int64
+	bytes    atomic.Int64
+	pages    atomic.Int64
+	issues   int
+	comments int
+
+	// References --resolve found pointing to deleted or inaccessible issues
+	missingRefs int
+
+	// Requests left in the rate limit window as of the last response, -1 until known
+	rateRemaining atomic.Int64
+}
+
+func newRunStats() *runStats {
+	s := &runStats{started: time.Now()}
+	s.rateRemaining.Store(-1)
+	return s
+}
+
+// recordRateLimit keeps the remaining rate limit a response reports.
+func (s *runStats) recordRateLimit(header http.Header) {
+	remaining, err := strconv.ParseInt(header.Get("X-RateLimit-Remaining"), 10, 64)
+	if err == nil {
+		s.rateRemaining.Store(remaining)
+	}
+}
+
+// summary describes the run in one line.
+func (s *runStats) summary() string {
+	summary := fmt.Sprintf("Fetched %d issue(s) and %d comment(s) over %d page(s): %d request(s), %d bytes downloaded in %s.",
+		s.issues, s.comments, s.pages.Load(), s.requests.Load(), s.bytes.Load(), time.Since(s.started).Round(time.Millisecond))
+	if s.missingRefs > 0 {
+		summary += fmt.Sprintf(" %d reference(s) point to deleted or inaccessible issues.", s.missingRefs)
+	}
+	return summary
+}
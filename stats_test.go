@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRunStatsSummary(t *testing.T) {
+	tests := []struct {
+		name        string
+		missingRefs int
+		wantSuffix  string
+	}{
+		{name: "plain", wantSuffix: "."},
+		{name: "missing references", missingRefs: 2, wantSuffix: ". 2 reference(s) point to deleted or inaccessible issues."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newRunStats()
+			s.issues = 2
+			s.comments = 15
+			s.pages.Add(3)
+			s.requests.Add(4)
+			s.bytes.Add(2048)
+			s.missingRefs = tt.missingRefs
+
+			got := s.summary()
+			wantPrefix := "Fetched 2 issue(s) and 15 comment(s) over 3 page(s): 4 request(s), 2048 bytes downloaded in "
+			if !strings.HasPrefix(got, wantPrefix) || !strings.HasSuffix(got, tt.wantSuffix) {
+				t.Errorf("summary() = %q, want %q...%q", got, wantPrefix, tt.wantSuffix)
+			}
+		})
+	}
+}
+
+func TestRecordRateLimit(t *testing.T) {
+	s := newRunStats()
+	tests := []struct {
+		remaining string
+		want      int64
+	}{
+		{remaining: "", want: -1},
+		{remaining: "4999", want: 4999},
+		{remaining: "invalid", want: 4999},
+		{remaining: "0", want: 0},
+	}
+
+	for _, tt := range tests {
+		header := http.Header{}
+		if tt.remaining != "" {
+			header.Set("X-RateLimit-Remaining", tt.remaining)
+		}
+		s.recordRateLimit(header)
+		if got := s.rateRemaining.Load(); got != tt.want {
+			t.Errorf("after X-RateLimit-Remaining %q remaining = %d, want %d", tt.remaining, got, tt.want)
+		}
+	}
+}
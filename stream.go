@@ -0,0 +1,200 @@
+package main
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Set for --format jsonl-gz, whose comments are written as their pages arrive
+var streamOutput bool
+
+// eachCommentPage fetches a comments listing a page at a time, handing each
+// page to each before the next one is requested.
+func (f *fetcher) eachCommentPage(url string, each func([]Comment) error) error {
+	for next := firstPageURL(url); next != ""; {
+		var page []Comment
+		var err error
+		next, err = f.getJSONPage(next, &page)
+		if err != nil {
+			if f.ctx.Err() != nil {
+				return errInterrupted
+			}
+			return err
+		}
+		f.stats.pages.Add(1)
+
+		for i := range page {
+			page[i].Body = selectBody(page[i].Body, page[i].BodyText, page[i].BodyHTML)
+		}
+		err = each(page)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// streamThread works like saveThread for --format jsonl-gz, but writes the
+// comments page by page as they are fetched instead of collecting the whole
+// thread first. Each page is filtered, prepared and flushed through the gzip
+// stream before the next one is requested.
+func streamThread(f *fetcher, owner, repo, issueNumber, outputFile string, names *anonymizer, fields []string) (savedThread, error) {
+	filter, err := f.commentFilter(owner, repo, issueNumber)
+	if err != nil {
+		return savedThread{}, err
+	}
+
+	// The issue goes first, so it's fetched and prepared on its own
+	var issue Issue
+	var offlineComments []Comment
+	if f.offline != nil {
+		issue, offlineComments = f.offline.thread()
+	} else if typeFlag == "issue" && includeIssueFlag {
+		issue, err = fetchIssueDetails(f, owner, repo, issueNumber)
+		if err != nil {
+			return savedThread{}, err
+		}
+	}
+	err = completeComments(f, owner, repo, &issue, nil, false)
+	if err != nil {
+		return savedThread{}, err
+	}
+	redactions := prepareThread(&issue, nil, names)
+
+	pattern := outputFile
+	outputFile, err = expandOutputName(pattern, issueNumber, issue)
+	if err != nil {
+		return savedThread{}, usageErrorf("%w", err)
+	}
+	if outputFile != pattern {
+		err = makeOutputDir(outputFile)
+		if err != nil {
+			return savedThread{}, err
+		}
+	}
+	if archiveLayout == "date" {
+		outputFile, err = archiveOutput(owner, repo, issueNumber, issue)
+		if err != nil {
+			return savedThread{}, err
+		}
+	}
+
+	// Write to an output that replaces the destination once complete, or to stdout
+	var dest output
+	var out io.Writer = os.Stdout
+	if outputFile != "-" {
+		dest, err = openOutput(outputFile)
+		if err != nil {
+			return savedThread{}, err
+		}
+		defer dest.Close()
+		out = dest
+	}
+	gzipWriter := gzip.NewWriter(out)
+
+	if typeFlag == "commit" {
+		err = writeNDJSON(gzipWriter, nil, shaFlag, nil, fields)
+	} else if includeIssueFlag {
+		err = writeNDJSON(gzipWriter, &issue, "", nil, fields)
+	}
+	if err != nil {
+		return savedThread{}, err
+	}
+	err = gzipWriter.Flush()
+	if err != nil {
+		return savedThread{}, fmt.Errorf("failed to write output: %w", err)
+	}
+
+	// Every page goes through the same steps fetchThread takes for the whole
+	// thread, and is then only counted, apart from what --head shows
+	thread := savedThread{owner: owner, repo: repo, issueNumber: issueNumber, outputFile: outputFile}
+	var preview []Comment
+	each := func(page []Comment) error {
+		page = filter.apply(page, f.state)
+		err := completeComments(f, owner, repo, &Issue{}, page, false)
+		if err != nil {
+			return err
+		}
+		redactions += prepareThread(&Issue{}, page, names)
+
+		err = writeNDJSON(gzipWriter, nil, "", page, fields)
+		if err != nil {
+			return err
+		}
+		err = gzipWriter.Flush()
+		if err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		thread.add(page)
+		if n := headFlag - len(preview); n > 0 {
+			if n > len(page) {
+				n = len(page)
+			}
+			preview = append(preview, page[:n]...)
+		}
+		return nil
+	}
+
+	// run turns down --graphql, Gitea and --resume, whose listings don't come in plain REST pages
+	switch {
+	case f.offline != nil:
+		err = each(offlineComments)
+	case typeFlag == "commit":
+		err = f.eachCommentPage(commitCommentsURL(owner, repo, shaFlag), each)
+		if err != nil && !errors.Is(err, errInterrupted) {
+			err = fmt.Errorf("failed to fetch comments: %w", err)
+		}
+	default:
+		err = f.eachCommentPage(issueURL(owner, repo, issueNumber)+"/comments", each)
+		if err != nil && !errors.Is(err, errInterrupted) {
+			err = fmt.Errorf("failed to fetch comments: %w", permissionError(err, owner, repo))
+		}
+	}
+	if err != nil && !errors.Is(err, errInterrupted) {
+		return savedThread{}, err
+	}
+	interrupted := err
+
+	if dedupFlag != dedupOff {
+		statusf("Removed %d duplicate comment(s).\n", filter.removed)
+	}
+
+	// Flush the gzip stream before the output is committed so the archive isn't truncated
+	err = gzipWriter.Close()
+	if err != nil {
+		return savedThread{}, fmt.Errorf("failed to finish compressed output: %w", err)
+	}
+	if dest != nil {
+		err = dest.commit()
+		if err != nil {
+			return savedThread{}, err
+		}
+	}
+
+	// Remember how far this run got
+	if f.state != nil {
+		for _, id := range thread.ids {
+			f.state.recordID(filter.key, id)
+		}
+	}
+	if outputFile != "-" {
+		statusf("Issue details and comments have been fetched and saved to %s.\n", outputFile)
+	}
+
+	// Show the first comments on the terminal for --head
+	if headFlag > 0 {
+		err = previewThread(os.Stdout, issue, preview, fields)
+		if err != nil {
+			return savedThread{}, err
+		}
+	}
+
+	if redactFlag {
+		statusf("Redacted %d secret(s) from the issue and comments.\n", redactions)
+	}
+
+	return thread, interrupted
+}
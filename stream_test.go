@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readJSONLines decompresses as much of a gzipped NDJSON file as was
+// flushed so far, returning the type and id of each complete line.
+func readJSONLines(t *testing.T, path string) []string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		var record struct {
+			Type string `json:"type"`
+			ID   int64  `json:"id"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &record) == nil {
+			lines = append(lines, fmt.Sprintf("%s %d", record.Type, record.ID))
+		}
+	}
+	return lines
+}
+
+func TestStreamThreadWritesEachPage(t *testing.T) {
+	dir := t.TempDir()
+	outputFile := filepath.Join(dir, "comments.jsonl.gz")
+
+	// While the second page is requested, the first must already be in the file
+	var seenBeforePage2 []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/o/r/issues/1":
+			fmt.Fprint(w, `{"id":100,"number":1,"title":"t","body":"b","user":{"login":"a"}}`)
+		case r.URL.Path == "/repos/o/r/issues/1/comments" && r.URL.Query().Get("page") == "2":
+			temps, _ := filepath.Glob(filepath.Join(dir, ".comments.jsonl.gz.tmp-*"))
+			if len(temps) == 1 {
+				seenBeforePage2 = readJSONLines(t, temps[0])
+			}
+			fmt.Fprint(w, `[{"id":3,"body":"three","user":{"login":"c"}}]`)
+		case r.URL.Path == "/repos/o/r/issues/1/comments":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/o/r/issues/1/comments?page=2>; rel="next"`, server.URL))
+			fmt.Fprint(w, `[{"id":1,"body":"one","user":{"login":"a"}},{"id":2,"body":"one","user":{"login":"a"}}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	setFlag(t, &apiBaseURL, server.URL)
+	setFlag(t, &schemaFlag, "github")
+	setFlag(t, &typeFlag, "issue")
+	setFlag(t, &includeIssueFlag, true)
+	setFlag(t, &includeHidden, true)
+	setFlag(t, &dedupFlag, dedupConsecutive)
+	setFlag(t, &formatFlag, "ndjson")
+	setFlag(t, &outputFlag, outputFile)
+
+	f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats()}
+	thread, err := streamThread(f, "o", "r", "1", outputFile, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"issue 100", "comment 1"}
+	if strings.Join(seenBeforePage2, ",") != strings.Join(want, ",") {
+		t.Errorf("before page 2 the output held %v, want %v", seenBeforePage2, want)
+	}
+	want = append(want, "comment 3")
+	if got := readJSONLines(t, outputFile); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("output = %v, want %v", got, want)
+	}
+	if thread.count != 2 || !reflect.DeepEqual(thread.ids, []int64{1, 3}) {
+		t.Errorf("saved %d comments %v, want 2 [1 3]", thread.count, thread.ids)
+	}
+}
+
+func TestRunJSONLGzRejectsWholeListings(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T)
+	}{
+		{name: "graphql", setup: func(t *testing.T) { setFlag(t, &graphqlFlag, true) }},
+		{name: "gitea", setup: func(t *testing.T) { setFlag(t, &schemaFlag, "gitea") }},
+		{name: "resume", setup: func(t *testing.T) { setFlag(t, &resumeFlag, "resume.json") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &formatFlag, "jsonl-gz")
+			setFlag(t, &gzipFlag, false)
+			setFlag(t, &streamOutput, false)
+			tt.setup(t)
+
+			err := runStubbed(t, stubAPI{})
+			if exitCode(err) != exitUsage || !strings.Contains(err.Error(), "jsonl-gz") {
+				t.Errorf("run() error = %v, want a usage error about jsonl-gz", err)
+			}
+		})
+	}
+}
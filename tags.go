@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Annotated tags can point at other tags; give up after this many
+const maxTagDepth = 5
+
+// tagTime returns when the commit a tag points to was committed, looking
+// each tag up once.
+func (f *fetcher) tagTime(owner, repo, tag string) (time.Time, error) {
+	key := owner + "/" + repo + "@" + tag
+	if at, ok := f.tagTimes[key]; ok {
+		return at, nil
+	}
+
+	var ref struct {
+		Object struct {
+			Type string `json:"type"`
+			SHA  string `json:"sha"`
+		} `json:"object"`
+	}
+	err := f.getJSON(fmt.Sprintf("%s/repos/%s/%s/git/ref/tags/%s", apiBaseURL, owner, repo, escapeRef(tag)), &ref)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to look up tag %s: %w", tag, permissionError(err, owner, repo))
+	}
+
+	// Annotated tags are objects of their own pointing at the commit
+	object := ref.Object
+	for depth := 0; object.Type == "tag"; depth++ {
+		if depth == maxTagDepth {
+			return time.Time{}, fmt.Errorf("tag %s doesn't lead to a commit", tag)
+		}
+
+		var annotated struct {
+			Object struct {
+				Type string `json:"type"`
+				SHA  string `json:"sha"`
+			} `json:"object"`
+		}
+		err = f.getJSON(fmt.Sprintf("%s/repos/%s/%s/git/tags/%s", apiBaseURL, owner, repo, object.SHA), &annotated)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to look up tag %s: %w", tag, err)
+		}
+		object = annotated.Object
+	}
+	if object.Type != "commit" {
+		return time.Time{}, fmt.Errorf("tag %s points to a %s, not a commit", tag, object.Type)
+	}
+
+	var commit struct {
+		Committer struct {
+			Date time.Time `json:"date"`
+		} `json:"committer"`
+	}
+	err = f.getJSON(fmt.Sprintf("%s/repos/%s/%s/git/commits/%s", apiBaseURL, owner, repo, object.SHA), &commit)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to look up the commit of tag %s: %w", tag, err)
+	}
+
+	if f.tagTimes == nil {
+		f.tagTimes = make(map[string]time.Time)
+	}
+	f.tagTimes[key] = commit.Committer.Date
+	return commit.Committer.Date, nil
+}
+
+// escapeRef escapes each segment of a ref name for a URL path, keeping the
+// slashes of names such as release/v1 that are part of the ref.
+func escapeRef(ref string) string {
+	segments := strings.Split(ref, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEscapeRef(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"v1.2.0", "v1.2.0"},
+		{"release/v1", "release/v1"},
+		{"team/release candidate#2", "team/release%20candidate%232"},
+		{"100%", "100%25"},
+	}
+
+	for _, tt := range tests {
+		if got := escapeRef(tt.ref); got != tt.want {
+			t.Errorf("escapeRef(%q) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestTagTime(t *testing.T) {
+	committed := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// release/v1 is an annotated tag pointing at a lightweight tag's commit
+	responses := map[string]string{
+		"/repos/o/r/git/ref/tags/release/v1": `{"object":{"type":"tag","sha":"t1"}}`,
+		"/repos/o/r/git/tags/t1":             `{"object":{"type":"commit","sha":"c1"}}`,
+		"/repos/o/r/git/ref/tags/v2":         `{"object":{"type":"commit","sha":"c1"}}`,
+		"/repos/o/r/git/ref/tags/tree":       `{"object":{"type":"tree","sha":"x"}}`,
+		"/repos/o/r/git/commits/c1":          fmt.Sprintf(`{"committer":{"date":%q}}`, committed.Format(time.RFC3339)),
+	}
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, ok := responses[r.URL.EscapedPath()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+	setFlag(t, &apiBaseURL, server.URL)
+
+	tests := []struct {
+		tag     string
+		wantErr bool
+	}{
+		{tag: "release/v1"},
+		{tag: "v2"},
+		{tag: "tree", wantErr: true},
+		{tag: "missing", wantErr: true},
+	}
+
+	f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats()}
+	for _, tt := range tests {
+		got, err := f.tagTime("o", "r", tt.tag)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("tagTime(%q) error = %v, want error %v", tt.tag, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !got.Equal(committed) {
+			t.Errorf("tagTime(%q) = %s, want %s", tt.tag, got, committed)
+		}
+	}
+
+	// Tags already looked up aren't requested again
+	before := requests
+	f.tagTime("o", "r", "release/v1")
+	if requests != before {
+		t.Errorf("looking up release/v1 again made %d request(s)", requests-before)
+	}
+}
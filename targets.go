@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// One repository and issue to fetch, as listed under "targets" in the inputs file
+type target struct {
+	Owner       string `json:"owner"`
+	Repo        string `json:"repo"`
+	IssueNumber string `json:"issueNumber"`
+}
+
+// expandTargets checks the targets from the inputs file and splits their
+// comma separated issue numbers into one target per issue.
+func expandTargets(targets []target) ([]target, error) {
+	var expanded []target
+	for i, t := range targets {
+		if t.Owner == "" || t.Repo == "" {
+			return nil, fmt.Errorf("target %d in github-comments-fetcher-inputs.txt needs both 'owner' and 'repo'", i+1)
+		}
+		err := validateOwnerName(t.Owner)
+		if err != nil {
+			return nil, err
+		}
+		err = validateRepoName(t.Repo)
+		if err != nil {
+			return nil, err
+		}
+
+		numbers, err := parseIssueNumbers(t.IssueNumber)
+		if err != nil {
+			return nil, fmt.Errorf("target %s/%s: %w", t.Owner, t.Repo, err)
+		}
+		if len(numbers) == 0 {
+			return nil, fmt.Errorf("target %s/%s in github-comments-fetcher-inputs.txt has no 'issueNumber'", t.Owner, t.Repo)
+		}
+
+		for _, number := range numbers {
+			expanded = append(expanded, target{Owner: t.Owner, Repo: t.Repo, IssueNumber: number})
+		}
+	}
+	return expanded, nil
+}
+
+// applyPositionalArgs reads the repository and issue numbers given as
+// arguments, like owner/repo 42 or owner/repo#42, parsing flags that come
+// after them too. Flags win over the arguments.
+func applyPositionalArgs() error {
+	var owner, repo string
+	var numbers []string
+	for flag.NArg() > 0 {
+		arg := flag.Arg(0)
+		if name, number, ok := strings.Cut(arg, "/"); ok {
+			if owner != "" {
+				return usageErrorf("only one owner/repo argument can be given, got %q after %s/%s", arg, owner, repo)
+			}
+			owner, repo = name, number
+			repo, number, _ = strings.Cut(repo, "#")
+			if number != "" {
+				numbers = append(numbers, number)
+			}
+
+			err := validateOwnerName(owner)
+			if err == nil {
+				err = validateRepoName(repo)
+			}
+			if err != nil {
+				return usageErrorf("invalid argument %q: %w", arg, err)
+			}
+		} else {
+			parsed, err := parseIssueNumbers(arg)
+			if err != nil || len(parsed) == 0 {
+				return usageErrorf("unexpected argument %q; expected owner/repo or an issue number", arg)
+			}
+			numbers = append(numbers, parsed...)
+		}
+
+		err := flag.CommandLine.Parse(flag.Args()[1:])
+		if err != nil {
+			return usageErrorf("%w", err)
+		}
+	}
+
+	if ownerFlag == "" {
+		ownerFlag = owner
+	}
+	if repoFlag == "" {
+		repoFlag = repo
+	}
+	if issueNumberFlag == "" {
+		issueNumberFlag = strings.Join(numbers, ",")
+	}
+	return nil
+}
+
+// spansRepos reports whether the targets belong to more than one repository.
+func spansRepos(targets []target) bool {
+	for _, t := range targets {
+		if t.Owner != targets[0].Owner || t.Repo != targets[0].Repo {
+			return true
+		}
+	}
+	return false
+}
+
+// Characters GitHub allows in account and repository names
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// validateOwnerName checks a user or organization name against GitHub's rules.
+func validateOwnerName(s string) error {
+	return validateName("owner", s, 39)
+}
+
+// validateRepoName checks a repository name against GitHub's rules.
+func validateRepoName(s string) error {
+	return validateName("repository", s, 100)
+}
+
+// validateName rejects names that are empty, longer than max characters or
+// contain anything but letters, digits, hyphens, underscores and dots.
+func validateName(kind, s string, max int) error {
+	switch {
+	case s == "":
+		return fmt.Errorf("the %s name can't be empty", kind)
+	case len(s) > max:
+		return fmt.Errorf("invalid %s name %q: longer than %d characters", kind, s, max)
+	case !namePattern.MatchString(s):
+		return fmt.Errorf("invalid %s name %q: only letters, digits, '-', '_' and '.' are allowed", kind, s)
+	case s == "." || s == "..":
+		return fmt.Errorf("invalid %s name %q", kind, s)
+	}
+	return nil
+}
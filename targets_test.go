@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets []target
+		want    []target
+		wantErr string
+	}{
+		{
+			name:    "one issue each",
+			targets: []target{{Owner: "o", Repo: "r", IssueNumber: "1"}, {Owner: "p", Repo: "q", IssueNumber: "2"}},
+			want:    []target{{Owner: "o", Repo: "r", IssueNumber: "1"}, {Owner: "p", Repo: "q", IssueNumber: "2"}},
+		},
+		{
+			name:    "several issues",
+			targets: []target{{Owner: "o", Repo: "r", IssueNumber: "1, #5"}},
+			want:    []target{{Owner: "o", Repo: "r", IssueNumber: "1"}, {Owner: "o", Repo: "r", IssueNumber: "5"}},
+		},
+		{
+			name:    "missing repo",
+			targets: []target{{Owner: "o", IssueNumber: "1"}},
+			wantErr: "target 1 in github-comments-fetcher-inputs.txt needs both 'owner' and 'repo'",
+		},
+		{
+			name:    "missing issue number",
+			targets: []target{{Owner: "o", Repo: "r", IssueNumber: "1"}, {Owner: "p", Repo: "q"}},
+			wantErr: "target p/q in github-comments-fetcher-inputs.txt has no 'issueNumber'",
+		},
+		{
+			name:    "invalid owner",
+			targets: []target{{Owner: "../o", Repo: "r", IssueNumber: "1"}},
+			wantErr: `invalid owner name "../o"`,
+		},
+		{
+			name:    "invalid issue number",
+			targets: []target{{Owner: "o", Repo: "r", IssueNumber: "one"}},
+			wantErr: `target o/r: invalid issue number "one"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandTargets(tt.targets)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expandTargets() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandTargets() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		validate func(string) error
+		value    string
+		wantErr  string
+	}{
+		{name: "owner", validate: validateOwnerName, value: "octo-org"},
+		{name: "repository with dots", validate: validateRepoName, value: "my.repo_v2"},
+		{name: "empty owner", validate: validateOwnerName, value: "", wantErr: "the owner name can't be empty"},
+		{name: "long owner", validate: validateOwnerName, value: strings.Repeat("a", 40), wantErr: "longer than 39 characters"},
+		{name: "long repository", validate: validateRepoName, value: strings.Repeat("a", 101), wantErr: "longer than 100 characters"},
+		{name: "path traversal", validate: validateRepoName, value: "..", wantErr: `invalid repository name ".."`},
+		{name: "slash", validate: validateRepoName, value: "a/b", wantErr: "only letters, digits"},
+		{name: "space", validate: validateOwnerName, value: "octo org", wantErr: "only letters, digits"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.validate(tt.value)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validating %q: %v", tt.value, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validating %q: error = %v, want %q", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyPositionalArgs(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantOwner   string
+		wantRepo    string
+		wantNumbers string
+		wantErr     string
+	}{
+		{name: "repository and number", args: []string{"octocat/hello-world", "42"}, wantOwner: "octocat", wantRepo: "hello-world", wantNumbers: "42"},
+		{name: "number in the reference", args: []string{"octocat/hello-world#42"}, wantOwner: "octocat", wantRepo: "hello-world", wantNumbers: "42"},
+		{name: "several numbers", args: []string{"octocat/hello-world", "1,2", "3"}, wantOwner: "octocat", wantRepo: "hello-world", wantNumbers: "1,2,3"},
+		{name: "number only", args: []string{"42"}, wantNumbers: "42"},
+		{name: "repository only", args: []string{"octocat/hello-world"}, wantOwner: "octocat", wantRepo: "hello-world"},
+		{name: "flag after the arguments wins", args: []string{"octocat/hello-world", "42", "-I", "7"}, wantOwner: "octocat", wantRepo: "hello-world", wantNumbers: "7"},
+		{name: "flag before the arguments wins", args: []string{"-O", "other", "octocat/hello-world", "42"}, wantOwner: "other", wantRepo: "hello-world", wantNumbers: "42"},
+		{name: "no arguments", args: nil},
+		{name: "two repositories", args: []string{"a/b", "c/d"}, wantErr: "only one owner/repo argument"},
+		{name: "neither repository nor number", args: []string{"hello"}, wantErr: `unexpected argument "hello"`},
+		{name: "invalid owner", args: []string{"bad owner/repo"}, wantErr: `invalid argument "bad owner/repo"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &ownerFlag, "")
+			setFlag(t, &repoFlag, "")
+			setFlag(t, &issueNumberFlag, "")
+			err := flag.CommandLine.Parse(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { flag.CommandLine.Parse(nil) })
+
+			err = applyPositionalArgs()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("applyPositionalArgs() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ownerFlag != tt.wantOwner || repoFlag != tt.wantRepo || issueNumberFlag != tt.wantNumbers {
+				t.Errorf("owner, repo, numbers = %q, %q, %q, want %q, %q, %q",
+					ownerFlag, repoFlag, issueNumberFlag, tt.wantOwner, tt.wantRepo, tt.wantNumbers)
+			}
+		})
+	}
+}
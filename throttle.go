@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Share of the interval between requests that --rate adds at random, so runs
+// started together drift apart instead of hitting the API in step
+const rateJitter = 0.2
+
+// throttle spaces out requests: a token bucket refilled at the rate, with a
+// random delay of up to the jitter in front of each request.
+type throttle struct {
+	limiter *rate.Limiter
+	jitter  time.Duration
+}
+
+func newThrottle(perSecond float64) *throttle {
+	interval := time.Duration(float64(time.Second) / perSecond)
+	return &throttle{
+		limiter: rate.NewLimiter(rate.Limit(perSecond), 1),
+		jitter:  time.Duration(float64(interval) * rateJitter),
+	}
+}
+
+// wait blocks until the next request may go out. The jitter comes before
+// the bucket, so requests are never closer together than the rate allows.
+func (t *throttle) wait(ctx context.Context) error {
+	if t.jitter > 0 {
+		err := sleepContext(ctx, time.Duration(rand.Int63n(int64(t.jitter))))
+		if err != nil {
+			return err
+		}
+	}
+	return t.limiter.Wait(ctx)
+}
+
+// The throttle for --rate shared by every request, made on first use
+var (
+	requestThrottle     *throttle
+	requestThrottleOnce sync.Once
+)
+
+// waitForRequest blocks until --rate lets the next request go out, whether
+// it's for GitHub, S3 or the translation service. Without --rate it returns
+// right away.
+func waitForRequest(ctx context.Context) error {
+	requestThrottleOnce.Do(func() {
+		if rateFlag > 0 {
+			requestThrottle = newThrottle(rateFlag)
+		}
+	})
+	if requestThrottle == nil {
+		return nil
+	}
+	return requestThrottle.wait(ctx)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestThrottleSpacing(t *testing.T) {
+	tests := []struct {
+		perSecond float64
+		requests  int
+	}{
+		{perSecond: 20, requests: 5},
+		{perSecond: 50, requests: 6},
+		{perSecond: 100, requests: 10},
+	}
+
+	for _, tt := range tests {
+		throttle := newThrottle(tt.perSecond)
+		interval := time.Duration(float64(time.Second) / tt.perSecond)
+		if want := interval / 5; throttle.jitter != want {
+			t.Errorf("rate %v: jitter = %s, want %s", tt.perSecond, throttle.jitter, want)
+		}
+
+		started := time.Now()
+		var sent []time.Time
+		for i := 0; i < tt.requests; i++ {
+			err := throttle.wait(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			sent = append(sent, time.Now())
+		}
+
+		// The bucket holds one token, so request i can't go out before i intervals
+		// have passed. Measured from the start, as a late wake-up only delays one request
+		tolerance := time.Millisecond
+		for i := 1; i < len(sent); i++ {
+			if elapsed, want := sent[i].Sub(started), time.Duration(i)*interval; elapsed < want-tolerance {
+				t.Errorf("rate %v: request %d went out %s after the start, want at least %s", tt.perSecond, i+1, elapsed, want)
+			}
+		}
+	}
+}
+
+func TestThrottleCancelled(t *testing.T) {
+	throttle := newThrottle(0.5)
+	err := throttle.wait(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The next request would wait two seconds
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	err = throttle.wait(ctx)
+	if err == nil {
+		t.Fatal("wait returned without error after the context ended")
+	}
+	if waited := time.Since(started); waited > time.Second {
+		t.Errorf("wait took %s to give up", waited)
+	}
+}
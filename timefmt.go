@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Layout used for every timestamp in the text output unless --time-format says otherwise
+const timeLayout = "2006-01-02 15:04:05"
+
+// Settings derived from --time-format and --timezone
+var (
+	displayLayout   = timeLayout
+	displayRelative bool
+	displayLocation = time.UTC
+)
+
+// configureTime applies the --time-format and --timezone flags.
+func configureTime(format, timezone string) error {
+	switch format {
+	case "", "default":
+		displayLayout = timeLayout
+	case "rfc3339":
+		displayLayout = time.RFC3339
+	case "date":
+		displayLayout = "2006-01-02"
+	case "relative":
+		displayRelative = true
+	default:
+		displayLayout = format
+	}
+
+	if timezone != "" {
+		location, err := time.LoadLocation(timezone)
+		if err != nil {
+			return fmt.Errorf("unknown timezone %q: %w", timezone, err)
+		}
+		displayLocation = location
+	}
+
+	return nil
+}
+
+// formatTime renders a timestamp for the text output.
+func formatTime(t time.Time) string {
+	if displayRelative {
+		return relativeTime(t, time.Now())
+	}
+	return t.In(displayLocation).Format(displayLayout)
+}
+
+// relativeTime describes t relative to now, e.g. "3 days ago".
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	suffix := " ago"
+	if d < 0 {
+		d = -d
+		suffix = ""
+	}
+
+	var amount int
+	var unit string
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		amount, unit = int(d/time.Minute), "minute"
+	case d < 24*time.Hour:
+		amount, unit = int(d/time.Hour), "hour"
+	case d < 30*24*time.Hour:
+		amount, unit = int(d/(24*time.Hour)), "day"
+	case d < 365*24*time.Hour:
+		amount, unit = int(d/(30*24*time.Hour)), "month"
+	default:
+		amount, unit = int(d/(365*24*time.Hour)), "year"
+	}
+
+	if amount != 1 {
+		unit += "s"
+	}
+	if suffix == "" {
+		return fmt.Sprintf("in %d %s", amount, unit)
+	}
+	return fmt.Sprintf("%d %s%s", amount, unit, suffix)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfigureTime(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		format   string
+		timezone string
+		want     string
+		wantErr  bool
+	}{
+		{name: "default", want: "2024-03-01 12:30:00"},
+		{name: "rfc3339", format: "rfc3339", want: "2024-03-01T12:30:00Z"},
+		{name: "date", format: "date", want: "2024-03-01"},
+		{name: "custom layout", format: "02 Jan 15:04", want: "01 Mar 12:30"},
+		{name: "timezone", timezone: "Asia/Tokyo", want: "2024-03-01 21:30:00"},
+		{name: "timezone with layout", format: "rfc3339", timezone: "America/New_York", want: "2024-03-01T07:30:00-05:00"},
+		{name: "unknown timezone", timezone: "Mars/Olympus", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &displayLayout, timeLayout)
+			setFlag(t, &displayRelative, false)
+			setFlag(t, &displayLocation, time.UTC)
+
+			err := configureTime(tt.format, tt.timezone)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("configureTime() error = %v, want error: %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := formatTime(at); got != tt.want {
+				t.Errorf("formatTime() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		ago  time.Duration
+		want string
+	}{
+		{ago: 30 * time.Second, want: "just now"},
+		{ago: time.Minute, want: "1 minute ago"},
+		{ago: 5 * time.Minute, want: "5 minutes ago"},
+		{ago: 3 * time.Hour, want: "3 hours ago"},
+		{ago: 24 * time.Hour, want: "1 day ago"},
+		{ago: 60 * 24 * time.Hour, want: "2 months ago"},
+		{ago: 800 * 24 * time.Hour, want: "2 years ago"},
+		{ago: -2 * time.Hour, want: "in 2 hours"},
+	}
+
+	for _, tt := range tests {
+		if got := relativeTime(now.Add(-tt.ago), now); got != tt.want {
+			t.Errorf("relativeTime(%s ago) = %q, want %q", tt.ago, got, tt.want)
+		}
+	}
+}
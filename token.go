@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Scopes check-token looks for, as classic tokens list them
+var checkedScopes = []string{"repo", "issues"}
+
+// checkToken looks the token up with GitHub and prints who it belongs to
+// and the scopes it was granted. An invalid token is an authentication error.
+func checkToken(out io.Writer) error {
+	token := resolveToken()
+	if token == "" {
+		return authErrorf("no access token to check; pass --token, set GITHUB_ACCESS_TOKEN or run login")
+	}
+
+	client, err := newHTTPClient()
+	if err != nil {
+		return usageErrorf("%w", err)
+	}
+	f := &fetcher{ctx: context.Background(), client: client, accessToken: token, stats: newRunStats()}
+
+	req, err := http.NewRequest("GET", apiBaseURL+"/user", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := f.sendRequest(req)
+	if err != nil {
+		return fmt.Errorf("failed to check token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return authErrorf("the token for %s is invalid or has expired", apiBaseURL)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to check token: %s", resp.Status)
+	}
+
+	var user User
+	err = json.NewDecoder(resp.Body).Decode(&user)
+	if err != nil {
+		return fmt.Errorf("failed to parse user: %w", err)
+	}
+
+	fmt.Fprintf(out, "Token for %s belongs to %s.\n", apiBaseURL, user.Login)
+
+	// Fine-grained tokens don't send the header, their permissions are per repository
+	header, ok := resp.Header["X-Oauth-Scopes"]
+	if !ok {
+		fmt.Fprintln(out, "Scopes: not reported; fine-grained tokens have per-repository permissions instead")
+		return nil
+	}
+	var scopes []string
+	for _, scope := range strings.Split(strings.Join(header, ","), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		fmt.Fprintln(out, "Scopes: none (public data only)")
+	} else {
+		fmt.Fprintf(out, "Scopes: %s\n", strings.Join(scopes, ", "))
+	}
+
+	for _, wanted := range checkedScopes {
+		state := "missing"
+		for _, scope := range scopes {
+			if scope == wanted {
+				state = "present"
+			}
+		}
+		fmt.Fprintf(out, "  %s: %s\n", wanted, state)
+	}
+	return nil
+}
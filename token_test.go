@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckToken(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		scopes    []string
+		want      []string
+		wantCode  int
+		wantError string
+	}{
+		{
+			name:   "classic token",
+			status: http.StatusOK,
+			scopes: []string{"repo, read:org"},
+			want:   []string{"Token for URL belongs to octocat.", "Scopes: repo, read:org", "  repo: present", "  issues: missing"},
+		},
+		{
+			name:   "no scopes",
+			status: http.StatusOK,
+			scopes: []string{""},
+			want:   []string{"Scopes: none (public data only)", "  repo: missing"},
+		},
+		{
+			name:   "fine-grained token",
+			status: http.StatusOK,
+			want:   []string{"Scopes: not reported; fine-grained tokens have per-repository permissions instead"},
+		},
+		{
+			name:      "invalid token",
+			status:    http.StatusUnauthorized,
+			wantCode:  exitAuth,
+			wantError: "is invalid or has expired",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotAuth string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotAuth = r.Header.Get("Authorization")
+				if r.URL.Path != "/user" {
+					http.NotFound(w, r)
+					return
+				}
+				for _, scopes := range tt.scopes {
+					w.Header().Add("X-OAuth-Scopes", scopes)
+				}
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, `{"login":"octocat"}`)
+			}))
+			defer server.Close()
+			setFlag(t, &apiBaseURL, server.URL)
+			setFlag(t, &tokenFlag, "ghp_test")
+			setFlag(t, &proxyFlag, "")
+
+			var out strings.Builder
+			err := checkToken(&out)
+			if gotAuth != "Bearer ghp_test" {
+				t.Errorf("Authorization = %q, want the token", gotAuth)
+			}
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) || exitCode(err) != tt.wantCode {
+					t.Fatalf("checkToken() error = %v (exit code %d), want %q (exit code %d)", err, exitCode(err), tt.wantError, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				want = strings.ReplaceAll(want, "URL", server.URL)
+				if !strings.Contains(out.String(), want+"\n") {
+					t.Errorf("output is missing %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
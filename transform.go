@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// normalizeNewlines converts CRLF (and lone CR) line endings to LF and strips
+// trailing spaces and tabs from every line.
+func normalizeNewlines(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrapText breaks lines wider than width columns at spaces, keeping existing
+// line breaks and leaving fenced ``` code blocks untouched. Wide characters
+// such as CJK count as two columns and may be broken between, since such text
+// has no spaces. Other words wider than width are kept whole on their own line.
+func wrapText(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+
+	var wrapped []string
+	inFence := false
+	for _, line := range strings.Split(s, "\n") {
+		// Code blocks keep their layout, fences included
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			wrapped = append(wrapped, line)
+			continue
+		}
+		if inFence || stringWidth(line) <= width {
+			wrapped = append(wrapped, line)
+			continue
+		}
+
+		// Fill each line greedily with whole words and single wide characters
+		current, currentWidth := "", 0
+		for _, piece := range wrapPieces(line) {
+			separator := ""
+			if piece.spaced && current != "" {
+				separator = " "
+			}
+
+			if current != "" && currentWidth+len(separator)+piece.width > width {
+				wrapped = append(wrapped, current)
+				current, currentWidth, separator = "", 0, ""
+			}
+			current += separator + piece.text
+			currentWidth += len(separator) + piece.width
+		}
+		wrapped = append(wrapped, current)
+	}
+	return strings.Join(wrapped, "\n")
+}
+
+// A run of text that wrapText keeps on one line
+type wrapPiece struct {
+	text   string
+	width  int
+	spaced bool // preceded by a space in the original line
+}
+
+// wrapPieces splits a line into words, with every wide character a piece of its own.
+func wrapPieces(line string) []wrapPiece {
+	var pieces []wrapPiece
+	for _, word := range strings.Fields(line) {
+		spaced := true
+		start := 0
+		for i, r := range word {
+			if runeWidth(r) < 2 {
+				continue
+			}
+			if start < i {
+				pieces = append(pieces, wrapPiece{text: word[start:i], width: stringWidth(word[start:i]), spaced: spaced})
+				spaced = false
+			}
+			end := i + utf8.RuneLen(r)
+			pieces = append(pieces, wrapPiece{text: word[i:end], width: 2, spaced: spaced})
+			spaced = false
+			start = end
+		}
+		if start < len(word) {
+			pieces = append(pieces, wrapPiece{text: word[start:], width: stringWidth(word[start:]), spaced: spaced})
+		}
+	}
+	return pieces
+}
+
+// stringWidth is the number of terminal columns s takes up.
+func stringWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// runeWidth is the number of terminal columns r takes up: two for East Asian
+// wide characters and most emoji, none for combining marks and one otherwise.
+func runeWidth(r rune) int {
+	switch {
+	case unicode.Is(unicode.Mn, r) || r == '\u200d' || r == '\ufe0f':
+		return 0
+	case r >= 0x1100 && r <= 0x115f, // Hangul Jamo
+		r >= 0x2e80 && r <= 0xa4cf && r != 0x303f,              // CJK radicals to Yi
+		r >= 0xac00 && r <= 0xd7a3,                             // Hangul syllables
+		r >= 0xf900 && r <= 0xfaff,                             // CJK compatibility ideographs
+		r >= 0xfe30 && r <= 0xfe4f,                             // CJK compatibility forms
+		r >= 0xff00 && r <= 0xff60, r >= 0xffe0 && r <= 0xffe6, // Fullwidth forms
+		r >= 0x1f300 && r <= 0x1f64f, r >= 0x1f900 && r <= 0x1f9ff, // Emoji
+		r >= 0x20000 && r <= 0x3fffd: // CJK extensions
+		return 2
+	}
+	return 1
+}
+
+// flattenQuotes removes the > quoted lines of a reply along with the blank
+// lines following them, leaving fenced ``` code blocks untouched. It returns
+// the new body and how many lines were removed.
+func flattenQuotes(s string) (string, int) {
+	var kept []string
+	inFence, afterQuote, removed := false, false, 0
+	for _, line := range strings.Split(s, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+		}
+
+		if !inFence && strings.HasPrefix(trimmed, ">") {
+			afterQuote = true
+			removed++
+			continue
+		}
+		if afterQuote && trimmed == "" {
+			removed++
+			continue
+		}
+		afterQuote = false
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n"), removed
+}
+
+// runFilter pipes body through the shell command given with --exec and
+// returns what it printed.
+func runFilter(command, body string) (string, error) {
+	cmd := exec.Command("sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	}
+	cmd.Stdin = strings.NewReader(body)
+	cmd.Stderr = os.Stderr
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("failed to run %q: %w", command, err)
+	}
+	return out.String(), nil
+}
+
+// Marker put at the end of bodies cut short by --max-body-bytes
+const truncatedMarker = "[...truncated]"
+
+// truncateBody cuts s down to at most max bytes without splitting a
+// character, marking where it was cut. The marker counts towards max, and is
+// left out when max is too small to hold it. It reports whether s was cut.
+func truncateBody(s string, max int) (string, bool) {
+	if len(s) <= max {
+		return s, false
+	}
+
+	marker := "\n" + truncatedMarker
+	if max < len(marker) {
+		marker = ""
+	}
+	cut := max - len(marker)
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + marker, true
+}
+
+// limitBodies applies --max-body-bytes to the issue and comments, truncating
+// oversized bodies or failing with --on-oversize fail. It returns how many
+// bodies were over the limit.
+func limitBodies(issue *Issue, comments []Comment) (int, error) {
+	oversized := 0
+	limit := func(what, body string) (string, error) {
+		if len(body) <= maxBodyBytesFlag {
+			return body, nil
+		}
+		oversized++
+		if onOversizeFlag == "fail" {
+			return "", fmt.Errorf("%s is %d bytes, over the --max-body-bytes limit of %d", what, len(body), maxBodyBytesFlag)
+		}
+		body, _ = truncateBody(body, maxBodyBytesFlag)
+		return body, nil
+	}
+
+	var err error
+	issue.Body, err = limit("the issue body", issue.Body)
+	if err != nil {
+		return oversized, err
+	}
+	for i := range comments {
+		comments[i].Body, err = limit(fmt.Sprintf("comment %d", i+1), comments[i].Body)
+		if err != nil {
+			return oversized, err
+		}
+	}
+	return oversized, nil
+}
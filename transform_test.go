@@ -0,0 +1,258 @@
+package main
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeNewlines(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "CRLF", in: "one\r\ntwo\r\n", want: "one\ntwo\n"},
+		{name: "bare CR", in: "one\rtwo", want: "one\ntwo"},
+		{name: "trailing spaces and tabs", in: "one  \ntwo\t\nthree", want: "one\ntwo\nthree"},
+		{name: "leading spaces kept", in: "  indented\n\tcode", want: "  indented\n\tcode"},
+		{name: "already clean", in: "one\n\ntwo", want: "one\n\ntwo"},
+		{name: "empty", in: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeNewlines(tt.in); got != tt.want {
+				t.Errorf("normalizeNewlines(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		width int
+		want  string
+	}{
+		{name: "short line", in: "fits fine", width: 20, want: "fits fine"},
+		{name: "wrapped at spaces", in: "the quick brown fox jumps over the lazy dog", width: 15, want: "the quick brown\nfox jumps over\nthe lazy dog"},
+		{name: "line breaks kept", in: "one two\nthree four five", width: 10, want: "one two\nthree four\nfive"},
+		{name: "long word kept whole", in: "see https://example.com/a/very/long/path now", width: 10, want: "see\nhttps://example.com/a/very/long/path\nnow"},
+		{name: "code fence untouched", in: "```\nthis code line is far too long to fit\n```\nwrap this text too", width: 10, want: "```\nthis code line is far too long to fit\n```\nwrap this\ntext too"},
+		{name: "disabled", in: "no wrapping at all here", width: 0, want: "no wrapping at all here"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wrapText(tt.in, tt.width); got != tt.want {
+				t.Errorf("wrapText(%q, %d) =\n%s\nwant\n%s", tt.in, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlattenQuotes(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          string
+		want        string
+		wantRemoved int
+	}{
+		{
+			name:        "multi-line quote",
+			in:          "> Does it crash?\n> On every start?\n\nYes, every time.",
+			want:        "Yes, every time.",
+			wantRemoved: 3,
+		},
+		{
+			name:        "quote between replies",
+			in:          "Thanks.\n> old text\n  > indented\nNew text.",
+			want:        "Thanks.\nNew text.",
+			wantRemoved: 2,
+		},
+		{
+			name:        "fenced code kept",
+			in:          "```\n> not a quote\n```\n> quote",
+			want:        "```\n> not a quote\n```",
+			wantRemoved: 1,
+		},
+		{
+			name:        "no quotes",
+			in:          "Plain reply.\n\nSecond paragraph.",
+			want:        "Plain reply.\n\nSecond paragraph.",
+			wantRemoved: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, removed := flattenQuotes(tt.in)
+			if got != tt.want || removed != tt.wantRemoved {
+				t.Errorf("flattenQuotes(%q) = %q, %d, want %q, %d", tt.in, got, removed, tt.want, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+func TestRunFilter(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands are POSIX shell")
+	}
+
+	tests := []struct {
+		name    string
+		command string
+		body    string
+		want    string
+		wantErr bool
+	}{
+		{name: "transforms the body", command: "tr a-z A-Z", body: "shout\n", want: "SHOUT\n"},
+		{name: "reads all of stdin", command: "wc -l | tr -d ' '", body: "one\ntwo\nthree\n", want: "3\n"},
+		{name: "empty output", command: "cat >/dev/null", body: "gone", want: ""},
+		{name: "failing command", command: "exit 3", body: "x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := runFilter(tt.command, tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runFilter() error = %v, want error: %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("runFilter() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		max     int
+		want    string
+		wantCut bool
+	}{
+		{name: "fits", in: "short", max: 10, want: "short"},
+		{name: "exactly the limit", in: "0123456789", max: 10, want: "0123456789"},
+		{name: "cut with room for the marker", in: "0123456789abcdefghijklmnopqrstuvwxyz", max: 25, want: "0123456789\n" + truncatedMarker, wantCut: true},
+		{name: "multibyte character kept whole", in: "ab€cd" + strings.Repeat("x", 20), max: 19, want: "ab\n" + truncatedMarker, wantCut: true},
+		{name: "no room for the marker", in: "0123456789abc", max: 10, want: "0123456789", wantCut: true},
+		{name: "no room for the marker, multibyte", in: "ab€cd", max: 4, want: "ab", wantCut: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, cut := truncateBody(tt.in, tt.max)
+			if got != tt.want || cut != tt.wantCut {
+				t.Errorf("truncateBody(%q, %d) = %q, %v, want %q, %v", tt.in, tt.max, got, cut, tt.want, tt.wantCut)
+			}
+			if len(got) > tt.max {
+				t.Errorf("truncateBody(%q, %d) is %d bytes, over the limit", tt.in, tt.max, len(got))
+			}
+		})
+	}
+}
+
+func TestLimitBodies(t *testing.T) {
+	tests := []struct {
+		name          string
+		onOversize    string
+		wantOversized int
+		wantBodies    []string
+		wantErr       string
+	}{
+		{name: "truncate", onOversize: "truncate", wantOversized: 2, wantBodies: []string{"ok", "far t\n" + truncatedMarker}},
+		{name: "fail", onOversize: "fail", wantOversized: 1, wantErr: "the issue body is 25 bytes, over the --max-body-bytes limit of 20"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &maxBodyBytesFlag, 20)
+			setFlag(t, &onOversizeFlag, tt.onOversize)
+
+			issue := Issue{Body: "an issue text over limits"}
+			comments := []Comment{{Body: "ok"}, {Body: "far too long for the limit"}}
+			oversized, err := limitBodies(&issue, comments)
+			if oversized != tt.wantOversized {
+				t.Errorf("oversized = %d, want %d", oversized, tt.wantOversized)
+			}
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("limitBodies() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := []string{comments[0].Body, comments[1].Body}; !reflect.DeepEqual(got, tt.wantBodies) {
+				t.Errorf("comment bodies = %q, want %q", got, tt.wantBodies)
+			}
+		})
+	}
+}
+
+func TestWrapTextWideCharacters(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		width int
+		want  string
+	}{
+		{name: "CJK broken between characters", in: "これは長い文章です", width: 8, want: "これは長\nい文章で\nす"},
+		{name: "CJK after a word", in: "see 日本語", width: 6, want: "see 日\n本語"},
+		{name: "accents count once", in: "café café café", width: 9, want: "café café\ncafé"},
+		{name: "combining marks take no width", in: "café café", width: 9, want: "café café"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wrapText(tt.in, tt.width); got != tt.want {
+				t.Errorf("wrapText(%q, %d) = %q, want %q", tt.in, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{s: "abc", want: 3},
+		{s: "日本", want: 4},
+		{s: "한국어", want: 6},
+		{s: "é", want: 1},
+		{s: "👍", want: 2},
+	}
+
+	for _, tt := range tests {
+		if got := stringWidth(tt.s); got != tt.want {
+			t.Errorf("stringWidth(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestPrepareThreadInvalidUTF8(t *testing.T) {
+	issue := Issue{Body: "caf\xe9"}
+	comments := []Comment{{Body: "ok \xff\xfe done"}, {Body: "fine"}}
+	prepareThread(&issue, comments, nil)
+
+	tests := []struct {
+		got  string
+		want string
+	}{
+		{got: issue.Body, want: "caf�"},
+		{got: comments[0].Body, want: "ok � done"},
+		{got: comments[1].Body, want: "fine"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("body = %q, want %q", tt.got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// translator turns text into another language
+type translator interface {
+	translate(text, target string) (string, error)
+}
+
+// Translator for --translate-to, nil when comments aren't translated
+var commentTranslator translator
+
+// httpTranslator calls a LibreTranslate-compatible endpoint: it posts the
+// text as JSON and reads back translatedText.
+type httpTranslator struct {
+	client *http.Client
+	url    string
+	key    string
+}
+
+// newHTTPTranslator uses a plain client, as the TLS and proxy flags are
+// meant for GitHub.
+func newHTTPTranslator(url, key string) *httpTranslator {
+	return &httpTranslator{client: &http.Client{}, url: url, key: key}
+}
+
+func (t *httpTranslator) translate(text, target string) (string, error) {
+	request := struct {
+		Q      string `json:"q"`
+		Source string `json:"source"`
+		Target string `json:"target"`
+		Format string `json:"format"`
+		APIKey string `json:"api_key,omitempty"`
+	}{Q: text, Source: "auto", Target: target, Format: "text", APIKey: t.key}
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal translation request: %w", err)
+	}
+
+	err = waitForRequest(context.Background())
+	if err != nil {
+		return "", err
+	}
+	resp, err := t.client.Post(t.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to reach translation service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("translation failed: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		TranslatedText string `json:"translatedText"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse translation: %w", err)
+	}
+	return result.TranslatedText, nil
+}
+
+// translateComments adds a translation to each comment with a body. A
+// comment that can't be translated keeps just its original.
+func translateComments(t translator, target string, comments []Comment) {
+	for i := range comments {
+		if strings.TrimSpace(comments[i].Body) == "" {
+			continue
+		}
+		translated, err := t.translate(comments[i].Body, target)
+		if err != nil {
+			log.Printf("Keeping comment %d untranslated: %s", comments[i].ID, err)
+			continue
+		}
+		comments[i].Translation = translated
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPTranslator(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		reply   string
+		want    string
+		wantErr bool
+	}{
+		{name: "translated", status: http.StatusOK, reply: `{"translatedText":"hallo"}`, want: "hallo"},
+		{name: "rejected key", status: http.StatusForbidden, reply: `{"error":"invalid key"}`, wantErr: true},
+		{name: "not JSON", status: http.StatusOK, reply: `hallo`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var request map[string]string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&request)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.reply))
+			}))
+			defer server.Close()
+
+			got, err := newHTTPTranslator(server.URL, "secret").translate("hello", "de")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("translate() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("translate() = %q, want %q", got, tt.want)
+			}
+			if request["q"] != "hello" || request["target"] != "de" || request["source"] != "auto" || request["api_key"] != "secret" {
+				t.Errorf("request = %v", request)
+			}
+		})
+	}
+}
+
+// upperTranslator translates by upper-casing, and fails on bodies containing "fail".
+type upperTranslator struct{}
+
+func (upperTranslator) translate(text, target string) (string, error) {
+	if strings.Contains(text, "fail") {
+		return "", errors.New("unavailable")
+	}
+	return strings.ToUpper(text), nil
+}
+
+func TestTranslateComments(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{body: "hello", want: "HELLO"},
+		{body: "  \n", want: ""},
+		{body: "please fail", want: ""},
+	}
+
+	comments := make([]Comment, len(tests))
+	for i, tt := range tests {
+		comments[i].Body = tt.body
+	}
+	translateComments(upperTranslator{}, "de", comments)
+
+	for i, tt := range tests {
+		if comments[i].Translation != tt.want {
+			t.Errorf("translation of %q = %q, want %q", tt.body, comments[i].Translation, tt.want)
+		}
+		if comments[i].Body != tt.body {
+			t.Errorf("body changed to %q", comments[i].Body)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+)
+
+// userName returns the display name of a user, looking each login up once.
+// Failed lookups are logged and remembered as having no name.
+func (f *fetcher) userName(login string) string {
+	if name, ok := f.names[login]; ok {
+		return name
+	}
+
+	var user struct {
+		Name string `json:"name"`
+	}
+	err := f.getJSON(fmt.Sprintf("%s/users/%s", apiBaseURL, url.PathEscape(login)), &user)
+	if err != nil {
+		log.Printf("Showing @%s without a name: %s", login, err)
+	}
+
+	f.names[login] = user.Name
+	return user.Name
+}
+
+// resolveNames fills in the display names of the issue and comment authors.
+func (f *fetcher) resolveNames(issue *Issue, comments []Comment) {
+	if f.names == nil {
+		f.names = make(map[string]string)
+	}
+
+	if issue.User.Login != "" {
+		issue.User.Name = f.userName(issue.User.Login)
+	}
+	for i := range comments {
+		if comments[i].User.Login != "" {
+			comments[i].User.Name = f.userName(comments[i].User.Login)
+		}
+	}
+}
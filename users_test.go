@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolveNames(t *testing.T) {
+	lookups := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups[r.URL.Path]++
+		switch r.URL.Path {
+		case "/users/alice":
+			fmt.Fprint(w, `{"login":"alice","name":"Alice Liddell"}`)
+		case "/users/bob":
+			fmt.Fprint(w, `{"login":"bob","name":null}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	setFlag(t, &apiBaseURL, server.URL)
+
+	issue := Issue{User: User{Login: "alice"}}
+	comments := []Comment{
+		{User: User{Login: "bob"}},
+		{User: User{Login: "alice"}},
+		{User: User{Login: "gone"}},
+		{User: User{Login: ""}},
+	}
+	f := &fetcher{ctx: context.Background(), client: server.Client(), stats: newRunStats()}
+	f.resolveNames(&issue, comments)
+
+	tests := []struct {
+		user User
+		want string
+	}{
+		{user: issue.User, want: "Alice Liddell"},
+		{user: comments[0].User, want: ""},
+		{user: comments[1].User, want: "Alice Liddell"},
+		{user: comments[2].User, want: ""},
+		{user: comments[3].User, want: ""},
+	}
+	for _, tt := range tests {
+		if tt.user.Name != tt.want {
+			t.Errorf("name of %q = %q, want %q", tt.user.Login, tt.user.Name, tt.want)
+		}
+	}
+
+	// Every login is looked up once, even when the lookup failed
+	for path, count := range lookups {
+		if count != 1 {
+			t.Errorf("%s looked up %d times, want once", path, count)
+		}
+	}
+	if len(lookups) != 3 {
+		t.Errorf("looked up %v, want alice, bob and gone", lookups)
+	}
+}
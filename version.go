@@ -0,0 +1,16 @@
+package main
+
+import "fmt"
+
+// Build metadata, set at build time with
+// -ldflags "-X main.version=... -X main.commit=... -X main.date=..."
+var (
+	version = "dev"
+	commit  = "dev"
+	date    = "dev"
+)
+
+// versionString describes the running build.
+func versionString() string {
+	return fmt.Sprintf("github-comments-fetcher %s (commit %s, built %s)", version, commit, date)
+}
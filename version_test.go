@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestVersionString(t *testing.T) {
+	tests := []struct {
+		name                  string
+		version, commit, date string
+		want                  string
+	}{
+		{name: "development build", version: "dev", commit: "dev", date: "dev", want: "github-comments-fetcher dev (commit dev, built dev)"},
+		{name: "release build", version: "1.4.0", commit: "abc1234", date: "2024-05-01", want: "github-comments-fetcher 1.4.0 (commit abc1234, built 2024-05-01)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &version, tt.version)
+			setFlag(t, &commit, tt.commit)
+			setFlag(t, &date, tt.date)
+
+			if got := versionString(); got != tt.want {
+				t.Errorf("versionString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}